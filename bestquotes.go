@@ -0,0 +1,223 @@
+package carrierpricing
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/giefferre/carrierpricing/postcode"
+)
+
+var errNoVehicleCanCarryParcel = errors.New("no vehicle can carry the given parcel")
+
+const (
+	reasonCheapest           = "cheapest option across all vehicles and carriers"
+	reasonFastest            = "fastest option across all vehicles and carriers"
+	reasonCheapestAndFastest = "cheapest and fastest option across all vehicles and carriers"
+)
+
+// GetBestQuotesArgs contains arguments for the GetBestQuotes method.
+// When IncludeBreakdown is true, the price of each option is itemised.
+// When Currency is set, prices are converted to it.
+// PriceDisplay chooses whether prices are displayed before (PriceDisplayNet, the
+// default) or including taxes (PriceDisplayGross).
+// When PickupTime is set, the surcharges of the period it falls in are applied.
+type GetBestQuotesArgs struct {
+	PickupPostcode   string     `json:"pickup_postcode"`
+	DeliveryPostcode string     `json:"delivery_postcode"`
+	Parcel           *Parcel    `json:"parcel,omitempty"`
+	PickupTime       *time.Time `json:"pickup_time,omitempty"`
+	IncludeBreakdown bool       `json:"include_breakdown"`
+	Currency         string     `json:"currency,omitempty"`
+	PriceDisplay     string     `json:"price_display,omitempty"`
+}
+
+// GetBestQuotesResponse is the response object for the GetBestQuotes method.
+// ExchangeRate is set when prices have been converted to the requested currency.
+// QuoteReference is set when quotes are stored.
+type GetBestQuotesResponse struct {
+	QuoteReference
+	PickupPostcode   string        `json:"pickup_postcode"`
+	DeliveryPostcode string        `json:"delivery_postcode"`
+	TaxJurisdiction  string        `json:"tax_jurisdiction"`
+	TaxRate          float64       `json:"tax_rate"`
+	Options          []QuoteOption `json:"options"`
+	ExchangeRate     *ExchangeRate `json:"exchange_rate,omitempty"`
+}
+
+// QuoteOption is the object returned in the GetBestQuotesResponse indicating
+// the vehicle and carrier service to be used for the delivery, with the
+// ServiceID needed to book it, its price and delivery time, and the reason why
+// it has been selected. EstimatedDelivery is set when the pickup time is known.
+// The Breakdown, when requested, itemises the price before taxes.
+type QuoteOption struct {
+	Rank        int    `json:"rank"`
+	Vehicle     string `json:"vehicle"`
+	CarrierName string `json:"service"`
+	ServiceID   string `json:"service_id"`
+	Amount      Money  `json:"price"`
+	TaxedAmounts
+	Surcharges        []PriceAdjustment `json:"surcharges,omitempty"`
+	DeliveryTime      DeliveryDuration  `json:"delivery_time"`
+	EstimatedDelivery *DeliveryWindow   `json:"estimated_delivery,omitempty"`
+	Reason            string            `json:"reason"`
+	Breakdown         *PriceBreakdown   `json:"breakdown,omitempty"`
+}
+
+// GetBestQuotes calculates the price of the delivery between pickup and delivery
+// post codes for every vehicle able to carry the given parcel and all the
+// available carriers, returning the cheapest and the fastest options.
+func (s *Service) GetBestQuotes(ctx context.Context, args GetBestQuotesArgs) (*GetBestQuotesResponse, error) {
+	s.logger.Printf("executing GetBestQuotes with args: %v\n", args)
+
+	rules := s.PricingRules()
+
+	exchangeRate, err := s.exchangeRate(rules, args.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	pickup, delivery, err := s.parsePostcodes(args.PickupPostcode, args.DeliveryPostcode)
+	if err != nil {
+		return nil, err
+	}
+
+	taxation, err := newTaxation(rules, args.PriceDisplay, pickup, delivery)
+	if err != nil {
+		return nil, err
+	}
+
+	basePrice, err := s.calculateBasePrice(rules, pickup, delivery, args.Parcel)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := []QuoteOption{}
+	vehiclesAbleToCarryParcel := 0
+
+	for _, vehicleType := range ValidVehicleTypes {
+		err := s.checkVehicleCapacity(rules, vehicleType, args.Parcel)
+		if err != nil {
+			continue
+		}
+		vehiclesAbleToCarryParcel++
+
+		priceByVehicle := s.applyVehicleMarkup(rules, basePrice.amount, vehicleType)
+		surcharges := s.calculateSurcharges(rules, args.PickupTime, pickup, priceByVehicle)
+		price := priceByVehicle + sumOfAdjustments(surcharges)
+		availableCarrierServices, err := s.findCarrierServices(ctx,
+			newCarrierQuery(vehicleType, pickup, []postcode.Postcode{*delivery}, args.Parcel, args.PickupTime),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		var vehicleBreakdown *PriceBreakdown
+		if args.IncludeBreakdown {
+			vehicleBreakdown = newPriceBreakdown(rules, *basePrice, vehicleType, surcharges, price)
+		}
+
+		for _, priceByCarrier := range s.getPriceListFromPriceAndCarrierServices(rules, price, vehicleBreakdown, availableCarrierServices, pickup, args.PickupTime) {
+			candidates = append(candidates, QuoteOption{
+				Vehicle:           vehicleType,
+				CarrierName:       priceByCarrier.CarrierName,
+				ServiceID:         priceByCarrier.ServiceID,
+				Amount:            priceByCarrier.Amount,
+				Surcharges:        surcharges,
+				DeliveryTime:      priceByCarrier.DeliveryTime,
+				EstimatedDelivery: priceByCarrier.EstimatedDelivery,
+				Breakdown:         priceByCarrier.Breakdown,
+			})
+		}
+	}
+
+	if vehiclesAbleToCarryParcel == 0 {
+		return nil, invalidArgs(errNoVehicleCanCarryParcel)
+	}
+
+	if len(candidates) == 0 {
+		return nil, invalidArgs(errNoAvailableCarrierServicesForVehicle)
+	}
+
+	// options are ranked before the conversion, so that rounding cannot affect them
+	options := rankQuoteOptions(candidates)
+	for i := range options {
+		options[i].TaxedAmounts = taxation.apply(exchangeRate.convert(options[i].Amount, rules.Rounding))
+		options[i].Amount = taxation.display(options[i].TaxedAmounts)
+		options[i].Surcharges = exchangeRate.convertAdjustments(options[i].Surcharges, rules.Rounding)
+		options[i].Breakdown = exchangeRate.convertBreakdown(options[i].Breakdown, rules.Rounding)
+	}
+
+	response := &GetBestQuotesResponse{
+		PickupPostcode:   pickup.String(),
+		DeliveryPostcode: delivery.String(),
+		TaxJurisdiction:  taxation.jurisdiction,
+		TaxRate:          taxation.rate,
+		Options:          options,
+		ExchangeRate:     exchangeRate,
+	}
+
+	err = s.storeQuote(quoteTypeBest, args, response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// rankQuoteOptions selects the cheapest and the fastest among the given
+// candidates; ties are broken by the other criteria, then by the order of
+// the candidates themselves. A single option is returned when the cheapest
+// candidate is the fastest one too.
+// The fastest candidate is the one with the earliest estimated delivery, when
+// known, otherwise the one with the shortest delivery time.
+func rankQuoteOptions(candidates []QuoteOption) []QuoteOption {
+	byPrice := make([]int, len(candidates))
+	byDeliveryTime := make([]int, len(candidates))
+	for i := range candidates {
+		byPrice[i] = i
+		byDeliveryTime[i] = i
+	}
+
+	sort.SliceStable(byPrice, func(i, j int) bool {
+		a, b := candidates[byPrice[i]], candidates[byPrice[j]]
+		if a.Amount.Amount != b.Amount.Amount {
+			return a.Amount.Amount < b.Amount.Amount
+		}
+		return a.deliversBefore(b)
+	})
+
+	sort.SliceStable(byDeliveryTime, func(i, j int) bool {
+		a, b := candidates[byDeliveryTime[i]], candidates[byDeliveryTime[j]]
+		if a.deliversBefore(b) || b.deliversBefore(a) {
+			return a.deliversBefore(b)
+		}
+		return a.Amount.Amount < b.Amount.Amount
+	})
+
+	cheapest := candidates[byPrice[0]]
+	fastest := candidates[byDeliveryTime[0]]
+
+	if byPrice[0] == byDeliveryTime[0] {
+		cheapest.Rank = 1
+		cheapest.Reason = reasonCheapestAndFastest
+		return []QuoteOption{cheapest}
+	}
+
+	cheapest.Rank = 1
+	cheapest.Reason = reasonCheapest
+	fastest.Rank = 2
+	fastest.Reason = reasonFastest
+
+	return []QuoteOption{cheapest, fastest}
+}
+
+// deliversBefore returns true if the QuoteOption is expected to be delivered
+// before the other one.
+func (qo QuoteOption) deliversBefore(other QuoteOption) bool {
+	if qo.EstimatedDelivery != nil && other.EstimatedDelivery != nil {
+		return qo.EstimatedDelivery.Latest.Before(other.EstimatedDelivery.Latest)
+	}
+	return qo.DeliveryTime.Nominal() < other.DeliveryTime.Nominal()
+}
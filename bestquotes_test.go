@@ -0,0 +1,209 @@
+package carrierpricing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestGetBestQuotesLogs(t *testing.T) {
+	// tests that the GetBestQuotes logs the execution
+	logDestination := bytes.NewBufferString("")
+
+	logger := log.New(logDestination, "", 0)
+	service, err := NewService(logger, &mockCarrierServiceFinder{}, &mockDistanceCalculator{}, nil)
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
+
+	service.GetBestQuotes(context.Background(), GetBestQuotesArgs{
+		PickupPostcode:   "FROM",
+		DeliveryPostcode: "TO",
+	})
+
+	expectedLogString := "executing GetBestQuotes with args: {FROM TO <nil> <nil> false  }\n"
+	actualLogString := logDestination.String()
+
+	if expectedLogString != actualLogString {
+		t.Fatalf("expected log message was not received, had %v", actualLogString)
+	}
+}
+
+func TestGetBestQuotes(t *testing.T) {
+	tests := []struct {
+		Arguments      GetBestQuotesArgs
+		ExpectedResult *GetBestQuotesResponse
+		ExpectedError  error
+	}{
+		// case #1 invalid PickupPostcode argument
+		{
+			Arguments: GetBestQuotesArgs{
+				PickupPostcode:   "_",
+				DeliveryPostcode: "EC2A3LT",
+			},
+			ExpectedResult: nil,
+			ExpectedError:  errors.New("invalid postcode \"_\": postcode has an invalid length"),
+		},
+		// case #2 no vehicle can carry the parcel
+		{
+			Arguments: GetBestQuotesArgs{
+				PickupPostcode:   "SW1A1AA",
+				DeliveryPostcode: "EC2A3LT",
+				Parcel:           &Parcel{WeightKg: 2000},
+			},
+			ExpectedResult: nil,
+			ExpectedError:  errors.New("no vehicle can carry the given parcel"),
+		},
+		// case #3 some vehicles can carry the parcel but no carrier services are available for them
+		{
+			Arguments: GetBestQuotesArgs{
+				PickupPostcode:   "SW1A1AA",
+				DeliveryPostcode: "EC2A3LT",
+				Parcel:           &Parcel{WeightKg: 1000},
+			},
+			ExpectedResult: nil,
+			ExpectedError:  errors.New("no available carrier services for the given vehicle"),
+		},
+		// case #4 cheapest and fastest options use different vehicles
+		{
+			Arguments: GetBestQuotesArgs{
+				PickupPostcode:   "SW1A1AA",
+				DeliveryPostcode: "EC2A3LT",
+			},
+			ExpectedResult: &GetBestQuotesResponse{
+				PickupPostcode:   "SW1A 1AA",
+				DeliveryPostcode: "EC2A 3LT",
+				TaxJurisdiction:  JurisdictionGreatBritain,
+				TaxRate:          0.2,
+				Options: []QuoteOption{
+					QuoteOption{
+						Rank:         1,
+						Vehicle:      "parcel_car",
+						CarrierName:  "MockService3",
+						ServiceID:    "1",
+						Amount:       gbp(384),
+						TaxedAmounts: taxedGBP(384, 77),
+						DeliveryTime: workingDays(3),
+						Reason:       "cheapest option across all vehicles and carriers",
+					},
+					QuoteOption{
+						Rank:         2,
+						Vehicle:      "small_van",
+						CarrierName:  "MockService1",
+						ServiceID:    "1",
+						Amount:       gbp(431),
+						TaxedAmounts: taxedGBP(431, 86),
+						DeliveryTime: workingDays(1),
+						Reason:       "fastest option across all vehicles and carriers",
+					},
+				},
+			},
+			ExpectedError: nil,
+		},
+		// case #5 the parcel is too heavy for a parcel car
+		{
+			Arguments: GetBestQuotesArgs{
+				PickupPostcode:   "SW1A1AA",
+				DeliveryPostcode: "EC2A3LT",
+				Parcel:           &Parcel{WeightKg: 200},
+			},
+			ExpectedResult: &GetBestQuotesResponse{
+				PickupPostcode:   "SW1A 1AA",
+				DeliveryPostcode: "EC2A 3LT",
+				TaxJurisdiction:  JurisdictionGreatBritain,
+				TaxRate:          0.2,
+				Options: []QuoteOption{
+					QuoteOption{
+						Rank:         1,
+						Vehicle:      "small_van",
+						CarrierName:  "MockService2",
+						ServiceID:    "1",
+						Amount:       gbp(3021),
+						TaxedAmounts: taxedGBP(3021, 604),
+						DeliveryTime: workingDays(5),
+						Reason:       "cheapest option across all vehicles and carriers",
+					},
+					QuoteOption{
+						Rank:         2,
+						Vehicle:      "small_van",
+						CarrierName:  "MockService1",
+						ServiceID:    "1",
+						Amount:       gbp(3031),
+						TaxedAmounts: taxedGBP(3031, 606),
+						DeliveryTime: workingDays(1),
+						Reason:       "fastest option across all vehicles and carriers",
+					},
+				},
+			},
+			ExpectedError: nil,
+		},
+	}
+
+	logger := log.New(os.Stdout, "", log.LstdFlags)
+	service, err := NewService(logger, &mockCarrierServiceFinder{}, &mockDistanceCalculator{}, nil)
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
+
+	for _, tc := range tests {
+		result, err := service.GetBestQuotes(context.Background(), tc.Arguments)
+		if (tc.ExpectedError != nil && err == nil) ||
+			(tc.ExpectedError == nil && err != nil) ||
+			(tc.ExpectedError != nil && err != nil && tc.ExpectedError.Error() != err.Error()) {
+			t.Fatalf(
+				"expected error '%v', received: '%v'\n",
+				tc.ExpectedError,
+				err,
+			)
+		}
+		if !reflect.DeepEqual(tc.ExpectedResult, result) {
+			t.Fatalf(
+				"expected result '%v', received: '%v'\n",
+				tc.ExpectedResult,
+				result,
+			)
+		}
+	}
+}
+
+func TestRankQuoteOptionsSingleOption(t *testing.T) {
+	// tests that a single option is returned when the cheapest is the fastest too
+	options := rankQuoteOptions([]QuoteOption{
+		QuoteOption{Vehicle: "bicycle", CarrierName: "A", Amount: gbp(200), DeliveryTime: workingDays(3)},
+		QuoteOption{Vehicle: "motorbike", CarrierName: "B", Amount: gbp(100), DeliveryTime: workingDays(1)},
+	})
+
+	expectedOptions := []QuoteOption{
+		QuoteOption{
+			Rank:         1,
+			Vehicle:      "motorbike",
+			CarrierName:  "B",
+			Amount:       gbp(100),
+			DeliveryTime: workingDays(1),
+			Reason:       "cheapest and fastest option across all vehicles and carriers",
+		},
+	}
+
+	if !reflect.DeepEqual(expectedOptions, options) {
+		t.Fatalf("expected options '%v', received: '%v'", expectedOptions, options)
+	}
+}
+
+func TestRankQuoteOptionsByEstimatedDelivery(t *testing.T) {
+	// tests that the fastest option is the one delivered first, when estimated
+	nextDay := &DeliveryWindow{Earliest: ukTime(2026, 10, 16, 8, 0), Latest: ukTime(2026, 10, 16, 18, 0)}
+	sameDay := &DeliveryWindow{Earliest: ukTime(2026, 10, 15, 10, 0), Latest: ukTime(2026, 10, 15, 14, 0)}
+
+	options := rankQuoteOptions([]QuoteOption{
+		QuoteOption{Vehicle: "bicycle", CarrierName: "A", Amount: gbp(100), DeliveryTime: workingDays(1), EstimatedDelivery: nextDay},
+		QuoteOption{Vehicle: "motorbike", CarrierName: "B", Amount: gbp(200), DeliveryTime: NewDeliveryDuration(4, DeliveryTimeUnitHours), EstimatedDelivery: sameDay},
+	})
+
+	if len(options) != 2 || options[0].CarrierName != "A" || options[1].CarrierName != "B" {
+		t.Fatalf("expected A to be the cheapest and B the fastest option, received: '%v'", options)
+	}
+}
@@ -0,0 +1,431 @@
+package carrierpricing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BookingStatus is the state of a Booking.
+type BookingStatus string
+
+const (
+	// BookingStatusPending is the status of a booking just created, waiting to
+	// be confirmed by the carrier.
+	BookingStatusPending BookingStatus = "pending"
+
+	// BookingStatusConfirmed is the status of a booking the carrier agreed to carry.
+	BookingStatusConfirmed BookingStatus = "confirmed"
+
+	// BookingStatusCollected is the status of a booking whose parcel has been collected.
+	BookingStatusCollected BookingStatus = "collected"
+
+	// BookingStatusDelivered is the status of a booking whose parcel has been delivered.
+	BookingStatusDelivered BookingStatus = "delivered"
+
+	// BookingStatusCancelled is the status of a booking cancelled before the collection.
+	BookingStatusCancelled BookingStatus = "cancelled"
+)
+
+// bookingTransitions lists the statuses each status can move to; delivered and
+// cancelled bookings cannot change anymore.
+var bookingTransitions = map[BookingStatus][]BookingStatus{
+	BookingStatusPending:   {BookingStatusConfirmed, BookingStatusCancelled},
+	BookingStatusConfirmed: {BookingStatusCollected, BookingStatusCancelled},
+	BookingStatusCollected: {BookingStatusDelivered},
+}
+
+var (
+	// ErrBookingNotFound is returned when no booking has the given ID.
+	ErrBookingNotFound = errors.New("booking not found")
+
+	// ErrCarrierNotAvailable is returned when the carrier chosen for a booking is
+	// no longer available for the quoted delivery.
+	ErrCarrierNotAvailable = errors.New("carrier no longer available for the quoted delivery")
+
+	// ErrInvalidBookingTransition is returned when a booking cannot move to the
+	// requested status from its current one.
+	ErrInvalidBookingTransition = errors.New("invalid booking status transition")
+
+	// ErrDispatchFailed is returned when the carrier of a booking could not book,
+	// cancel or track the job handed over to it.
+	ErrDispatchFailed = errors.New("carrier dispatch failed")
+
+	// ErrBookingNotDispatched is returned when tracking a booking which has not
+	// been handed over to its carrier.
+	ErrBookingNotDispatched = errors.New("booking not dispatched to the carrier")
+
+	// ErrQuoteAlreadyBooked is returned when the chosen carrier service of a quote
+	// has already been booked.
+	ErrQuoteAlreadyBooked = errors.New("quote already booked with the carrier service")
+
+	errBookingStoreNotAvailable = errors.New("bookings are not available")
+	errQuoteNotBookable         = errors.New("only quotes by carrier can be booked")
+	errCarrierNotQuoted         = errors.New("carrier service not listed in the quote")
+	errServiceIDMissing         = errors.New("service_id must be provided")
+)
+
+// PriceChangedError is returned when a quote cannot be booked because the price
+// of the chosen carrier is now higher than the quoted one.
+type PriceChangedError struct {
+	QuotedPrice  Money
+	CurrentPrice Money
+}
+
+func (e *PriceChangedError) Error() string {
+	return fmt.Sprintf("price changed from %s to %s since the quote", e.QuotedPrice, e.CurrentPrice)
+}
+
+// BookingStore is a software service used to save the bookings created by the
+// Service. CreateBooking saves a new booking, returning ErrQuoteAlreadyBooked
+// when a booking has already been created for the same QuoteID, CarrierName
+// and ServiceID, whatever its status; SaveBooking replaces the booking with the
+// same ID; GetBooking must return ErrBookingNotFound when no booking has the
+// given ID.
+type BookingStore interface {
+	CreateBooking(booking *Booking) error
+	SaveBooking(booking *Booking) error
+	GetBooking(id string) (*Booking, error)
+}
+
+// WithBookingStore sets the BookingStore used to save bookings; without it,
+// quotes cannot be booked. Bookings also require a QuoteStore.
+func WithBookingStore(bookingStore BookingStore) ServiceOption {
+	return func(s *Service) {
+		s.bookingStore = bookingStore
+	}
+}
+
+// Booking is a delivery booked with a carrier at the price of a stored quote.
+// Price is displayed as in the quote, before or including taxes; History lists
+// all the statuses of the booking, the current one being the last.
+// CarrierReference is the reference the carrier gave to the job once dispatched.
+type Booking struct {
+	ID               string     `json:"id"`
+	QuoteID          string     `json:"quote_id"`
+	CarrierName      string     `json:"service"`
+	ServiceID        string     `json:"service_id"`
+	Vehicle          string     `json:"vehicle"`
+	PickupPostcode   string     `json:"pickup_postcode"`
+	DeliveryPostcode string     `json:"delivery_postcode"`
+	PickupTime       *time.Time `json:"pickup_time,omitempty"`
+	Price            Money      `json:"price"`
+	TaxedAmounts
+	DeliveryTime      DeliveryDuration `json:"delivery_time"`
+	EstimatedDelivery *DeliveryWindow  `json:"estimated_delivery,omitempty"`
+	CarrierReference  string           `json:"carrier_reference,omitempty"`
+	Status            BookingStatus    `json:"status"`
+	CreatedAt         time.Time        `json:"created_at"`
+	UpdatedAt         time.Time        `json:"updated_at"`
+	History           []BookingEvent   `json:"history"`
+}
+
+// BookingEvent records the time a Booking moved to a status.
+type BookingEvent struct {
+	Status BookingStatus `json:"status"`
+	At     time.Time     `json:"at"`
+}
+
+// CreateBookingArgs contains arguments for the CreateBooking method: the ID of
+// a stored quote by carrier, and the name of the carrier and the ID of the
+// service chosen from its price list, both required.
+type CreateBookingArgs struct {
+	QuoteID     string `json:"quote_id"`
+	CarrierName string `json:"service"`
+	ServiceID   string `json:"service_id"`
+}
+
+// UpdateBookingStatusArgs contains arguments for the UpdateBookingStatus method.
+type UpdateBookingStatusArgs struct {
+	BookingID string        `json:"booking_id"`
+	Status    BookingStatus `json:"status"`
+}
+
+// CreateBooking books the delivery of a stored quote by carrier with the chosen
+// carrier service. The quote is calculated again: the booking is rejected with
+// ErrCarrierNotAvailable when the service is no longer listed, and with a
+// *PriceChangedError when its price is higher than the quoted one; otherwise
+// the quoted price is kept. The booking is created in the pending status;
+// ErrQuoteAlreadyBooked is returned when the carrier service of the quote has
+// already been booked.
+func (s *Service) CreateBooking(ctx context.Context, args CreateBookingArgs) (*Booking, error) {
+	s.logger.Printf("executing CreateBooking with args: %v\n", args)
+
+	if s.bookingStore == nil {
+		return nil, errBookingStoreNotAvailable
+	}
+
+	if args.ServiceID == "" {
+		return nil, invalidArgs(errServiceIDMissing)
+	}
+
+	quote, err := s.GetQuote(args.QuoteID)
+	if err != nil {
+		return nil, err
+	}
+
+	if quote.Type != quoteTypeByCarrier {
+		return nil, invalidArgs(errQuoteNotBookable)
+	}
+
+	quoteArgs := GetQuotesByCarrierArgs{}
+	err = json.Unmarshal(quote.Args, &quoteArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	quoteResponse := &GetQuotesByCarrierResponse{}
+	err = json.Unmarshal(quote.Response, quoteResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	quoted, found := findPriceByCarrier(quoteResponse.PriceList, args.CarrierName, args.ServiceID)
+	if !found {
+		return nil, invalidArgs(fmt.Errorf("%w: %q, service %q", errCarrierNotQuoted, args.CarrierName, args.ServiceID))
+	}
+
+	currentResponse, err := s.calculateQuotesByCarrier(ctx, quoteArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	current, found := findPriceByCarrier(currentResponse.PriceList, quoted.CarrierName, quoted.ServiceID)
+	if !found {
+		return nil, ErrCarrierNotAvailable
+	}
+
+	if current.Amount.Currency != quoted.Amount.Currency || current.Amount.Amount > quoted.Amount.Amount {
+		return nil, &PriceChangedError{QuotedPrice: quoted.Amount, CurrentPrice: current.Amount}
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.currentTime()
+	booking := &Booking{
+		ID:                id,
+		QuoteID:           quote.ID,
+		CarrierName:       quoted.CarrierName,
+		ServiceID:         quoted.ServiceID,
+		Vehicle:           quoteResponse.Vehicle,
+		PickupPostcode:    quoteResponse.PickupPostcode,
+		DeliveryPostcode:  quoteResponse.DeliveryPostcode,
+		PickupTime:        quoteArgs.PickupTime,
+		Price:             quoted.Amount,
+		TaxedAmounts:      quoted.TaxedAmounts,
+		DeliveryTime:      quoted.DeliveryTime,
+		EstimatedDelivery: quoted.EstimatedDelivery,
+		Status:            BookingStatusPending,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+		History:           []BookingEvent{{Status: BookingStatusPending, At: now}},
+	}
+
+	err = s.bookingStore.CreateBooking(booking)
+	if err != nil {
+		return nil, err
+	}
+
+	return booking, nil
+}
+
+// GetBooking returns the booking with the given ID.
+func (s *Service) GetBooking(id string) (*Booking, error) {
+	s.logger.Printf("executing GetBooking with args: %v\n", id)
+
+	if s.bookingStore == nil {
+		return nil, errBookingStoreNotAvailable
+	}
+
+	return s.bookingStore.GetBooking(id)
+}
+
+// UpdateBookingStatus moves the booking with the given ID to the given status;
+// ErrInvalidBookingTransition is returned when the booking cannot move to it
+// from its current status. Confirmed bookings are booked with their carrier, and
+// cancelled ones cancelled with it, when it has a CarrierDispatcher; the status
+// does not change if the carrier fails to do so.
+func (s *Service) UpdateBookingStatus(args UpdateBookingStatusArgs) (*Booking, error) {
+	s.logger.Printf("executing UpdateBookingStatus with args: %v\n", args)
+
+	if s.bookingStore == nil {
+		return nil, errBookingStoreNotAvailable
+	}
+
+	// transitions of the same booking are serialised, so that concurrent updates
+	// cannot both succeed
+	unlock := s.bookingLocks.lock(args.BookingID)
+	defer unlock()
+
+	booking, err := s.bookingStore.GetBooking(args.BookingID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !booking.Status.canMoveTo(args.Status) {
+		return nil, fmt.Errorf("%w from %s to %q", ErrInvalidBookingTransition, booking.Status, args.Status)
+	}
+
+	err = s.dispatchBooking(booking, args.Status)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.currentTime()
+	booking.Status = args.Status
+	booking.UpdatedAt = now
+	booking.History = append(booking.History, BookingEvent{Status: args.Status, At: now})
+
+	err = s.bookingStore.SaveBooking(booking)
+	if err != nil {
+		return nil, err
+	}
+
+	return booking, nil
+}
+
+// TrackBooking returns the status of the booking with the given ID as reported
+// by its carrier; ErrBookingNotDispatched is returned when the booking has not
+// been handed over to the carrier.
+func (s *Service) TrackBooking(id string) (*TrackingInfo, error) {
+	s.logger.Printf("executing TrackBooking with args: %v\n", id)
+
+	booking, err := s.GetBooking(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if booking.CarrierReference == "" {
+		return nil, ErrBookingNotDispatched
+	}
+
+	dispatcher, found, err := s.findCarrierDispatcher(booking.CarrierName)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrBookingNotDispatched
+	}
+
+	trackingInfo, err := dispatcher.Track(booking.CarrierReference)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDispatchFailed, err)
+	}
+
+	return trackingInfo, nil
+}
+
+// dispatchBooking books the given booking with its carrier when it moves to
+// the confirmed status, setting its CarrierReference, and cancels it with the
+// carrier when it moves to the cancelled one; nothing is done for carriers
+// having no CarrierDispatcher, and for bookings cancelled before being handed
+// over to the carrier.
+func (s *Service) dispatchBooking(booking *Booking, status BookingStatus) error {
+	switch {
+	case status != BookingStatusConfirmed && status != BookingStatusCancelled:
+		return nil
+	case status == BookingStatusCancelled && booking.CarrierReference == "":
+		// the job has never been handed over to the carrier
+		return nil
+	}
+
+	dispatcher, found, err := s.findCarrierDispatcher(booking.CarrierName)
+	if err != nil || !found {
+		return err
+	}
+
+	switch status {
+	case BookingStatusConfirmed:
+		reference, err := dispatcher.Book(&DispatchJob{
+			BookingID:        booking.ID,
+			CarrierName:      booking.CarrierName,
+			ServiceID:        booking.ServiceID,
+			Vehicle:          booking.Vehicle,
+			PickupPostcode:   booking.PickupPostcode,
+			DeliveryPostcode: booking.DeliveryPostcode,
+			PickupTime:       booking.PickupTime,
+			Price:            booking.Price,
+		})
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrDispatchFailed, err)
+		}
+		booking.CarrierReference = reference
+	case BookingStatusCancelled:
+		err = dispatcher.Cancel(booking.CarrierReference)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrDispatchFailed, err)
+		}
+	}
+
+	return nil
+}
+
+// bookingLocks serialises the transitions of each booking, without making the
+// ones of different bookings wait for each other, e.g. while their carriers are
+// booking the jobs. Locks are kept only while bookings are being updated.
+type bookingLocks struct {
+	mutex sync.Mutex
+	locks map[string]*bookingLock
+}
+
+type bookingLock struct {
+	sync.Mutex
+	holders int
+}
+
+// lock locks the booking with the given ID, waiting for the update in progress,
+// if any; the returned function unlocks it.
+func (bl *bookingLocks) lock(id string) func() {
+	bl.mutex.Lock()
+	if bl.locks == nil {
+		bl.locks = map[string]*bookingLock{}
+	}
+	lock, exists := bl.locks[id]
+	if !exists {
+		lock = &bookingLock{}
+		bl.locks[id] = lock
+	}
+	lock.holders++
+	bl.mutex.Unlock()
+
+	lock.Lock()
+
+	return func() {
+		lock.Unlock()
+
+		bl.mutex.Lock()
+		lock.holders--
+		if lock.holders == 0 {
+			delete(bl.locks, id)
+		}
+		bl.mutex.Unlock()
+	}
+}
+
+// canMoveTo returns true if a booking can move from the BookingStatus to the given one.
+func (bs BookingStatus) canMoveTo(status BookingStatus) bool {
+	for _, allowed := range bookingTransitions[bs] {
+		if allowed == status {
+			return true
+		}
+	}
+	return false
+}
+
+// findPriceByCarrier returns the element of the given price list for the
+// given service of the given carrier, whose name is case insensitive.
+func findPriceByCarrier(priceList PriceByCarrierList, carrierName, serviceID string) (PriceByCarrier, bool) {
+	for _, priceByCarrier := range priceList {
+		if strings.EqualFold(priceByCarrier.CarrierName, carrierName) && priceByCarrier.ServiceID == serviceID {
+			return priceByCarrier, true
+		}
+	}
+	return PriceByCarrier{}, false
+}
@@ -0,0 +1,651 @@
+package carrierpricing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCreateBooking(t *testing.T) {
+	tests := []struct {
+		Description       string
+		Args              CreateBookingArgs
+		ChangeService     func(service *Service, carrierServices *mockCarrierServiceList)
+		ExpectedServiceID string
+		ExpectedPrice     Money
+		ExpectedError     error
+	}{
+		{
+			Description:       "booking at the quoted price",
+			Args:              CreateBookingArgs{CarrierName: "MockService2", ServiceID: "1"},
+			ExpectedServiceID: "1",
+			ExpectedPrice:     gbp(421),
+		},
+		{
+			Description:       "booking another service of the same carrier",
+			Args:              CreateBookingArgs{CarrierName: "MockService2", ServiceID: "express"},
+			ExpectedServiceID: "express",
+			ExpectedPrice:     gbp(441),
+		},
+		{
+			Description: "lower current price, the quoted price is kept",
+			Args:        CreateBookingArgs{CarrierName: "mockservice2", ServiceID: "1"},
+			ChangeService: func(service *Service, carrierServices *mockCarrierServiceList) {
+				(*carrierServices)[1].Markup = gbp(0)
+			},
+			ExpectedServiceID: "1",
+			ExpectedPrice:     gbp(421),
+		},
+		{
+			Description: "higher current price",
+			Args:        CreateBookingArgs{CarrierName: "MockService2", ServiceID: "1"},
+			ChangeService: func(service *Service, carrierServices *mockCarrierServiceList) {
+				(*carrierServices)[1].Markup = gbp(50)
+			},
+			ExpectedError: &PriceChangedError{QuotedPrice: gbp(421), CurrentPrice: gbp(461)},
+		},
+		{
+			Description: "carrier no longer available",
+			Args:        CreateBookingArgs{CarrierName: "MockService2", ServiceID: "1"},
+			ChangeService: func(service *Service, carrierServices *mockCarrierServiceList) {
+				*carrierServices = (*carrierServices)[:1]
+			},
+			ExpectedError: ErrCarrierNotAvailable,
+		},
+		{
+			Description: "service of the carrier no longer available",
+			Args:        CreateBookingArgs{CarrierName: "MockService2", ServiceID: "express"},
+			ChangeService: func(service *Service, carrierServices *mockCarrierServiceList) {
+				*carrierServices = (*carrierServices)[:2]
+			},
+			ExpectedError: ErrCarrierNotAvailable,
+		},
+		{
+			Description:   "carrier not in the quote",
+			Args:          CreateBookingArgs{CarrierName: "MockService3", ServiceID: "1"},
+			ExpectedError: errors.New("carrier service not listed in the quote: \"MockService3\", service \"1\""),
+		},
+		{
+			Description:   "service not in the quote",
+			Args:          CreateBookingArgs{CarrierName: "MockService2", ServiceID: "2"},
+			ExpectedError: errors.New("carrier service not listed in the quote: \"MockService2\", service \"2\""),
+		},
+		{
+			Description:   "missing service",
+			Args:          CreateBookingArgs{CarrierName: "MockService2"},
+			ExpectedError: errors.New("service_id must be provided"),
+		},
+		{
+			Description:   "unknown quote",
+			Args:          CreateBookingArgs{QuoteID: "unknown", CarrierName: "MockService2", ServiceID: "1"},
+			ExpectedError: ErrQuoteNotFound,
+		},
+		{
+			Description: "expired quote",
+			Args:        CreateBookingArgs{CarrierName: "MockService2", ServiceID: "1"},
+			ChangeService: func(service *Service, carrierServices *mockCarrierServiceList) {
+				service.now = func() time.Time { return time.Date(2026, 10, 15, 11, 0, 0, 0, time.UTC) }
+			},
+			ExpectedError: ErrQuoteExpired,
+		},
+	}
+
+	for _, tc := range tests {
+		carrierServices := &mockCarrierServiceList{
+			CarrierService{Name: "MockService1", Markup: gbp(20), DeliveryTime: workingDays(1)},
+			CarrierService{Name: "MockService2", Markup: gbp(10), DeliveryTime: workingDays(5)},
+			CarrierService{Name: "MockService2", ServiceID: "express", Markup: gbp(30), DeliveryTime: workingDays(1)},
+		}
+
+		service, err := NewService(
+			log.New(ioutil.Discard, "", 0),
+			carrierServices,
+			&mockDistanceCalculator{},
+			nil,
+			WithQuoteStore(&mockQuoteStore{quotes: map[string]StoredQuote{}}, 15*time.Minute),
+			WithBookingStore(&mockBookingStore{bookings: map[string]Booking{}}),
+		)
+		if err != nil {
+			t.Fatalf("%s: NewService returned error %v", tc.Description, err)
+		}
+		service.now = func() time.Time { return time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC) }
+
+		quote, err := service.GetQuotesByCarrier(context.Background(), GetQuotesByCarrierArgs{
+			PickupPostcode:   "SW1A1AA",
+			DeliveryPostcode: "EC2A3LT",
+			Vehicle:          VehicleTypeSmallVan,
+		})
+		if err != nil {
+			t.Fatalf("%s: GetQuotesByCarrier returned error %v", tc.Description, err)
+		}
+
+		if tc.ChangeService != nil {
+			tc.ChangeService(service, carrierServices)
+		}
+
+		if tc.Args.QuoteID == "" {
+			tc.Args.QuoteID = quote.QuoteID
+		}
+
+		booking, err := service.CreateBooking(context.Background(), tc.Args)
+
+		if tc.ExpectedError != nil {
+			var priceChangedError *PriceChangedError
+			switch {
+			case errors.As(tc.ExpectedError, &priceChangedError):
+				var receivedError *PriceChangedError
+				if !errors.As(err, &receivedError) || !reflect.DeepEqual(priceChangedError, receivedError) {
+					t.Fatalf("%s: expected error '%v', received: '%v'", tc.Description, tc.ExpectedError, err)
+				}
+			case err == nil || err.Error() != tc.ExpectedError.Error():
+				t.Fatalf("%s: expected error '%v', received: '%v'", tc.Description, tc.ExpectedError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: CreateBooking returned error %v", tc.Description, err)
+		}
+
+		if booking.Price != tc.ExpectedPrice || booking.CarrierName != "MockService2" || booking.ServiceID != tc.ExpectedServiceID || booking.Status != BookingStatusPending {
+			t.Fatalf("%s: unexpected booking %+v", tc.Description, booking)
+		}
+
+		storedBooking, err := service.GetBooking(booking.ID)
+		if err != nil || !reflect.DeepEqual(booking, storedBooking) {
+			t.Fatalf("%s: expected stored booking '%+v', received: '%+v' (%v)", tc.Description, booking, storedBooking, err)
+		}
+	}
+}
+
+func TestCreateBookingTwice(t *testing.T) {
+	service, err := NewService(
+		log.New(ioutil.Discard, "", 0),
+		&mockCarrierServiceList{
+			CarrierService{Name: "MockService1", Markup: gbp(20), DeliveryTime: workingDays(1)},
+			CarrierService{Name: "MockService2", Markup: gbp(10), DeliveryTime: workingDays(5)},
+		},
+		&mockDistanceCalculator{},
+		nil,
+		WithQuoteStore(&mockQuoteStore{quotes: map[string]StoredQuote{}}, 15*time.Minute),
+		WithBookingStore(&mockBookingStore{bookings: map[string]Booking{}}),
+	)
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
+
+	quote, err := service.GetQuotesByCarrier(context.Background(), GetQuotesByCarrierArgs{
+		PickupPostcode:   "SW1A1AA",
+		DeliveryPostcode: "EC2A3LT",
+		Vehicle:          VehicleTypeSmallVan,
+	})
+	if err != nil {
+		t.Fatalf("GetQuotesByCarrier returned error %v", err)
+	}
+
+	args := CreateBookingArgs{QuoteID: quote.QuoteID, CarrierName: "MockService1", ServiceID: "1"}
+	_, err = service.CreateBooking(context.Background(), args)
+	if err != nil {
+		t.Fatalf("CreateBooking returned error %v", err)
+	}
+
+	// the carrier name is matched regardless of its case, as when booking
+	args.CarrierName = "mockservice1"
+	_, err = service.CreateBooking(context.Background(), args)
+	if !errors.Is(err, ErrQuoteAlreadyBooked) {
+		t.Fatalf("expected error '%v', received: '%v'", ErrQuoteAlreadyBooked, err)
+	}
+
+	// other carrier services of the same quote can still be booked
+	_, err = service.CreateBooking(context.Background(), CreateBookingArgs{QuoteID: quote.QuoteID, CarrierName: "MockService2", ServiceID: "1"})
+	if err != nil {
+		t.Fatalf("CreateBooking returned error %v", err)
+	}
+}
+
+func TestUpdateBookingStatus(t *testing.T) {
+	tests := []struct {
+		Statuses      []BookingStatus
+		ExpectedError string
+	}{
+		// case #1 the whole lifecycle
+		{
+			Statuses: []BookingStatus{BookingStatusConfirmed, BookingStatusCollected, BookingStatusDelivered},
+		},
+		// case #2 cancelled before the collection
+		{
+			Statuses: []BookingStatus{BookingStatusConfirmed, BookingStatusCancelled},
+		},
+		// case #3 collected without being confirmed
+		{
+			Statuses:      []BookingStatus{BookingStatusCollected},
+			ExpectedError: "invalid booking status transition from pending to \"collected\"",
+		},
+		// case #4 cancelled after the collection
+		{
+			Statuses:      []BookingStatus{BookingStatusConfirmed, BookingStatusCollected, BookingStatusCancelled},
+			ExpectedError: "invalid booking status transition from collected to \"cancelled\"",
+		},
+		// case #5 delivered bookings cannot change
+		{
+			Statuses:      []BookingStatus{BookingStatusConfirmed, BookingStatusCollected, BookingStatusDelivered, BookingStatusPending},
+			ExpectedError: "invalid booking status transition from delivered to \"pending\"",
+		},
+		// case #6 unknown status
+		{
+			Statuses:      []BookingStatus{"lost"},
+			ExpectedError: "invalid booking status transition from pending to \"lost\"",
+		},
+	}
+
+	service, err := NewService(
+		log.New(ioutil.Discard, "", 0),
+		&mockCarrierServiceList{
+			CarrierService{Name: "MockService1", Markup: gbp(20), DeliveryTime: workingDays(1)},
+			CarrierService{Name: "MockService2", Markup: gbp(10), DeliveryTime: workingDays(5)},
+		},
+		&mockDistanceCalculator{},
+		nil,
+		WithQuoteStore(&mockQuoteStore{quotes: map[string]StoredQuote{}}, 15*time.Minute),
+		WithBookingStore(&mockBookingStore{bookings: map[string]Booking{}}),
+	)
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
+	service.now = func() time.Time { return time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC) }
+
+	for i, tc := range tests {
+		quote, err := service.GetQuotesByCarrier(context.Background(), GetQuotesByCarrierArgs{
+			PickupPostcode:   "SW1A1AA",
+			DeliveryPostcode: "EC2A3LT",
+			Vehicle:          VehicleTypeSmallVan,
+		})
+		if err != nil {
+			t.Fatalf("case #%d: GetQuotesByCarrier returned error %v", i+1, err)
+		}
+
+		booking, err := service.CreateBooking(context.Background(), CreateBookingArgs{QuoteID: quote.QuoteID, CarrierName: "MockService1", ServiceID: "1"})
+		if err != nil {
+			t.Fatalf("case #%d: CreateBooking returned error %v", i+1, err)
+		}
+
+		for _, status := range tc.Statuses {
+			booking, err = service.UpdateBookingStatus(UpdateBookingStatusArgs{BookingID: booking.ID, Status: status})
+			if err != nil {
+				break
+			}
+		}
+
+		if tc.ExpectedError != "" {
+			if err == nil || err.Error() != tc.ExpectedError || !errors.Is(err, ErrInvalidBookingTransition) {
+				t.Fatalf("case #%d: expected error '%s', received: '%v'", i+1, tc.ExpectedError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("case #%d: UpdateBookingStatus returned error %v", i+1, err)
+		}
+
+		expectedStatus := tc.Statuses[len(tc.Statuses)-1]
+		if booking.Status != expectedStatus || len(booking.History) != len(tc.Statuses)+1 {
+			t.Fatalf("case #%d: expected status %s after %d events, received: %+v", i+1, expectedStatus, len(tc.Statuses)+1, booking)
+		}
+	}
+
+	_, err = service.UpdateBookingStatus(UpdateBookingStatusArgs{BookingID: "unknown", Status: BookingStatusConfirmed})
+	if !errors.Is(err, ErrBookingNotFound) {
+		t.Fatalf("expected error '%v', received: '%v'", ErrBookingNotFound, err)
+	}
+}
+
+func TestDispatchBooking(t *testing.T) {
+	tests := []struct {
+		Description       string
+		Statuses          []BookingStatus
+		Dispatcher        *mockCarrierDispatcher
+		RegistryError     error
+		ExpectedReference string
+		ExpectedJobs      []string
+		ExpectedError     error
+	}{
+		{
+			Description: "carrier without dispatcher",
+			Statuses:    []BookingStatus{BookingStatusConfirmed},
+		},
+		{
+			Description:       "confirmed booking is booked with the carrier",
+			Statuses:          []BookingStatus{BookingStatusConfirmed},
+			Dispatcher:        &mockCarrierDispatcher{jobs: map[string]string{}},
+			ExpectedReference: "REF-1",
+			ExpectedJobs:      []string{"booked"},
+		},
+		{
+			Description:       "cancelled booking is cancelled with the carrier",
+			Statuses:          []BookingStatus{BookingStatusConfirmed, BookingStatusCancelled},
+			Dispatcher:        &mockCarrierDispatcher{jobs: map[string]string{}},
+			ExpectedReference: "REF-1",
+			ExpectedJobs:      []string{"cancelled"},
+		},
+		{
+			Description:  "pending booking cancelled without dispatching it",
+			Statuses:     []BookingStatus{BookingStatusCancelled},
+			Dispatcher:   &mockCarrierDispatcher{jobs: map[string]string{}},
+			ExpectedJobs: []string{},
+		},
+		{
+			Description:   "carrier failing to book the job",
+			Statuses:      []BookingStatus{BookingStatusConfirmed},
+			Dispatcher:    &mockCarrierDispatcher{jobs: map[string]string{}, err: errors.New("carrier down")},
+			ExpectedError: ErrDispatchFailed,
+		},
+		{
+			Description:   "carrier with an invalid dispatcher",
+			Statuses:      []BookingStatus{BookingStatusConfirmed},
+			RegistryError: errors.New("invalid dispatcher"),
+			ExpectedError: ErrDispatchFailed,
+		},
+		{
+			Description:   "pending booking of a carrier with an invalid dispatcher cancelled",
+			Statuses:      []BookingStatus{BookingStatusCancelled},
+			RegistryError: errors.New("invalid dispatcher"),
+		},
+	}
+
+	for _, tc := range tests {
+		var registry CarrierDispatcherRegistry = mockCarrierDispatcherRegistry{}
+		switch {
+		case tc.Dispatcher != nil:
+			registry = mockCarrierDispatcherRegistry{"MockService1": tc.Dispatcher}
+		case tc.RegistryError != nil:
+			registry = &mockFailingCarrierDispatcherRegistry{err: tc.RegistryError}
+		}
+
+		service, err := NewService(
+			log.New(ioutil.Discard, "", 0),
+			&mockCarrierServiceList{
+				CarrierService{Name: "MockService1", Markup: gbp(20), DeliveryTime: workingDays(1)},
+				CarrierService{Name: "MockService2", Markup: gbp(10), DeliveryTime: workingDays(5)},
+			},
+			&mockDistanceCalculator{},
+			nil,
+			WithQuoteStore(&mockQuoteStore{quotes: map[string]StoredQuote{}}, 15*time.Minute),
+			WithBookingStore(&mockBookingStore{bookings: map[string]Booking{}}),
+			WithCarrierDispatchers(registry),
+		)
+		if err != nil {
+			t.Fatalf("%s: NewService returned error %v", tc.Description, err)
+		}
+		service.now = func() time.Time { return time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC) }
+
+		quote, err := service.GetQuotesByCarrier(context.Background(), GetQuotesByCarrierArgs{
+			PickupPostcode:   "SW1A1AA",
+			DeliveryPostcode: "EC2A3LT",
+			Vehicle:          VehicleTypeSmallVan,
+		})
+		if err != nil {
+			t.Fatalf("%s: GetQuotesByCarrier returned error %v", tc.Description, err)
+		}
+
+		booking, err := service.CreateBooking(context.Background(), CreateBookingArgs{QuoteID: quote.QuoteID, CarrierName: "MockService1", ServiceID: "1"})
+		if err != nil {
+			t.Fatalf("%s: CreateBooking returned error %v", tc.Description, err)
+		}
+
+		for _, status := range tc.Statuses {
+			_, err = service.UpdateBookingStatus(UpdateBookingStatusArgs{BookingID: booking.ID, Status: status})
+			if err != nil {
+				break
+			}
+		}
+
+		storedBooking, _ := service.GetBooking(booking.ID)
+
+		if tc.ExpectedError != nil {
+			if !errors.Is(err, tc.ExpectedError) {
+				t.Fatalf("%s: expected error '%v', received: '%v'", tc.Description, tc.ExpectedError, err)
+			}
+			if storedBooking.Status != BookingStatusPending {
+				t.Fatalf("%s: expected the booking to stay pending, received: %s", tc.Description, storedBooking.Status)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: UpdateBookingStatus returned error %v", tc.Description, err)
+		}
+
+		if storedBooking.CarrierReference != tc.ExpectedReference {
+			t.Fatalf("%s: expected carrier reference '%s', received: '%s'", tc.Description, tc.ExpectedReference, storedBooking.CarrierReference)
+		}
+
+		if tc.Dispatcher != nil {
+			jobs := []string{}
+			for _, status := range tc.Dispatcher.jobs {
+				jobs = append(jobs, status)
+			}
+			if !reflect.DeepEqual(jobs, tc.ExpectedJobs) {
+				t.Fatalf("%s: expected jobs %v, received: %v", tc.Description, tc.ExpectedJobs, jobs)
+			}
+		}
+	}
+}
+
+func TestUpdateBookingStatusWhileDispatching(t *testing.T) {
+	dispatcher := &mockCarrierDispatcher{
+		jobs:    map[string]string{},
+		booking: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+
+	service, err := NewService(
+		log.New(ioutil.Discard, "", 0),
+		&mockCarrierServiceList{
+			CarrierService{Name: "MockService1", Markup: gbp(20), DeliveryTime: workingDays(1)},
+			CarrierService{Name: "MockService2", Markup: gbp(10), DeliveryTime: workingDays(5)},
+		},
+		&mockDistanceCalculator{},
+		nil,
+		WithQuoteStore(&mockQuoteStore{quotes: map[string]StoredQuote{}}, 15*time.Minute),
+		WithBookingStore(&mockBookingStore{bookings: map[string]Booking{}}),
+		WithCarrierDispatchers(mockCarrierDispatcherRegistry{"MockService1": dispatcher}),
+	)
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
+	service.now = func() time.Time { return time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC) }
+
+	quote, err := service.GetQuotesByCarrier(context.Background(), GetQuotesByCarrierArgs{
+		PickupPostcode:   "SW1A1AA",
+		DeliveryPostcode: "EC2A3LT",
+		Vehicle:          VehicleTypeSmallVan,
+	})
+	if err != nil {
+		t.Fatalf("GetQuotesByCarrier returned error %v", err)
+	}
+
+	slowBooking, err := service.CreateBooking(context.Background(), CreateBookingArgs{QuoteID: quote.QuoteID, CarrierName: "MockService1", ServiceID: "1"})
+	if err != nil {
+		t.Fatalf("CreateBooking returned error %v", err)
+	}
+	otherBooking, err := service.CreateBooking(context.Background(), CreateBookingArgs{QuoteID: quote.QuoteID, CarrierName: "MockService2", ServiceID: "1"})
+	if err != nil {
+		t.Fatalf("CreateBooking returned error %v", err)
+	}
+
+	slowUpdate := make(chan error)
+	go func() {
+		_, err := service.UpdateBookingStatus(UpdateBookingStatusArgs{BookingID: slowBooking.ID, Status: BookingStatusConfirmed})
+		slowUpdate <- err
+	}()
+	<-dispatcher.booking
+
+	// the carrier of the first booking is still booking the job
+	_, err = service.UpdateBookingStatus(UpdateBookingStatusArgs{BookingID: otherBooking.ID, Status: BookingStatusConfirmed})
+	if err != nil {
+		t.Fatalf("UpdateBookingStatus returned error %v", err)
+	}
+
+	// updates of the same booking wait for the one in progress
+	concurrentUpdate := make(chan error)
+	go func() {
+		_, err := service.UpdateBookingStatus(UpdateBookingStatusArgs{BookingID: slowBooking.ID, Status: BookingStatusConfirmed})
+		concurrentUpdate <- err
+	}()
+
+	close(dispatcher.release)
+
+	err = <-slowUpdate
+	if err != nil {
+		t.Fatalf("UpdateBookingStatus returned error %v", err)
+	}
+	err = <-concurrentUpdate
+	if !errors.Is(err, ErrInvalidBookingTransition) {
+		t.Fatalf("expected error '%v', received: '%v'", ErrInvalidBookingTransition, err)
+	}
+}
+
+func TestTrackBooking(t *testing.T) {
+	dispatcher := &mockCarrierDispatcher{jobs: map[string]string{}}
+
+	service, err := NewService(
+		log.New(ioutil.Discard, "", 0),
+		&mockCarrierServiceList{
+			CarrierService{Name: "MockService1", Markup: gbp(20), DeliveryTime: workingDays(1)},
+			CarrierService{Name: "MockService2", Markup: gbp(10), DeliveryTime: workingDays(5)},
+		},
+		&mockDistanceCalculator{},
+		nil,
+		WithQuoteStore(&mockQuoteStore{quotes: map[string]StoredQuote{}}, 15*time.Minute),
+		WithBookingStore(&mockBookingStore{bookings: map[string]Booking{}}),
+		WithCarrierDispatchers(mockCarrierDispatcherRegistry{"MockService1": dispatcher}),
+	)
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
+	service.now = func() time.Time { return time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC) }
+
+	quote, err := service.GetQuotesByCarrier(context.Background(), GetQuotesByCarrierArgs{
+		PickupPostcode:   "SW1A1AA",
+		DeliveryPostcode: "EC2A3LT",
+		Vehicle:          VehicleTypeSmallVan,
+	})
+	if err != nil {
+		t.Fatalf("GetQuotesByCarrier returned error %v", err)
+	}
+
+	booking, err := service.CreateBooking(context.Background(), CreateBookingArgs{QuoteID: quote.QuoteID, CarrierName: "MockService1", ServiceID: "1"})
+	if err != nil {
+		t.Fatalf("CreateBooking returned error %v", err)
+	}
+
+	_, err = service.TrackBooking(booking.ID)
+	if !errors.Is(err, ErrBookingNotDispatched) {
+		t.Fatalf("expected error '%v', received: '%v'", ErrBookingNotDispatched, err)
+	}
+
+	_, err = service.UpdateBookingStatus(UpdateBookingStatusArgs{BookingID: booking.ID, Status: BookingStatusConfirmed})
+	if err != nil {
+		t.Fatalf("UpdateBookingStatus returned error %v", err)
+	}
+
+	trackingInfo, err := service.TrackBooking(booking.ID)
+	if err != nil {
+		t.Fatalf("TrackBooking returned error %v", err)
+	}
+	if trackingInfo.Reference != "REF-1" || trackingInfo.Status != "booked" {
+		t.Fatalf("unexpected tracking info '%+v'", trackingInfo)
+	}
+
+	_, err = service.TrackBooking("unknown")
+	if !errors.Is(err, ErrBookingNotFound) {
+		t.Fatalf("expected error '%v', received: '%v'", ErrBookingNotFound, err)
+	}
+}
+
+type mockBookingStore struct {
+	bookings map[string]Booking
+}
+
+func (mbs *mockBookingStore) CreateBooking(booking *Booking) error {
+	for _, stored := range mbs.bookings {
+		if stored.QuoteID == booking.QuoteID && stored.CarrierName == booking.CarrierName && stored.ServiceID == booking.ServiceID {
+			return ErrQuoteAlreadyBooked
+		}
+	}
+	return mbs.SaveBooking(booking)
+}
+
+func (mbs *mockBookingStore) SaveBooking(booking *Booking) error {
+	stored := *booking
+	stored.History = append([]BookingEvent{}, booking.History...)
+	mbs.bookings[booking.ID] = stored
+	return nil
+}
+
+func (mbs *mockBookingStore) GetBooking(id string) (*Booking, error) {
+	booking, exists := mbs.bookings[id]
+	if !exists {
+		return nil, ErrBookingNotFound
+	}
+	booking.History = append([]BookingEvent{}, booking.History...)
+	return &booking, nil
+}
+
+type mockCarrierDispatcherRegistry map[string]CarrierDispatcher
+
+func (mcdr mockCarrierDispatcherRegistry) FindCarrierDispatcher(carrierName string) (CarrierDispatcher, bool, error) {
+	dispatcher, found := mcdr[carrierName]
+	return dispatcher, found, nil
+}
+
+// mockFailingCarrierDispatcherRegistry cannot create the dispatcher of any
+// carrier, returning err.
+type mockFailingCarrierDispatcherRegistry struct {
+	err error
+}
+
+func (mfcdr *mockFailingCarrierDispatcherRegistry) FindCarrierDispatcher(carrierName string) (CarrierDispatcher, bool, error) {
+	return nil, false, mfcdr.err
+}
+
+// mockCarrierDispatcher keeps the status of the jobs by reference; every call
+// fails with err, when set.
+type mockCarrierDispatcher struct {
+	jobs map[string]string
+	err  error
+
+	// when set, jobs are booked once release is closed, after signalling on booking
+	booking chan struct{}
+	release chan struct{}
+}
+
+func (mcd *mockCarrierDispatcher) Book(job *DispatchJob) (string, error) {
+	if mcd.release != nil {
+		mcd.booking <- struct{}{}
+		<-mcd.release
+	}
+	if mcd.err != nil {
+		return "", mcd.err
+	}
+	reference := fmt.Sprintf("REF-%d", len(mcd.jobs)+1)
+	mcd.jobs[reference] = "booked"
+	return reference, nil
+}
+
+func (mcd *mockCarrierDispatcher) Cancel(reference string) error {
+	if mcd.err != nil {
+		return mcd.err
+	}
+	mcd.jobs[reference] = "cancelled"
+	return nil
+}
+
+func (mcd *mockCarrierDispatcher) Track(reference string) (*TrackingInfo, error) {
+	if mcd.err != nil {
+		return nil, mcd.err
+	}
+	return &TrackingInfo{Reference: reference, Status: mcd.jobs[reference]}, nil
+}
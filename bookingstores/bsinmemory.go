@@ -0,0 +1,76 @@
+package bookingstores
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/giefferre/carrierpricing"
+)
+
+// BSInMemory implements the carrierpricing.BookingStore interface keeping the
+// bookings in memory; bookings are lost when the application stops.
+type BSInMemory struct {
+	mutex    sync.RWMutex
+	bookings map[string]carrierpricing.Booking
+	// booked maps the quoted carrier services booked to their booking ID
+	booked map[string]string
+}
+
+// NewBSInMemory returns a new, empty, BSInMemory object.
+func NewBSInMemory() *BSInMemory {
+	return &BSInMemory{
+		bookings: map[string]carrierpricing.Booking{},
+		booked:   map[string]string{},
+	}
+}
+
+// CreateBooking stores a copy of the given booking, unless the quoted carrier
+// service has already been booked.
+func (bs *BSInMemory) CreateBooking(booking *carrierpricing.Booking) error {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	key := bookedServiceKey(booking)
+	if id, exists := bs.booked[key]; exists {
+		return fmt.Errorf("%w: booking %s", carrierpricing.ErrQuoteAlreadyBooked, id)
+	}
+
+	bs.booked[key] = booking.ID
+	bs.bookings[booking.ID] = copyBooking(*booking)
+	return nil
+}
+
+// SaveBooking stores a copy of the given booking, replacing the one with the same ID.
+func (bs *BSInMemory) SaveBooking(booking *carrierpricing.Booking) error {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	bs.bookings[booking.ID] = copyBooking(*booking)
+	return nil
+}
+
+// GetBooking returns a copy of the booking with the given ID.
+func (bs *BSInMemory) GetBooking(id string) (*carrierpricing.Booking, error) {
+	bs.mutex.RLock()
+	defer bs.mutex.RUnlock()
+
+	booking, exists := bs.bookings[id]
+	if !exists {
+		return nil, carrierpricing.ErrBookingNotFound
+	}
+
+	booking = copyBooking(booking)
+	return &booking, nil
+}
+
+// copyBooking returns a copy of the given booking not sharing its history, so
+// that changes to the stored booking can only be made via SaveBooking.
+func copyBooking(booking carrierpricing.Booking) carrierpricing.Booking {
+	booking.History = append([]carrierpricing.BookingEvent{}, booking.History...)
+	return booking
+}
+
+// bookedServiceKey identifies the quoted carrier service of the given booking.
+func bookedServiceKey(booking *carrierpricing.Booking) string {
+	return booking.QuoteID + "|" + booking.CarrierName + "|" + booking.ServiceID
+}
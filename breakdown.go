@@ -0,0 +1,138 @@
+package carrierpricing
+
+const (
+	surchargeMinimumCharge = "minimum charge"
+)
+
+// PriceBreakdown itemises how a price has been calculated. All the amounts,
+// with discounts being subtracted, add up to Total; RoundingAdjustment accounts
+// for the difference between the sum of the single items, each one rounded to
+// the nearest minor unit, and the total actually charged. All the amounts are
+// expressed in the same currency.
+type PriceBreakdown struct {
+	BasePrice          Money             `json:"base_price"`
+	VehicleMultiplier  float64           `json:"vehicle_multiplier"`
+	VehicleMarkup      Money             `json:"vehicle_markup"`
+	CarrierBasePrice   Money             `json:"carrier_base_price"`
+	ServiceMarkup      Money             `json:"service_markup"`
+	Surcharges         []PriceAdjustment `json:"surcharges"`
+	Discounts          []PriceAdjustment `json:"discounts"`
+	RoundingAdjustment Money             `json:"rounding_adjustment"`
+	Total              Money             `json:"total"`
+}
+
+// PriceAdjustment is a surcharge or a discount listed in a PriceBreakdown.
+type PriceAdjustment struct {
+	Description string `json:"description"`
+	Amount      Money  `json:"amount"`
+}
+
+// basePrice is the price of a delivery before any markup.
+type basePrice struct {
+	// exact is the sum of all the price components, before any rounding.
+	exact float64
+
+	// amount is the rounded price, including the minimum charge.
+	amount int64
+
+	// minimumChargeSurcharge is the amount added to reach the minimum charge.
+	minimumChargeSurcharge int64
+}
+
+// newPriceBreakdown returns the PriceBreakdown of a price obtained applying the
+// markup of the given vehicle type and the given surcharges to the given base
+// price; vehicleType is empty when no vehicle markup has been applied.
+func newPriceBreakdown(rules *PricingRules, base basePrice, vehicleType string, surcharges []PriceAdjustment, total int64) *PriceBreakdown {
+	breakdown := &PriceBreakdown{
+		BasePrice:         rules.money(int64(RoundingModeHalfEven.Round(base.exact))),
+		VehicleMultiplier: 1,
+		VehicleMarkup:     rules.money(0),
+		CarrierBasePrice:  rules.money(0),
+		ServiceMarkup:     rules.money(0),
+		Surcharges:        []PriceAdjustment{},
+		Discounts:         []PriceAdjustment{},
+		Total:             rules.money(total),
+	}
+
+	if base.minimumChargeSurcharge > 0 {
+		breakdown.Surcharges = append(breakdown.Surcharges, PriceAdjustment{
+			Description: surchargeMinimumCharge,
+			Amount:      rules.money(base.minimumChargeSurcharge),
+		})
+	}
+	breakdown.Surcharges = append(breakdown.Surcharges, surcharges...)
+
+	if vehicleType != "" {
+		breakdown.VehicleMultiplier = rules.vehicleMultiplier(vehicleType)
+		breakdown.VehicleMarkup = rules.money(
+			multiplyMinorUnits(base.amount, breakdown.VehicleMultiplier, RoundingModeHalfEven) - base.amount,
+		)
+	}
+
+	breakdown.RoundingAdjustment = rules.money(total - breakdown.sumOfItems())
+
+	return breakdown
+}
+
+// withCarrierService returns a copy of the PriceBreakdown including the markup
+// of the given carrier service.
+func (pb *PriceBreakdown) withCarrierService(carrierService CarrierService) *PriceBreakdown {
+	breakdown := pb.copy()
+
+	breakdown.CarrierBasePrice, breakdown.ServiceMarkup = carrierService.markupComponents()
+	breakdown.Total.Amount += carrierService.Markup.Amount
+
+	return breakdown
+}
+
+// add sums all the items of the other PriceBreakdown to this one; surcharges and
+// discounts with the same description are merged together.
+func (pb *PriceBreakdown) add(other *PriceBreakdown) {
+	pb.BasePrice.Amount += other.BasePrice.Amount
+	pb.VehicleMarkup.Amount += other.VehicleMarkup.Amount
+	pb.CarrierBasePrice.Amount += other.CarrierBasePrice.Amount
+	pb.ServiceMarkup.Amount += other.ServiceMarkup.Amount
+	pb.Surcharges = mergePriceAdjustments(pb.Surcharges, other.Surcharges)
+	pb.Discounts = mergePriceAdjustments(pb.Discounts, other.Discounts)
+	pb.RoundingAdjustment.Amount += other.RoundingAdjustment.Amount
+	pb.Total.Amount += other.Total.Amount
+}
+
+func (pb *PriceBreakdown) copy() *PriceBreakdown {
+	breakdown := *pb
+	breakdown.Surcharges = append([]PriceAdjustment{}, pb.Surcharges...)
+	breakdown.Discounts = append([]PriceAdjustment{}, pb.Discounts...)
+	return &breakdown
+}
+
+// sumOfItems adds up all the items of the PriceBreakdown except the rounding adjustment.
+func (pb *PriceBreakdown) sumOfItems() int64 {
+	sum := pb.BasePrice.Amount + pb.VehicleMarkup.Amount + pb.CarrierBasePrice.Amount + pb.ServiceMarkup.Amount
+	for _, surcharge := range pb.Surcharges {
+		sum += surcharge.Amount.Amount
+	}
+	for _, discount := range pb.Discounts {
+		sum -= discount.Amount.Amount
+	}
+	return sum
+}
+
+func mergePriceAdjustments(adjustments, others []PriceAdjustment) []PriceAdjustment {
+	merged := append([]PriceAdjustment{}, adjustments...)
+
+	for _, other := range others {
+		found := false
+		for i := range merged {
+			if merged[i].Description == other.Description {
+				merged[i].Amount.Amount += other.Amount.Amount
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, other)
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,153 @@
+package carrierpricing
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"testing"
+)
+
+func TestGetQuotesByCarrierBreakdown(t *testing.T) {
+	// tests that each price of the list is itemised when requested
+	logger := log.New(ioutil.Discard, "", 0)
+	service, err := NewService(logger, &mockCarrierServiceFinder{}, &mockDistanceCalculator{}, nil)
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
+
+	result, err := service.GetQuotesByCarrier(context.Background(), GetQuotesByCarrierArgs{
+		PickupPostcode:   "SW1A1AA",
+		DeliveryPostcode: "EC2A3LT",
+		Vehicle:          VehicleTypeSmallVan,
+		IncludeBreakdown: true,
+	})
+	if err != nil {
+		t.Fatalf("GetQuotesByCarrier returned error %v", err)
+	}
+
+	expectedPriceList := PriceByCarrierList{
+		PriceByCarrier{
+			CarrierName:  "MockService2",
+			ServiceID:    "1",
+			Amount:       gbp(421),
+			TaxedAmounts: taxedGBP(421, 84),
+			DeliveryTime: workingDays(5),
+			Breakdown: &PriceBreakdown{
+				BasePrice:          gbp(316),
+				VehicleMultiplier:  1.3,
+				VehicleMarkup:      gbp(95),
+				CarrierBasePrice:   gbp(0),
+				ServiceMarkup:      gbp(10),
+				Surcharges:         []PriceAdjustment{},
+				Discounts:          []PriceAdjustment{},
+				RoundingAdjustment: gbp(0),
+				Total:              gbp(421),
+			},
+		},
+		PriceByCarrier{
+			CarrierName:  "MockService1",
+			ServiceID:    "1",
+			Amount:       gbp(431),
+			TaxedAmounts: taxedGBP(431, 86),
+			DeliveryTime: workingDays(1),
+			Breakdown: &PriceBreakdown{
+				BasePrice:          gbp(316),
+				VehicleMultiplier:  1.3,
+				VehicleMarkup:      gbp(95),
+				CarrierBasePrice:   gbp(15),
+				ServiceMarkup:      gbp(5),
+				Surcharges:         []PriceAdjustment{},
+				Discounts:          []PriceAdjustment{},
+				RoundingAdjustment: gbp(0),
+				Total:              gbp(431),
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(expectedPriceList, result.PriceList) {
+		t.Fatalf("expected price list '%v', received: '%v'", expectedPriceList, result.PriceList)
+	}
+}
+
+func TestGetQuotesByVehicleBreakdown(t *testing.T) {
+	tests := []struct {
+		Rules             *PricingRules
+		ExpectedBreakdown *PriceBreakdown
+	}{
+		// case #1 rounding up the base price and the vehicle price
+		// needs an adjustment: 315.4 + 31.6 is charged as 316 + 32
+		{
+			Rules: &PricingRules{
+				Currency:           CurrencyGBP,
+				VehicleMultipliers: DefaultPricingRules().VehicleMultipliers,
+				VehicleCapacities:  DefaultPricingRules().VehicleCapacities,
+				PricePerKilometre:  100,
+				Rounding:           RoundingModeUp,
+			},
+			ExpectedBreakdown: &PriceBreakdown{
+				BasePrice:          gbp(315),
+				VehicleMultiplier:  1.1,
+				VehicleMarkup:      gbp(32),
+				CarrierBasePrice:   gbp(0),
+				ServiceMarkup:      gbp(0),
+				Surcharges:         []PriceAdjustment{},
+				Discounts:          []PriceAdjustment{},
+				RoundingAdjustment: gbp(1),
+				Total:              gbp(348),
+			},
+		},
+		// case #2 minimum charge
+		{
+			Rules: &PricingRules{
+				Currency:           CurrencyGBP,
+				VehicleMultipliers: DefaultPricingRules().VehicleMultipliers,
+				VehicleCapacities:  DefaultPricingRules().VehicleCapacities,
+				PricePerKilometre:  100,
+				MinimumCharge:      1000,
+				Rounding:           RoundingModeHalfUp,
+			},
+			ExpectedBreakdown: &PriceBreakdown{
+				BasePrice:         gbp(315),
+				VehicleMultiplier: 1.1,
+				VehicleMarkup:     gbp(100),
+				CarrierBasePrice:  gbp(0),
+				ServiceMarkup:     gbp(0),
+				Surcharges: []PriceAdjustment{
+					PriceAdjustment{Description: "minimum charge", Amount: gbp(685)},
+				},
+				Discounts:          []PriceAdjustment{},
+				RoundingAdjustment: gbp(0),
+				Total:              gbp(1100),
+			},
+		},
+	}
+
+	for i, tc := range tests {
+		logger := log.New(ioutil.Discard, "", 0)
+		service, err := NewService(logger, &mockCarrierServiceFinder{}, &mockLineDistanceCalculator{
+			"SW1A": 0,
+			"EC2A": 3.154,
+		}, tc.Rules)
+		if err != nil {
+			t.Fatalf("NewService returned error %v", err)
+		}
+
+		result, err := service.GetQuotesByVehicle(context.Background(), GetQuotesByVehicleArgs{
+			PickupPostcode:   "SW1A1AA",
+			DeliveryPostcode: "EC2A3LT",
+			Vehicle:          VehicleTypeBicycle,
+			IncludeBreakdown: true,
+		})
+		if err != nil {
+			t.Fatalf("case #%d: GetQuotesByVehicle returned error %v", i+1, err)
+		}
+
+		if !reflect.DeepEqual(tc.ExpectedBreakdown, result.Breakdown) {
+			t.Fatalf("case #%d: expected breakdown '%+v', received: '%+v'", i+1, tc.ExpectedBreakdown, result.Breakdown)
+		}
+		if result.Price != result.Breakdown.Total {
+			t.Fatalf("case #%d: expected breakdown total to match price %v, received %v", i+1, result.Price, result.Breakdown.Total)
+		}
+	}
+}
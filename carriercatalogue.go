@@ -0,0 +1,264 @@
+package carrierpricing
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrCarrierNotFound is returned when no carrier of the catalogue has the given name.
+	ErrCarrierNotFound = errors.New("carrier not found")
+
+	// ErrCarrierServiceNotFound is returned when the carrier has no service at
+	// the given position.
+	ErrCarrierServiceNotFound = errors.New("carrier service not found")
+
+	// ErrCarrierAlreadyExists is returned when creating or renaming a carrier
+	// with the name of another carrier of the catalogue.
+	ErrCarrierAlreadyExists = errors.New("carrier already exists")
+
+	// ErrCarrierModified is returned when changing a carrier whose ETag is no
+	// longer the given one, as it has been changed in the meantime.
+	ErrCarrierModified = errors.New("carrier modified in the meantime")
+
+	// ErrInvalidCarrier is returned when a carrier, or one of its services, is
+	// not valid.
+	ErrInvalidCarrier = errors.New("invalid carrier")
+
+	errCarrierCatalogueNotAvailable = errors.New("carriers cannot be managed")
+)
+
+// CarrierCatalogue is a software service used to manage the carriers whose
+// services are found by the CarrierServiceFinder of the Service; changes must
+// be visible to the quotes computed right after them.
+// Carriers are identified by their name, regardless of its case; GetCarrier,
+// UpdateCarrier and DeleteCarrier must return ErrCarrierNotFound when there is
+// no such carrier. UpdateCarrier and DeleteCarrier change the carrier only when
+// the given etag is its current ETag, returning ErrCarrierModified otherwise.
+// CreateCarrier and UpdateCarrier return the carrier as stored by the change
+// itself, not as read afterwards, as it may be changed again in the meantime.
+// Invalid carriers must be rejected with an error wrapping ErrInvalidCarrier.
+type CarrierCatalogue interface {
+	ListCarriers() ([]CarrierDefinition, error)
+	GetCarrier(name string) (*CarrierDefinition, error)
+	CreateCarrier(carrier *CarrierDefinition) (*CarrierDefinition, error)
+	UpdateCarrier(name, etag string, carrier *CarrierDefinition) (*CarrierDefinition, error)
+	DeleteCarrier(name, etag string) error
+}
+
+// WithCarrierCatalogue sets the CarrierCatalogue used to manage carriers and
+// their services; without it, carriers cannot be managed via the Service.
+func WithCarrierCatalogue(carrierCatalogue CarrierCatalogue) ServiceOption {
+	return func(s *Service) {
+		s.carrierCatalogue = carrierCatalogue
+	}
+}
+
+// CarrierDefinition is a carrier of a CarrierCatalogue, as listed in carriers
+// files: base price and markups are expressed in minor units of its currency,
+// GBP when not specified.
+type CarrierDefinition struct {
+	Name         string              `json:"carrier_name"`
+	Currency     string              `json:"currency,omitempty"`
+	BasePrice    int64               `json:"base_price"`
+	WorkingHours *WorkingHours       `json:"working_hours,omitempty"`
+	CutOff       string              `json:"cut_off,omitempty"`
+	Services     []ServiceDefinition `json:"services"`
+}
+
+// ServiceDefinition is a service of a CarrierDefinition, as listed in carriers
+// files; services are identified by their position in the list. ID is the
+// ServiceID of the CarrierService of the service; when empty, the catalogue
+// gives it one.
+type ServiceDefinition struct {
+	ID             string           `json:"id,omitempty"`
+	DeliveryTime   DeliveryDuration `json:"delivery_time"`
+	Markup         int64            `json:"markup"`
+	Vehicles       []string         `json:"vehicles"`
+	CoverageAreas  []string         `json:"coverage_areas,omitempty"`
+	MaxWeightKg    float64          `json:"max_weight_kg,omitempty"`
+	MaxLengthCm    float64          `json:"max_length_cm,omitempty"`
+	OperatingHours *WorkingHours    `json:"operating_hours,omitempty"`
+}
+
+// ETag returns the entity tag of the current version of the carrier, quoted as
+// in HTTP headers; it changes whenever any of its fields or services changes.
+func (c *CarrierDefinition) ETag() string {
+	// encoding a CarrierDefinition cannot fail, as it only has plain fields
+	encoded, _ := json.Marshal(c)
+	hash := sha256.Sum256(encoded)
+	return `"` + hex.EncodeToString(hash[:8]) + `"`
+}
+
+// ListCarriersResponse contains the response of the ListCarriers method.
+type ListCarriersResponse struct {
+	Carriers []CarrierDefinition `json:"carriers"`
+}
+
+// UpdateCarrierArgs contains arguments for the UpdateCarrier method: the name
+// of the carrier to replace, the ETag it has been read with and its new
+// definition, which may rename it.
+type UpdateCarrierArgs struct {
+	CarrierName string
+	ETag        string
+	Carrier     CarrierDefinition
+}
+
+// DeleteCarrierArgs contains arguments for the DeleteCarrier method.
+type DeleteCarrierArgs struct {
+	CarrierName string
+	ETag        string
+}
+
+// CarrierServiceArgs contains arguments for the methods changing a service of a
+// carrier: the name of the carrier, the ETag it has been read with, the
+// position of the service in its list, unused when adding one, and the
+// definition of the service, unused when deleting one.
+type CarrierServiceArgs struct {
+	CarrierName  string
+	ETag         string
+	ServiceIndex int
+	Service      ServiceDefinition
+}
+
+// ListCarriers returns all the carriers of the catalogue, with their services.
+func (s *Service) ListCarriers() (*ListCarriersResponse, error) {
+	s.logger.Println("executing ListCarriers")
+
+	if s.carrierCatalogue == nil {
+		return nil, errCarrierCatalogueNotAvailable
+	}
+
+	carriers, err := s.carrierCatalogue.ListCarriers()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ListCarriersResponse{Carriers: carriers}, nil
+}
+
+// GetCarrier returns the carrier of the catalogue with the given name.
+// ErrCarrierNotFound is returned when there is no such carrier.
+func (s *Service) GetCarrier(name string) (*CarrierDefinition, error) {
+	s.logger.Printf("executing GetCarrier with args: %v\n", name)
+
+	if s.carrierCatalogue == nil {
+		return nil, errCarrierCatalogueNotAvailable
+	}
+
+	return s.carrierCatalogue.GetCarrier(name)
+}
+
+// CreateCarrier adds the given carrier to the catalogue, returning it as saved.
+// ErrCarrierAlreadyExists is returned when another carrier has the same name.
+func (s *Service) CreateCarrier(carrier CarrierDefinition) (*CarrierDefinition, error) {
+	s.logger.Printf("executing CreateCarrier with args: %v\n", carrier)
+
+	if s.carrierCatalogue == nil {
+		return nil, errCarrierCatalogueNotAvailable
+	}
+
+	return s.carrierCatalogue.CreateCarrier(&carrier)
+}
+
+// UpdateCarrier replaces the carrier with the given name, as long as it has not
+// been changed since it has been read with the given ETag; ErrCarrierModified is
+// returned otherwise.
+func (s *Service) UpdateCarrier(args UpdateCarrierArgs) (*CarrierDefinition, error) {
+	s.logger.Printf("executing UpdateCarrier with args: %v\n", args)
+
+	if s.carrierCatalogue == nil {
+		return nil, errCarrierCatalogueNotAvailable
+	}
+
+	return s.carrierCatalogue.UpdateCarrier(args.CarrierName, args.ETag, &args.Carrier)
+}
+
+// DeleteCarrier removes the carrier with the given name from the catalogue, as
+// long as it has not been changed since it has been read with the given ETag;
+// ErrCarrierModified is returned otherwise.
+func (s *Service) DeleteCarrier(args DeleteCarrierArgs) error {
+	s.logger.Printf("executing DeleteCarrier with args: %v\n", args)
+
+	if s.carrierCatalogue == nil {
+		return errCarrierCatalogueNotAvailable
+	}
+
+	return s.carrierCatalogue.DeleteCarrier(args.CarrierName, args.ETag)
+}
+
+// AddCarrierService appends the given service to the services of the carrier,
+// returning the carrier as saved.
+func (s *Service) AddCarrierService(args CarrierServiceArgs) (*CarrierDefinition, error) {
+	s.logger.Printf("executing AddCarrierService with args: %v\n", args)
+
+	return s.changeCarrierServices(args, func(services []ServiceDefinition) ([]ServiceDefinition, error) {
+		return append(services, args.Service), nil
+	})
+}
+
+// UpdateCarrierService replaces the service of the carrier at the given
+// position, keeping its ID unless a different one is given, and returns the
+// carrier as saved. ErrCarrierServiceNotFound is returned when the carrier has
+// no service at such position.
+func (s *Service) UpdateCarrierService(args CarrierServiceArgs) (*CarrierDefinition, error) {
+	s.logger.Printf("executing UpdateCarrierService with args: %v\n", args)
+
+	return s.changeCarrierServices(args, func(services []ServiceDefinition) ([]ServiceDefinition, error) {
+		if args.ServiceIndex < 0 || args.ServiceIndex >= len(services) {
+			return nil, fmt.Errorf("%w: %d", ErrCarrierServiceNotFound, args.ServiceIndex)
+		}
+		service := args.Service
+		if service.ID == "" {
+			service.ID = services[args.ServiceIndex].ID
+		}
+		services[args.ServiceIndex] = service
+		return services, nil
+	})
+}
+
+// DeleteCarrierService removes the service of the carrier at the given
+// position, returning the carrier as saved. ErrCarrierServiceNotFound is
+// returned when the carrier has no service at such position.
+func (s *Service) DeleteCarrierService(args CarrierServiceArgs) (*CarrierDefinition, error) {
+	s.logger.Printf("executing DeleteCarrierService with args: %v\n", args)
+
+	return s.changeCarrierServices(args, func(services []ServiceDefinition) ([]ServiceDefinition, error) {
+		if args.ServiceIndex < 0 || args.ServiceIndex >= len(services) {
+			return nil, fmt.Errorf("%w: %d", ErrCarrierServiceNotFound, args.ServiceIndex)
+		}
+		return append(services[:args.ServiceIndex], services[args.ServiceIndex+1:]...), nil
+	})
+}
+
+// changeCarrierServices replaces the services of the carrier of the given args
+// with the ones returned by change, called with a copy of the current ones.
+func (s *Service) changeCarrierServices(
+	args CarrierServiceArgs,
+	change func(services []ServiceDefinition) ([]ServiceDefinition, error),
+) (*CarrierDefinition, error) {
+	if s.carrierCatalogue == nil {
+		return nil, errCarrierCatalogueNotAvailable
+	}
+
+	carrier, err := s.carrierCatalogue.GetCarrier(args.CarrierName)
+	if err != nil {
+		return nil, err
+	}
+
+	// positions refer to the version of the carrier the client has read, hence
+	// they cannot be trusted once the carrier has changed
+	if carrier.ETag() != args.ETag {
+		return nil, ErrCarrierModified
+	}
+
+	carrier.Services, err = change(append([]ServiceDefinition{}, carrier.Services...))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.carrierCatalogue.UpdateCarrier(args.CarrierName, args.ETag, carrier)
+}
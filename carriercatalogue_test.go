@@ -0,0 +1,280 @@
+package carrierpricing
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCarrierDefinitionETag(t *testing.T) {
+	carrier := CarrierDefinition{
+		Name:      "MockCarrier",
+		BasePrice: 50,
+		Services:  []ServiceDefinition{{DeliveryTime: workingDays(1), Markup: 20, Vehicles: []string{VehicleTypeSmallVan}}},
+	}
+	etag := carrier.ETag()
+
+	if !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) {
+		t.Fatalf("expected a quoted ETag, received: %s", etag)
+	}
+
+	sameCarrier := CarrierDefinition{
+		Name:      "MockCarrier",
+		BasePrice: 50,
+		Services:  []ServiceDefinition{{DeliveryTime: workingDays(1), Markup: 20, Vehicles: []string{VehicleTypeSmallVan}}},
+	}
+	if sameCarrier.ETag() != etag {
+		t.Fatalf("expected the same ETag for the same carrier, received: %s and %s", etag, sameCarrier.ETag())
+	}
+
+	sameCarrier.Services[0].Markup++
+	if sameCarrier.ETag() == etag {
+		t.Fatalf("expected a different ETag once a service has been changed")
+	}
+}
+
+func TestCreateAndUpdateCarrier(t *testing.T) {
+	catalogue := &mockCarrierCatalogue{carriers: map[string]CarrierDefinition{}}
+	service, err := NewService(
+		log.New(ioutil.Discard, "", 0),
+		&mockCarrierServiceList{},
+		&mockDistanceCalculator{},
+		nil,
+		WithCarrierCatalogue(catalogue),
+	)
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
+
+	created, err := service.CreateCarrier(CarrierDefinition{
+		Name:      "MockCarrier",
+		BasePrice: 50,
+		Services:  []ServiceDefinition{{DeliveryTime: workingDays(1), Markup: 20, Vehicles: []string{VehicleTypeSmallVan}}},
+	})
+	if err != nil {
+		t.Fatalf("CreateCarrier returned error %v", err)
+	}
+
+	// the carrier is returned as stored by the catalogue, which identifies its services
+	if created.Services[0].ID != "1" || !reflect.DeepEqual(*created, catalogue.carriers["mockcarrier"]) {
+		t.Fatalf("expected the stored carrier '%+v', received: '%+v'", catalogue.carriers["mockcarrier"], *created)
+	}
+
+	updated, err := service.UpdateCarrier(UpdateCarrierArgs{
+		CarrierName: "mockcarrier",
+		ETag:        created.ETag(),
+		Carrier: CarrierDefinition{
+			Name:      "MockCarrierRenamed",
+			BasePrice: 60,
+			Services:  []ServiceDefinition{{DeliveryTime: workingDays(2), Markup: 10, Vehicles: []string{VehicleTypeBicycle}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("UpdateCarrier returned error %v", err)
+	}
+
+	if updated.Services[0].ID != "1" || !reflect.DeepEqual(*updated, catalogue.carriers["mockcarrierrenamed"]) {
+		t.Fatalf("expected the stored carrier '%+v', received: '%+v'", catalogue.carriers["mockcarrierrenamed"], *updated)
+	}
+
+	_, err = service.CreateCarrier(CarrierDefinition{Name: "mockcarrierrenamed"})
+	if err != ErrCarrierAlreadyExists {
+		t.Fatalf("expected error '%v', received: '%v'", ErrCarrierAlreadyExists, err)
+	}
+}
+
+func TestChangeCarrierServices(t *testing.T) {
+	smallVanService := ServiceDefinition{ID: "1", DeliveryTime: workingDays(1), Markup: 20, Vehicles: []string{VehicleTypeSmallVan}}
+	parcelCarService := ServiceDefinition{ID: "2", DeliveryTime: workingDays(3), Markup: 10, Vehicles: []string{VehicleTypeParcelCar, VehicleTypeMotorbike}}
+	newService := ServiceDefinition{DeliveryTime: NewDeliveryDuration(2, DeliveryTimeUnitHours), Markup: 7, Vehicles: []string{VehicleTypeBicycle}}
+	addedService := newService
+	addedService.ID = "3"
+	updatedService := newService
+	updatedService.ID = "2"
+
+	tests := []struct {
+		Description      string
+		Change           func(service *Service, args CarrierServiceArgs) (*CarrierDefinition, error)
+		Args             CarrierServiceArgs
+		ExpectedServices []ServiceDefinition
+		ExpectedError    error
+	}{
+		{
+			Description:      "service added",
+			Change:           (*Service).AddCarrierService,
+			Args:             CarrierServiceArgs{CarrierName: "mockcarrier", Service: newService},
+			ExpectedServices: []ServiceDefinition{smallVanService, parcelCarService, addedService},
+		},
+		{
+			Description:      "service updated",
+			Change:           (*Service).UpdateCarrierService,
+			Args:             CarrierServiceArgs{CarrierName: "MockCarrier", ServiceIndex: 1, Service: newService},
+			ExpectedServices: []ServiceDefinition{smallVanService, updatedService},
+		},
+		{
+			Description:      "service deleted",
+			Change:           (*Service).DeleteCarrierService,
+			Args:             CarrierServiceArgs{CarrierName: "MockCarrier", ServiceIndex: 0},
+			ExpectedServices: []ServiceDefinition{parcelCarService},
+		},
+		{
+			Description:   "service not found",
+			Change:        (*Service).UpdateCarrierService,
+			Args:          CarrierServiceArgs{CarrierName: "MockCarrier", ServiceIndex: 2, Service: newService},
+			ExpectedError: ErrCarrierServiceNotFound,
+		},
+		{
+			Description:   "negative service position",
+			Change:        (*Service).DeleteCarrierService,
+			Args:          CarrierServiceArgs{CarrierName: "MockCarrier", ServiceIndex: -1},
+			ExpectedError: ErrCarrierServiceNotFound,
+		},
+		{
+			Description:   "carrier modified in the meantime",
+			Change:        (*Service).AddCarrierService,
+			Args:          CarrierServiceArgs{CarrierName: "MockCarrier", ETag: `"outdated"`, Service: newService},
+			ExpectedError: ErrCarrierModified,
+		},
+		{
+			Description:   "carrier not found",
+			Change:        (*Service).AddCarrierService,
+			Args:          CarrierServiceArgs{CarrierName: "UnknownCarrier", Service: newService},
+			ExpectedError: ErrCarrierNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		original := CarrierDefinition{
+			Name:      "MockCarrier",
+			BasePrice: 50,
+			Services:  []ServiceDefinition{smallVanService, parcelCarService},
+		}
+		catalogue := &mockCarrierCatalogue{
+			carriers: map[string]CarrierDefinition{"mockcarrier": original},
+		}
+		service, err := NewService(
+			log.New(ioutil.Discard, "", 0),
+			&mockCarrierServiceList{},
+			&mockDistanceCalculator{},
+			nil,
+			WithCarrierCatalogue(catalogue),
+		)
+		if err != nil {
+			t.Fatalf("NewService returned error %v", err)
+		}
+
+		if tc.Args.ETag == "" {
+			tc.Args.ETag = original.ETag()
+		}
+
+		carrier, err := tc.Change(service, tc.Args)
+
+		if tc.ExpectedError != nil {
+			if !errors.Is(err, tc.ExpectedError) {
+				t.Fatalf("%s: expected error '%v', received: '%v'", tc.Description, tc.ExpectedError, err)
+			}
+			if stored := catalogue.carriers["mockcarrier"]; !reflect.DeepEqual(stored, original) {
+				t.Fatalf("%s: expected the carrier not to be changed, received: %+v", tc.Description, stored)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", tc.Description, err)
+		}
+
+		if !reflect.DeepEqual(carrier.Services, tc.ExpectedServices) {
+			t.Fatalf("%s: expected services '%+v', received: '%+v'", tc.Description, tc.ExpectedServices, carrier.Services)
+		}
+
+		if carrier.ETag() == tc.Args.ETag {
+			t.Fatalf("%s: expected the ETag of the carrier to change", tc.Description)
+		}
+	}
+}
+
+func TestCarrierCatalogueNotAvailable(t *testing.T) {
+	service, err := NewService(log.New(ioutil.Discard, "", 0), &mockCarrierServiceList{}, &mockDistanceCalculator{}, nil)
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
+
+	_, err = service.ListCarriers()
+	if err != errCarrierCatalogueNotAvailable {
+		t.Fatalf("expected error '%v', received: '%v'", errCarrierCatalogueNotAvailable, err)
+	}
+
+	_, err = service.AddCarrierService(CarrierServiceArgs{CarrierName: "MockCarrier"})
+	if err != errCarrierCatalogueNotAvailable {
+		t.Fatalf("expected error '%v', received: '%v'", errCarrierCatalogueNotAvailable, err)
+	}
+}
+
+// mockCarrierCatalogue keeps carriers in memory, by lower case name.
+type mockCarrierCatalogue struct {
+	carriers map[string]CarrierDefinition
+}
+
+func (mcc *mockCarrierCatalogue) ListCarriers() ([]CarrierDefinition, error) {
+	carriers := []CarrierDefinition{}
+	for _, carrier := range mcc.carriers {
+		carriers = append(carriers, carrier)
+	}
+	return carriers, nil
+}
+
+func (mcc *mockCarrierCatalogue) GetCarrier(name string) (*CarrierDefinition, error) {
+	carrier, exists := mcc.carriers[strings.ToLower(name)]
+	if !exists {
+		return nil, ErrCarrierNotFound
+	}
+	carrier.Services = append([]ServiceDefinition{}, carrier.Services...)
+	return &carrier, nil
+}
+
+func (mcc *mockCarrierCatalogue) CreateCarrier(carrier *CarrierDefinition) (*CarrierDefinition, error) {
+	if _, exists := mcc.carriers[strings.ToLower(carrier.Name)]; exists {
+		return nil, ErrCarrierAlreadyExists
+	}
+	return mcc.store(carrier), nil
+}
+
+func (mcc *mockCarrierCatalogue) UpdateCarrier(name, etag string, carrier *CarrierDefinition) (*CarrierDefinition, error) {
+	err := mcc.DeleteCarrier(name, etag)
+	if err != nil {
+		return nil, err
+	}
+	return mcc.store(carrier), nil
+}
+
+// store saves a copy of the given carrier, identifying the services without an
+// ID by their position, and returns another copy of it.
+func (mcc *mockCarrierCatalogue) store(carrier *CarrierDefinition) *CarrierDefinition {
+	stored := *carrier
+	stored.Services = append([]ServiceDefinition{}, carrier.Services...)
+	for i := range stored.Services {
+		if stored.Services[i].ID == "" {
+			stored.Services[i].ID = strconv.Itoa(i + 1)
+		}
+	}
+	mcc.carriers[strings.ToLower(stored.Name)] = stored
+
+	returned := stored
+	returned.Services = append([]ServiceDefinition{}, stored.Services...)
+	return &returned
+}
+
+func (mcc *mockCarrierCatalogue) DeleteCarrier(name, etag string) error {
+	current, err := mcc.GetCarrier(name)
+	if err != nil {
+		return err
+	}
+	if current.ETag() != etag {
+		return ErrCarrierModified
+	}
+	delete(mcc.carriers, strings.ToLower(name))
+	return nil
+}
@@ -0,0 +1,69 @@
+package carrierpricing
+
+import (
+	"fmt"
+	"time"
+)
+
+// CarrierDispatcher is a software service used to hand the deliveries booked with
+// a carrier over to it. Book returns the reference the carrier gave to the job,
+// which is then used to cancel and to track it.
+type CarrierDispatcher interface {
+	Book(job *DispatchJob) (string, error)
+	Cancel(reference string) error
+	Track(reference string) (*TrackingInfo, error)
+}
+
+// CarrierDispatcherRegistry is a software service used to get the CarrierDispatcher
+// of a carrier, by its name; false is returned when jobs are not handed over to
+// the carrier, an error when its CarrierDispatcher cannot be created, e.g.
+// because it is not configured properly.
+type CarrierDispatcherRegistry interface {
+	FindCarrierDispatcher(carrierName string) (CarrierDispatcher, bool, error)
+}
+
+// DispatchJob is the delivery handed over to a carrier when a Booking is confirmed.
+type DispatchJob struct {
+	BookingID        string     `json:"booking_id"`
+	CarrierName      string     `json:"service"`
+	ServiceID        string     `json:"service_id"`
+	Vehicle          string     `json:"vehicle"`
+	PickupPostcode   string     `json:"pickup_postcode"`
+	DeliveryPostcode string     `json:"delivery_postcode"`
+	PickupTime       *time.Time `json:"pickup_time,omitempty"`
+	Price            Money      `json:"price"`
+}
+
+// TrackingInfo is the status of a job as reported by the carrier, together with
+// the time it has been updated at and, when known, where the parcel is.
+type TrackingInfo struct {
+	Reference string    `json:"reference"`
+	Status    string    `json:"status"`
+	Location  string    `json:"location,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WithCarrierDispatchers sets the CarrierDispatcherRegistry used to hand bookings
+// over to carriers: bookings are booked with the carrier when confirmed and
+// cancelled with it when cancelled. Without it, or for carriers having no
+// CarrierDispatcher, bookings only change their status.
+func WithCarrierDispatchers(carrierDispatchers CarrierDispatcherRegistry) ServiceOption {
+	return func(s *Service) {
+		s.carrierDispatchers = carrierDispatchers
+	}
+}
+
+// findCarrierDispatcher returns the CarrierDispatcher of the given carrier;
+// ErrDispatchFailed is returned when it cannot be created.
+func (s *Service) findCarrierDispatcher(carrierName string) (CarrierDispatcher, bool, error) {
+	if s.carrierDispatchers == nil {
+		return nil, false, nil
+	}
+
+	dispatcher, found, err := s.carrierDispatchers.FindCarrierDispatcher(carrierName)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %v", ErrDispatchFailed, err)
+	}
+
+	return dispatcher, found, nil
+}
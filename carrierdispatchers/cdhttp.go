@@ -0,0 +1,115 @@
+package carrierdispatchers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/giefferre/carrierpricing"
+)
+
+// defaultHTTPTimeout is the timeout of the requests to the carriers when no
+// http.Client is given.
+const defaultHTTPTimeout = 10 * time.Second
+
+// CDHTTP implements the carrierpricing.CarrierDispatcher interface for carriers
+// exposing a simple JSON API under a base URL:
+// - POST {base URL}/jobs books the given job, returning its "reference"
+// - DELETE {base URL}/jobs/{reference} cancels the job
+// - GET {base URL}/jobs/{reference} returns the tracking info of the job
+type CDHTTP struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewCDHTTP returns a new CDHTTP object sending requests under the given base URL
+// via the given client; when nil, a client with a 10 seconds timeout is used.
+func NewCDHTTP(baseURL string, client *http.Client) *CDHTTP {
+	if client == nil {
+		client = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+
+	return &CDHTTP{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  client,
+	}
+}
+
+// Book books the given job with the carrier, returning the reference it gave to it.
+func (cd *CDHTTP) Book(job *carrierpricing.DispatchJob) (string, error) {
+	body, err := json.Marshal(job)
+	if err != nil {
+		return "", err
+	}
+
+	response := struct {
+		Reference string `json:"reference"`
+	}{}
+	err = cd.do(http.MethodPost, cd.baseURL+"/jobs", body, &response)
+	if err != nil {
+		return "", err
+	}
+
+	if response.Reference == "" {
+		return "", fmt.Errorf("no reference returned by %s", cd.baseURL)
+	}
+
+	return response.Reference, nil
+}
+
+// Cancel cancels the job with the given reference.
+func (cd *CDHTTP) Cancel(reference string) error {
+	return cd.do(http.MethodDelete, cd.jobURL(reference), nil, nil)
+}
+
+// Track returns the tracking info of the job with the given reference.
+func (cd *CDHTTP) Track(reference string) (*carrierpricing.TrackingInfo, error) {
+	trackingInfo := &carrierpricing.TrackingInfo{}
+	err := cd.do(http.MethodGet, cd.jobURL(reference), nil, trackingInfo)
+	if err != nil {
+		return nil, err
+	}
+	return trackingInfo, nil
+}
+
+func (cd *CDHTTP) jobURL(reference string) string {
+	return cd.baseURL + "/jobs/" + url.PathEscape(reference)
+}
+
+// do sends a request with the given JSON body, when not nil, and decodes the
+// JSON response into responseObject, when not nil. An error is returned when
+// the carrier does not respond with a 2xx status code.
+func (cd *CDHTTP) do(method, requestURL string, body []byte, responseObject interface{}) error {
+	request, err := http.NewRequest(method, requestURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := cd.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("%s %s responded %s: %s", method, requestURL, response.Status, strings.TrimSpace(string(responseBody)))
+	}
+
+	if responseObject == nil {
+		return nil
+	}
+	return json.Unmarshal(responseBody, responseObject)
+}
@@ -0,0 +1,157 @@
+package carrierdispatchers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/giefferre/carrierpricing"
+)
+
+func TestCDHTTP(t *testing.T) {
+	fakeCarrierServer := newFakeCarrierServer()
+	server := httptest.NewServer(fakeCarrierServer)
+	defer server.Close()
+
+	dispatcher := NewCDHTTP(server.URL+"/", server.Client())
+
+	job := &carrierpricing.DispatchJob{
+		BookingID:        "booking",
+		CarrierName:      "RoyalPackages",
+		ServiceID:        "1",
+		Vehicle:          "small_van",
+		PickupPostcode:   "SW1A1AA",
+		DeliveryPostcode: "EC2A3LT",
+		Price:            carrierpricing.NewMoney(421, carrierpricing.CurrencyGBP),
+	}
+
+	reference, err := dispatcher.Book(job)
+	if err != nil {
+		t.Fatalf("Book returned error %v", err)
+	}
+
+	bookedJob, found := fakeCarrierServer.Job(reference)
+	if !found || bookedJob != *job {
+		t.Fatalf("expected job '%+v' to be booked, received: '%+v'", *job, bookedJob)
+	}
+
+	fakeCarrierServer.SetStatus(reference, fakeJobStatusBooked, "London depot")
+
+	trackingInfo, err := dispatcher.Track(reference)
+	if err != nil {
+		t.Fatalf("Track returned error %v", err)
+	}
+	if trackingInfo.Reference != reference || trackingInfo.Status != fakeJobStatusBooked || trackingInfo.Location != "London depot" {
+		t.Fatalf("unexpected tracking info '%+v'", trackingInfo)
+	}
+
+	err = dispatcher.Cancel(reference)
+	if err != nil {
+		t.Fatalf("Cancel returned error %v", err)
+	}
+
+	trackingInfo, err = dispatcher.Track(reference)
+	if err != nil || trackingInfo.Status != fakeJobStatusCancelled {
+		t.Fatalf("expected the job to be cancelled, received: '%+v' (%v)", trackingInfo, err)
+	}
+
+	// cancelled jobs cannot be cancelled again
+	if err = dispatcher.Cancel(reference); err == nil {
+		t.Fatal("expected an error cancelling a cancelled job")
+	}
+
+	if _, err = dispatcher.Track("unknown"); err == nil {
+		t.Fatal("expected an error tracking an unknown job")
+	}
+
+	if _, err = dispatcher.Book(&carrierpricing.DispatchJob{}); err == nil {
+		t.Fatal("expected an error booking an invalid job")
+	}
+}
+
+func TestCDRegistryFromSource(t *testing.T) {
+	source := mockDispatcherSource{
+		"RoyalPackages": json.RawMessage(`{"url": "https://royalpackages.example/api"}`),
+		"Hercules":      json.RawMessage(`{"type": "http", "url": "http://localhost:8080"}`),
+		"Zippy":         json.RawMessage(`{"type": "ftp", "url": "https://zippy.example"}`),
+	}
+	registry := NewCDRegistryFromSource(source, nil)
+
+	tests := []struct {
+		CarrierName   string
+		ExpectedFound bool
+		ExpectedError string
+	}{
+		// case #1 default type
+		{CarrierName: "RoyalPackages", ExpectedFound: true},
+		// case #2 http type
+		{CarrierName: "Hercules", ExpectedFound: true},
+		// case #3 invalid dispatcher
+		{CarrierName: "Zippy", ExpectedError: `invalid dispatcher of "Zippy": unsupported type "ftp"`},
+		// case #4 carrier without dispatcher
+		{CarrierName: "OOPS", ExpectedFound: false},
+	}
+
+	for i, tc := range tests {
+		_, found, err := registry.FindCarrierDispatcher(tc.CarrierName)
+		if tc.ExpectedError != "" {
+			if err == nil || err.Error() != tc.ExpectedError {
+				t.Fatalf("case #%d: expected error '%s', received: '%v'", i+1, tc.ExpectedError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("case #%d: FindCarrierDispatcher returned error %v", i+1, err)
+		}
+		if found != tc.ExpectedFound {
+			t.Fatalf("case #%d: expected dispatcher found for %s to be %t, received: %t", i+1, tc.CarrierName, tc.ExpectedFound, found)
+		}
+	}
+
+	// changes made to the source are used straight away
+	source["OOPS"] = json.RawMessage(`{"url": "https://oops.example"}`)
+	if _, found, err := registry.FindCarrierDispatcher("OOPS"); !found || err != nil {
+		t.Fatalf("expected a dispatcher for OOPS once added to the source, received: %t (%v)", found, err)
+	}
+}
+
+func TestValidateDispatcherConfig(t *testing.T) {
+	tests := []struct {
+		RawConfig     string
+		ExpectedError string
+	}{
+		// case #1 default type
+		{RawConfig: `{"url": "https://royalpackages.example/api"}`},
+		// case #2 http type
+		{RawConfig: `{"type": "http", "url": "http://localhost:8080"}`},
+		// case #3 unsupported type
+		{
+			RawConfig:     `{"type": "ftp", "url": "https://royalpackages.example"}`,
+			ExpectedError: `unsupported type "ftp"`,
+		},
+		// case #4 invalid url
+		{
+			RawConfig:     `{"url": "royalpackages"}`,
+			ExpectedError: `invalid url provided "royalpackages"`,
+		},
+		// case #5 unknown field
+		{
+			RawConfig:     `{"url": "https://royalpackages.example", "token": "secret"}`,
+			ExpectedError: `json: unknown field "token"`,
+		},
+	}
+
+	for i, tc := range tests {
+		err := ValidateDispatcherConfig(json.RawMessage(tc.RawConfig))
+		if (err == nil && tc.ExpectedError != "") || (err != nil && err.Error() != tc.ExpectedError) {
+			t.Fatalf("case #%d: expected error '%s', received: '%v'", i+1, tc.ExpectedError, err)
+		}
+	}
+}
+
+type mockDispatcherSource map[string]json.RawMessage
+
+func (mds mockDispatcherSource) DispatcherConfig(carrierName string) (json.RawMessage, bool) {
+	rawConfig, found := mds[carrierName]
+	return rawConfig, found
+}
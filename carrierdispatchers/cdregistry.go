@@ -0,0 +1,135 @@
+package carrierdispatchers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/giefferre/carrierpricing"
+)
+
+// dispatcherTypeHTTP is the type of the dispatchers handled by CDHTTP, the
+// default one.
+const dispatcherTypeHTTP = "http"
+
+// CDRegistry implements the carrierpricing.CarrierDispatcherRegistry interface
+// keeping the CarrierDispatcher of each carrier in memory, by carrier name.
+type CDRegistry struct {
+	mutex       sync.RWMutex
+	dispatchers map[string]carrierpricing.CarrierDispatcher
+}
+
+// NewCDRegistry returns a new, empty, CDRegistry object.
+func NewCDRegistry() *CDRegistry {
+	return &CDRegistry{
+		dispatchers: map[string]carrierpricing.CarrierDispatcher{},
+	}
+}
+
+// Register sets the CarrierDispatcher of the carrier with the given name,
+// replacing the previous one.
+func (cdr *CDRegistry) Register(carrierName string, dispatcher carrierpricing.CarrierDispatcher) {
+	cdr.mutex.Lock()
+	defer cdr.mutex.Unlock()
+
+	cdr.dispatchers[carrierName] = dispatcher
+}
+
+// FindCarrierDispatcher returns the CarrierDispatcher of the carrier with the given name.
+func (cdr *CDRegistry) FindCarrierDispatcher(carrierName string) (carrierpricing.CarrierDispatcher, bool, error) {
+	cdr.mutex.RLock()
+	defer cdr.mutex.RUnlock()
+
+	dispatcher, found := cdr.dispatchers[carrierName]
+	return dispatcher, found, nil
+}
+
+// DispatcherSource provides the "dispatcher" object of each carrier, as listed
+// in the files used by carrierservicefinders.CSFFromJSONFile, which implements
+// this interface; false is returned for the carriers having none.
+type DispatcherSource interface {
+	DispatcherConfig(carrierName string) (json.RawMessage, bool)
+}
+
+// CDRegistryFromSource implements the carrierpricing.CarrierDispatcherRegistry
+// interface creating the CarrierDispatcher of each carrier from its
+// "dispatcher" object, as provided by a DispatcherSource when the dispatcher
+// is looked up: changes made to the carriers, e.g. reloading them, are used
+// straight away.
+type CDRegistryFromSource struct {
+	source DispatcherSource
+	client *http.Client
+}
+
+// NewCDRegistryFromSource returns a new CDRegistryFromSource object creating
+// the dispatchers of the carriers of the given source, having a "dispatcher"
+// object: its "type" is "http", the default, and its "url" is the base URL of
+// the API of the carrier, called via the given client (see NewCDHTTP).
+func NewCDRegistryFromSource(source DispatcherSource, client *http.Client) *CDRegistryFromSource {
+	return &CDRegistryFromSource{
+		source: source,
+		client: client,
+	}
+}
+
+// FindCarrierDispatcher returns the CarrierDispatcher of the carrier with the
+// given name, as currently configured by the source; false is returned when the
+// carrier has no dispatcher, an error when it is not valid (see
+// ValidateDispatcherConfig).
+func (cdr *CDRegistryFromSource) FindCarrierDispatcher(carrierName string) (carrierpricing.CarrierDispatcher, bool, error) {
+	rawConfig, found := cdr.source.DispatcherConfig(carrierName)
+	if !found {
+		return nil, false, nil
+	}
+
+	config, err := parseDispatcherConfig(rawConfig)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid dispatcher of %q: %v", carrierName, err)
+	}
+
+	return NewCDHTTP(config.URL, cdr.client), true, nil
+}
+
+// ValidateDispatcherConfig returns an error if the given "dispatcher" object of
+// a carrier contains unknown fields or invalid values.
+func ValidateDispatcherConfig(rawConfig json.RawMessage) error {
+	_, err := parseDispatcherConfig(rawConfig)
+	return err
+}
+
+type dispatcherConfig struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// parseDispatcherConfig returns the dispatcherConfig of the given "dispatcher"
+// object, checking its fields and values.
+func parseDispatcherConfig(rawConfig json.RawMessage) (dispatcherConfig, error) {
+	config := dispatcherConfig{}
+
+	decoder := json.NewDecoder(bytes.NewReader(rawConfig))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&config)
+	if err != nil {
+		return dispatcherConfig{}, err
+	}
+
+	return config, config.validate()
+}
+
+// validate returns an error if the dispatcherConfig contains invalid values.
+func (dc dispatcherConfig) validate() error {
+	if dc.Type != "" && dc.Type != dispatcherTypeHTTP {
+		return fmt.Errorf("unsupported type %q", dc.Type)
+	}
+
+	baseURL, err := url.Parse(dc.URL)
+	if err != nil || (baseURL.Scheme != "http" && baseURL.Scheme != "https") || baseURL.Host == "" {
+		return fmt.Errorf("invalid url provided %q", dc.URL)
+	}
+
+	return nil
+}
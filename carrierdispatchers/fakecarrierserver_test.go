@@ -0,0 +1,126 @@
+package carrierdispatchers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/giefferre/carrierpricing"
+)
+
+const (
+	// fakeJobStatusBooked is the status of the jobs just booked with a fakeCarrierServer.
+	fakeJobStatusBooked = "booked"
+
+	// fakeJobStatusCancelled is the status of the jobs cancelled with a fakeCarrierServer.
+	fakeJobStatusCancelled = "cancelled"
+)
+
+// fakeCarrierServer is an http.Handler serving the API called by CDHTTP, keeping
+// the jobs in memory, used to test CDHTTP via httptest.NewServer.
+type fakeCarrierServer struct {
+	mutex sync.Mutex
+	jobs  map[string]*fakeJob
+	count int
+}
+
+type fakeJob struct {
+	job          carrierpricing.DispatchJob
+	trackingInfo carrierpricing.TrackingInfo
+}
+
+// newFakeCarrierServer returns a new fakeCarrierServer object with no jobs.
+func newFakeCarrierServer() *fakeCarrierServer {
+	return &fakeCarrierServer{
+		jobs: map[string]*fakeJob{},
+	}
+}
+
+// Job returns the job booked with the given reference.
+func (fcs *fakeCarrierServer) Job(reference string) (carrierpricing.DispatchJob, bool) {
+	fcs.mutex.Lock()
+	defer fcs.mutex.Unlock()
+
+	job, found := fcs.jobs[reference]
+	if !found {
+		return carrierpricing.DispatchJob{}, false
+	}
+	return job.job, true
+}
+
+// SetStatus changes the tracking status and location of the job with the given
+// reference, as a carrier would while delivering it; false is returned when
+// there is no such job.
+func (fcs *fakeCarrierServer) SetStatus(reference, status, location string) bool {
+	fcs.mutex.Lock()
+	defer fcs.mutex.Unlock()
+
+	job, found := fcs.jobs[reference]
+	if !found {
+		return false
+	}
+
+	job.trackingInfo.Status = status
+	job.trackingInfo.Location = location
+	job.trackingInfo.UpdatedAt = time.Now().UTC()
+	return true
+}
+
+// ServeHTTP serves the POST /jobs, DELETE /jobs/{reference} and
+// GET /jobs/{reference} endpoints.
+func (fcs *fakeCarrierServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fcs.mutex.Lock()
+	defer fcs.mutex.Unlock()
+
+	if r.URL.Path == "/jobs" && r.Method == http.MethodPost {
+		job := carrierpricing.DispatchJob{}
+		err := json.NewDecoder(r.Body).Decode(&job)
+		if err != nil || job.BookingID == "" {
+			http.Error(w, "invalid job", http.StatusBadRequest)
+			return
+		}
+
+		fcs.count++
+		reference := fmt.Sprintf("FAKE-%06d", fcs.count)
+		fcs.jobs[reference] = &fakeJob{
+			job: job,
+			trackingInfo: carrierpricing.TrackingInfo{
+				Reference: reference,
+				Status:    fakeJobStatusBooked,
+				UpdatedAt: time.Now().UTC(),
+			},
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"reference": reference})
+		return
+	}
+
+	reference := strings.TrimPrefix(r.URL.Path, "/jobs/")
+	job, found := fcs.jobs[reference]
+	if reference == r.URL.Path || !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(job.trackingInfo)
+	case http.MethodDelete:
+		if job.trackingInfo.Status != fakeJobStatusBooked {
+			http.Error(w, "job cannot be cancelled", http.StatusConflict)
+			return
+		}
+		job.trackingInfo.Status = fakeJobStatusCancelled
+		job.trackingInfo.UpdatedAt = time.Now().UTC()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
@@ -1,17 +1,189 @@
 package carrierpricing
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/giefferre/carrierpricing/postcode"
+)
+
+// DefaultCarrierServiceFinderTimeout is how long the Service waits for the
+// CarrierServiceFinder, unless a different timeout is given to
+// WithCarrierServiceFinderTimeout.
+const DefaultCarrierServiceFinderTimeout = 5 * time.Second
+
+// ErrCarrierServicesUnavailable is returned when the CarrierServiceFinder fails,
+// or does not respond in time, so that carrier services cannot be found.
+var ErrCarrierServicesUnavailable = errors.New("carrier services temporarily unavailable")
+
 // CarrierServiceFinder is a software service used to get the list of all the available carriers
 // for a specific vehicle.
 type CarrierServiceFinder interface {
 	FindCarrierServicesForVehicle(vehicleType string) []CarrierService
 }
 
+// CarrierServiceFinderV2 is a CarrierServiceFinder able to report failures, e.g.
+// of the database or remote API it relies on, and expected to stop searching
+// when the given context is done. It receives the whole CarrierQuery, so that
+// only the carrier services able to carry out the delivery are returned.
+// Finders implementing only CarrierServiceFinder can be used via
+// AdaptCarrierServiceFinder.
+type CarrierServiceFinderV2 interface {
+	FindCarrierServices(ctx context.Context, query CarrierQuery) ([]CarrierService, error)
+}
+
+// CarrierQuery describes the delivery carrier services are looked for: the
+// vehicle, the postcode parcels are collected from and the ones they are
+// delivered to, more than one for routes. Parcels and PickupTime are set when
+// known; finders should not exclude services because of what is not known.
+type CarrierQuery struct {
+	Vehicle           string
+	PickupPostcode    *postcode.Postcode
+	DeliveryPostcodes []postcode.Postcode
+	Parcels           []Parcel
+	PickupTime        *time.Time
+}
+
+// Postcodes returns all the postcodes of the CarrierQuery, starting from the pickup one.
+func (cq CarrierQuery) Postcodes() []postcode.Postcode {
+	postcodes := []postcode.Postcode{}
+	if cq.PickupPostcode != nil {
+		postcodes = append(postcodes, *cq.PickupPostcode)
+	}
+	return append(postcodes, cq.DeliveryPostcodes...)
+}
+
+// newCarrierQuery returns the CarrierQuery for a delivery from the given pickup
+// postcode, of the given parcel, when not nil.
+func newCarrierQuery(vehicleType string, pickup *postcode.Postcode, deliveries []postcode.Postcode, parcel *Parcel, pickupTime *time.Time) CarrierQuery {
+	query := CarrierQuery{
+		Vehicle:           vehicleType,
+		PickupPostcode:    pickup,
+		DeliveryPostcodes: deliveries,
+		PickupTime:        pickupTime,
+	}
+	if parcel != nil {
+		query.Parcels = []Parcel{*parcel}
+	}
+	return query
+}
+
+// AdaptCarrierServiceFinder returns a CarrierServiceFinderV2 using the given
+// CarrierServiceFinder, which only receives the vehicle of the CarrierQuery;
+// it never fails, unless the context is already done.
+// The given finder is returned as it is when it already implements
+// CarrierServiceFinderV2.
+func AdaptCarrierServiceFinder(carrierServiceFinder CarrierServiceFinder) CarrierServiceFinderV2 {
+	if carrierServiceFinder == nil {
+		return nil
+	}
+	if carrierServiceFinderV2, ok := carrierServiceFinder.(CarrierServiceFinderV2); ok {
+		return carrierServiceFinderV2
+	}
+	return carrierServiceFinderAdapter{carrierServiceFinder}
+}
+
+type carrierServiceFinderAdapter struct {
+	carrierServiceFinder CarrierServiceFinder
+}
+
+// FindCarrierServices returns the carrier services found for the vehicle of
+// the query, ignoring the rest of it.
+func (csfa carrierServiceFinderAdapter) FindCarrierServices(ctx context.Context, query CarrierQuery) ([]CarrierService, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return csfa.carrierServiceFinder.FindCarrierServicesForVehicle(query.Vehicle), nil
+}
+
+// WithCarrierServiceFinderV2 sets the CarrierServiceFinderV2 used to find carrier
+// services, replacing the CarrierServiceFinder given to NewService.
+func WithCarrierServiceFinderV2(carrierServiceFinder CarrierServiceFinderV2) ServiceOption {
+	return func(s *Service) {
+		s.carrierServiceFinder = carrierServiceFinder
+	}
+}
+
+// WithCarrierServiceFinderTimeout sets how long the Service waits for the
+// carrier services to be found; when not positive, DefaultCarrierServiceFinderTimeout
+// is used.
+func WithCarrierServiceFinderTimeout(timeout time.Duration) ServiceOption {
+	return func(s *Service) {
+		s.carrierServiceFinderTimeout = timeout
+	}
+}
+
+// findCarrierServices returns the carrier services available for the given
+// query, giving up when the given context is done or the finder does not
+// respond in time; failures of the finder, timeouts included, are reported as
+// ErrCarrierServicesUnavailable.
+func (s *Service) findCarrierServices(ctx context.Context, query CarrierQuery) ([]CarrierService, error) {
+	timeout := s.carrierServiceFinderTimeout
+	if timeout <= 0 {
+		timeout = DefaultCarrierServiceFinderTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	carrierServices, err := s.carrierServiceFinder.FindCarrierServices(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCarrierServicesUnavailable, err)
+	}
+
+	return identifyCarrierServices(carrierServices), nil
+}
+
+// identifyCarrierServices returns a copy of the given carrier services where
+// the ones without a ServiceID are identified by their position among the
+// services of the same carrier, whose name is case insensitive.
+func identifyCarrierServices(carrierServices []CarrierService) []CarrierService {
+	identified := make([]CarrierService, len(carrierServices))
+	positions := map[string]int{}
+	for i, carrierService := range carrierServices {
+		carrierName := strings.ToLower(carrierService.Name)
+		positions[carrierName]++
+		if carrierService.ServiceID == "" {
+			carrierService.ServiceID = strconv.Itoa(positions[carrierName])
+		}
+		identified[i] = carrierService
+	}
+	return identified
+}
+
 // CarrierService represents the way a company carrying parcels around can deliver
 // parcels according to a specific vehicle. It includes the Carrier name, a Markup
-// (composed of both base markup and vehicle-based markup) and a DeliveryTime, in
-// minutes.
+// (composed of both base markup and vehicle-based markup) and a DeliveryTime.
+// BasePrice and ServiceMarkup are the two components of the Markup, used to itemise
+// prices; finders not able to distinguish them may leave both to zero.
+// WorkingHours and CutOff, a "15:04" formatted UK local time after which parcels
+// are collected on the next working day, are used to estimate when a parcel will
+// be delivered; when not set, the carrier is considered to work at any time.
+// ServiceID identifies the service among the ones of the same carrier, and must
+// not change as long as the service is offered; finders not setting it get the
+// position of the service among the ones of the carrier they found, from "1".
 type CarrierService struct {
-	Name         string
-	Markup       int64
-	DeliveryTime int64
+	Name          string
+	ServiceID     string
+	Markup        Money
+	BasePrice     Money
+	ServiceMarkup Money
+	DeliveryTime  DeliveryDuration
+	WorkingHours  *WorkingHours
+	CutOff        string
+}
+
+// markupComponents returns the carrier base price and the service markup which
+// compose the Markup; when they do not add up to it, the whole Markup is
+// considered as service markup.
+func (cs CarrierService) markupComponents() (Money, Money) {
+	sum, err := cs.BasePrice.Add(cs.ServiceMarkup)
+	if err != nil || sum != cs.Markup {
+		return NewMoney(0, cs.Markup.Currency), cs.Markup
+	}
+	return cs.BasePrice, cs.ServiceMarkup
 }
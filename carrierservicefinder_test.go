@@ -0,0 +1,240 @@
+package carrierpricing
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAdaptCarrierServiceFinder(t *testing.T) {
+	csf := AdaptCarrierServiceFinder(&mockCarrierServiceFinder{})
+
+	carrierServices, err := csf.FindCarrierServices(context.Background(), CarrierQuery{Vehicle: VehicleTypeSmallVan})
+	if err != nil {
+		t.Fatalf("FindCarrierServices returned error %v", err)
+	}
+
+	expectedCarrierServices := (&mockCarrierServiceFinder{}).FindCarrierServicesForVehicle(VehicleTypeSmallVan)
+	if !reflect.DeepEqual(expectedCarrierServices, carrierServices) {
+		t.Fatalf("expected carrier services '%v', received: '%v'", expectedCarrierServices, carrierServices)
+	}
+
+	// the context is checked before searching
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = csf.FindCarrierServices(ctx, CarrierQuery{Vehicle: VehicleTypeSmallVan})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected error '%v', received: '%v'", context.Canceled, err)
+	}
+
+	// finders implementing CarrierServiceFinderV2 are not adapted
+	failing := &mockFailingCarrierServiceFinder{}
+	if AdaptCarrierServiceFinder(failing) != CarrierServiceFinderV2(failing) {
+		t.Fatal("expected a CarrierServiceFinderV2 to be returned as it is")
+	}
+
+	if AdaptCarrierServiceFinder(nil) != nil {
+		t.Fatal("expected nil to be returned for a nil finder")
+	}
+}
+
+func TestCarrierServiceFinderErrors(t *testing.T) {
+	tests := []struct {
+		Description string
+		Finder      *mockFailingCarrierServiceFinder
+		Timeout     time.Duration
+		Cancelled   bool
+		Expected    error
+	}{
+		{
+			Description: "finder failing",
+			Finder:      &mockFailingCarrierServiceFinder{err: errors.New("connection refused")},
+			Expected:    ErrCarrierServicesUnavailable,
+		},
+		{
+			Description: "finder not responding in time",
+			Finder:      &mockFailingCarrierServiceFinder{delay: time.Second},
+			Timeout:     10 * time.Millisecond,
+			Expected:    ErrCarrierServicesUnavailable,
+		},
+		{
+			Description: "request cancelled while finding carrier services",
+			Finder:      &mockFailingCarrierServiceFinder{delay: time.Second},
+			Cancelled:   true,
+			Expected:    ErrCarrierServicesUnavailable,
+		},
+		{
+			Description: "finder with no carrier services",
+			Finder:      &mockFailingCarrierServiceFinder{},
+			Expected:    errNoAvailableCarrierServicesForVehicle,
+		},
+	}
+
+	for _, tc := range tests {
+		logger := log.New(ioutil.Discard, "", 0)
+		service, err := NewService(
+			logger,
+			nil,
+			&mockDistanceCalculator{},
+			nil,
+			WithCarrierServiceFinderV2(tc.Finder),
+			WithCarrierServiceFinderTimeout(tc.Timeout),
+		)
+		if err != nil {
+			t.Fatalf("NewService returned error %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		if tc.Cancelled {
+			cancel()
+		}
+
+		_, err = service.GetQuotesByCarrier(ctx, GetQuotesByCarrierArgs{
+			PickupPostcode:   "SW1A1AA",
+			DeliveryPostcode: "EC2A3LT",
+			Vehicle:          VehicleTypeSmallVan,
+		})
+		if !errors.Is(err, tc.Expected) {
+			t.Fatalf("%s: GetQuotesByCarrier expected error '%v', received: '%v'", tc.Description, tc.Expected, err)
+		}
+
+		_, err = service.GetBestQuotes(ctx, GetBestQuotesArgs{
+			PickupPostcode:   "SW1A1AA",
+			DeliveryPostcode: "EC2A3LT",
+		})
+		if !errors.Is(err, tc.Expected) {
+			t.Fatalf("%s: GetBestQuotes expected error '%v', received: '%v'", tc.Description, tc.Expected, err)
+		}
+
+		cancel()
+	}
+}
+
+func TestCarrierQuery(t *testing.T) {
+	pickupTime := time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)
+	parcel := Parcel{WeightKg: 2, LengthCm: 30, WidthCm: 20, HeightCm: 10}
+
+	tests := []struct {
+		Description              string
+		GetQuote                 func(service *Service) error
+		ExpectedDeliveryDistrict []string
+		ExpectedParcels          []Parcel
+	}{
+		{
+			Description: "quotes by carrier",
+			GetQuote: func(service *Service) error {
+				_, err := service.GetQuotesByCarrier(context.Background(), GetQuotesByCarrierArgs{
+					PickupPostcode:   "SW1A1AA",
+					DeliveryPostcode: "EC2A3LT",
+					Vehicle:          VehicleTypeSmallVan,
+					Parcel:           &parcel,
+					PickupTime:       &pickupTime,
+				})
+				return err
+			},
+			ExpectedDeliveryDistrict: []string{"EC2A"},
+			ExpectedParcels:          []Parcel{parcel},
+		},
+		{
+			Description: "shipment quote",
+			GetQuote: func(service *Service) error {
+				_, err := service.GetShipmentQuote(context.Background(), GetShipmentQuoteArgs{
+					PickupPostcode:   "SW1A1AA",
+					DeliveryPostcode: "EC2A3LT",
+					Vehicle:          VehicleTypeSmallVan,
+					Parcels:          []Parcel{parcel, parcel},
+					PickupTime:       &pickupTime,
+				})
+				return err
+			},
+			ExpectedDeliveryDistrict: []string{"EC2A"},
+			ExpectedParcels:          []Parcel{parcel, parcel},
+		},
+		{
+			Description: "route quote",
+			GetQuote: func(service *Service) error {
+				_, err := service.GetRouteQuote(context.Background(), GetRouteQuoteArgs{
+					PickupPostcode:    "SW1A1AA",
+					DeliveryPostcodes: []string{"EC2A3LT", "N11AA"},
+					Vehicle:           VehicleTypeSmallVan,
+					PickupTime:        &pickupTime,
+				})
+				return err
+			},
+			ExpectedDeliveryDistrict: []string{"EC2A", "N1"},
+		},
+	}
+
+	for _, tc := range tests {
+		finder := &mockRecordingCarrierServiceFinder{}
+		logger := log.New(ioutil.Discard, "", 0)
+		service, err := NewService(logger, nil, &mockDistanceCalculator{}, nil, WithCarrierServiceFinderV2(finder))
+		if err != nil {
+			t.Fatalf("NewService returned error %v", err)
+		}
+
+		err = tc.GetQuote(service)
+		if err != nil {
+			t.Fatalf("%s: returned error %v", tc.Description, err)
+		}
+
+		if len(finder.queries) != 1 {
+			t.Fatalf("%s: expected a single query, received: %d", tc.Description, len(finder.queries))
+		}
+		query := finder.queries[0]
+
+		deliveryDistricts := []string{}
+		for _, delivery := range query.DeliveryPostcodes {
+			deliveryDistricts = append(deliveryDistricts, delivery.District)
+		}
+
+		if query.Vehicle != VehicleTypeSmallVan ||
+			query.PickupPostcode == nil || query.PickupPostcode.District != "SW1A" ||
+			!reflect.DeepEqual(deliveryDistricts, tc.ExpectedDeliveryDistrict) ||
+			!reflect.DeepEqual(query.Parcels, tc.ExpectedParcels) ||
+			query.PickupTime == nil || !query.PickupTime.Equal(pickupTime) {
+			t.Fatalf("%s: unexpected query %+v", tc.Description, query)
+		}
+
+		if len(query.Postcodes()) != len(tc.ExpectedDeliveryDistrict)+1 {
+			t.Fatalf("%s: expected %d postcodes, received: %v", tc.Description, len(tc.ExpectedDeliveryDistrict)+1, query.Postcodes())
+		}
+	}
+}
+
+// mockRecordingCarrierServiceFinder records the queries it receives, returning
+// the carrier services of the mockCarrierServiceFinder.
+type mockRecordingCarrierServiceFinder struct {
+	queries []CarrierQuery
+}
+
+func (mrcsf *mockRecordingCarrierServiceFinder) FindCarrierServices(ctx context.Context, query CarrierQuery) ([]CarrierService, error) {
+	mrcsf.queries = append(mrcsf.queries, query)
+	return (&mockCarrierServiceFinder{}).FindCarrierServicesForVehicle(query.Vehicle), nil
+}
+
+// mockFailingCarrierServiceFinder implements both the finder interfaces; it finds
+// no carrier services, returning err when set, after the given delay unless the
+// context is done first.
+type mockFailingCarrierServiceFinder struct {
+	err   error
+	delay time.Duration
+}
+
+func (mfcsf *mockFailingCarrierServiceFinder) FindCarrierServices(ctx context.Context, query CarrierQuery) ([]CarrierService, error) {
+	select {
+	case <-time.After(mfcsf.delay):
+		return nil, mfcsf.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (mfcsf *mockFailingCarrierServiceFinder) FindCarrierServicesForVehicle(vehicleType string) []CarrierService {
+	carrierServices, _ := mfcsf.FindCarrierServices(context.Background(), CarrierQuery{Vehicle: vehicleType})
+	return carrierServices
+}
@@ -0,0 +1,164 @@
+package carrierservicefinders
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/giefferre/carrierpricing"
+)
+
+// CompositeCSF implements both the carrierpricing.CarrierServiceFinder and the
+// carrierpricing.CarrierServiceFinderV2 interfaces, combining the carrier
+// services found by several finders, e.g. a CSFFromJSONFile listing the own
+// fleet and a CSFFromSQL listing partner carriers. Finders are queried
+// concurrently; an optional fallback finder is queried only when they all fail
+// or none of them finds any carrier service.
+type CompositeCSF struct {
+	finders  []carrierpricing.CarrierServiceFinderV2
+	fallback carrierpricing.CarrierServiceFinderV2
+}
+
+// NewCompositeCSF returns a new CompositeCSF object combining the given finders,
+// listed by priority, and falling back to the given fallback finder, if not nil.
+// Finders implementing only carrierpricing.CarrierServiceFinder are adapted via
+// carrierpricing.AdaptCarrierServiceFinder.
+func NewCompositeCSF(finders []carrierpricing.CarrierServiceFinder, fallback carrierpricing.CarrierServiceFinder) *CompositeCSF {
+	csf := &CompositeCSF{
+		finders:  make([]carrierpricing.CarrierServiceFinderV2, len(finders)),
+		fallback: carrierpricing.AdaptCarrierServiceFinder(fallback),
+	}
+
+	for i, finder := range finders {
+		csf.finders[i] = carrierpricing.AdaptCarrierServiceFinder(finder)
+	}
+
+	return csf
+}
+
+// FindCarrierServicesForVehicle finds CarrierService objects for the given
+// vehicleType; as failures cannot be reported, no carrier services are
+// returned when all the finders fail.
+func (csf *CompositeCSF) FindCarrierServicesForVehicle(vehicleType string) []carrierpricing.CarrierService {
+	carrierServices, err := csf.FindCarrierServices(context.Background(), carrierpricing.CarrierQuery{Vehicle: vehicleType})
+	if err != nil {
+		return []carrierpricing.CarrierService{}
+	}
+	return carrierServices
+}
+
+// FindCarrierServices finds CarrierService objects for the given query via all
+// the finders at once, listing the ones of each finder in the order the finders
+// have been given. A carrier service found by more than one finder, i.e. having
+// the same carrier name and ServiceID, is listed once, as found by the first
+// finder listing it; carrier services without a ServiceID are never merged.
+// Finders failing, or not responding before the context is done, are ignored
+// as long as any other finder responds; when they all fail, or none of them
+// finds any carrier service, the result of the fallback finder is returned.
+// An error is returned only when all the finders fail and there is no fallback
+// finder, or it fails as well.
+func (csf *CompositeCSF) FindCarrierServices(ctx context.Context, query carrierpricing.CarrierQuery) ([]carrierpricing.CarrierService, error) {
+	carrierServices, err := csf.findCarrierServices(ctx, query)
+	if (err == nil && len(carrierServices) > 0) || csf.fallback == nil {
+		return carrierServices, err
+	}
+
+	fallbackCarrierServices, fallbackErr := csf.fallback.FindCarrierServices(ctx, query)
+	switch {
+	case fallbackErr == nil:
+		return fallbackCarrierServices, nil
+	case err == nil:
+		// the finders found nothing, which is a valid result after all
+		return carrierServices, nil
+	default:
+		return nil, fmt.Errorf("%v; fallback finder: %v", err, fallbackErr)
+	}
+}
+
+// findResult is the response of the finder at the given position.
+type findResult struct {
+	index           int
+	carrierServices []carrierpricing.CarrierService
+	err             error
+}
+
+// findCarrierServices queries all the finders concurrently, merging the carrier
+// services found; an error is returned when all the finders fail.
+func (csf *CompositeCSF) findCarrierServices(ctx context.Context, query carrierpricing.CarrierQuery) ([]carrierpricing.CarrierService, error) {
+	// the channel is buffered, so that finders responding after the context is
+	// done do not block forever
+	results := make(chan findResult, len(csf.finders))
+	for i, finder := range csf.finders {
+		go func(i int, finder carrierpricing.CarrierServiceFinderV2) {
+			carrierServices, err := finder.FindCarrierServices(ctx, query)
+			results <- findResult{index: i, carrierServices: carrierServices, err: err}
+		}(i, finder)
+	}
+
+	carrierServicesByFinder := make([][]carrierpricing.CarrierService, len(csf.finders))
+	errs := make([]error, len(csf.finders))
+	responded := make([]bool, len(csf.finders))
+
+	for pending := len(csf.finders); pending > 0; pending-- {
+		select {
+		case result := <-results:
+			carrierServicesByFinder[result.index] = result.carrierServices
+			errs[result.index] = result.err
+			responded[result.index] = true
+		case <-ctx.Done():
+			// the finders which have not responded yet are given up on
+			for i := range responded {
+				if !responded[i] {
+					errs[i] = ctx.Err()
+				}
+			}
+			return mergeCarrierServices(carrierServicesByFinder, errs)
+		}
+	}
+
+	return mergeCarrierServices(carrierServicesByFinder, errs)
+}
+
+// mergeCarrierServices returns the carrier services found by the finders which
+// did not fail, without the duplicates found by more than one finder; an error
+// listing all the failures is returned when all the finders failed.
+func mergeCarrierServices(carrierServicesByFinder [][]carrierpricing.CarrierService, errs []error) ([]carrierpricing.CarrierService, error) {
+	carrierServices := []carrierpricing.CarrierService{}
+	foundBy := map[string]int{}
+	failures := []string{}
+
+	for i, found := range carrierServicesByFinder {
+		if errs[i] != nil {
+			failures = append(failures, fmt.Sprintf("finder %d: %v", i, errs[i]))
+			continue
+		}
+
+		for _, carrierService := range found {
+			if key, identified := carrierServiceKey(carrierService); identified {
+				if finder, exists := foundBy[key]; exists && finder != i {
+					continue
+				}
+				foundBy[key] = i
+			}
+
+			carrierServices = append(carrierServices, carrierService)
+		}
+	}
+
+	if len(failures) > 0 && len(failures) == len(carrierServicesByFinder) {
+		return nil, fmt.Errorf("all carrier service finders failed: %s", strings.Join(failures, "; "))
+	}
+
+	return carrierServices, nil
+}
+
+// carrierServiceKey identifies a service of a carrier: the carrier name,
+// regardless of its case, and the ServiceID of the service; false is returned
+// when the service has no ServiceID, hence it cannot be told apart from the
+// other services of the carrier.
+func carrierServiceKey(carrierService carrierpricing.CarrierService) (string, bool) {
+	if carrierService.ServiceID == "" {
+		return "", false
+	}
+	return strings.ToLower(strings.TrimSpace(carrierService.Name)) + "|" + carrierService.ServiceID, true
+}
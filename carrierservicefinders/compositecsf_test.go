@@ -0,0 +1,183 @@
+package carrierservicefinders
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/giefferre/carrierpricing"
+)
+
+func TestCompositeCSF(t *testing.T) {
+	ownFleet := mockFinder{services: []carrierpricing.CarrierService{
+		{
+			Name:         "OwnVans",
+			ServiceID:    "1",
+			Markup:       carrierpricing.NewMoney(20, carrierpricing.CurrencyGBP),
+			DeliveryTime: carrierpricing.NewDeliveryDuration(1, carrierpricing.DeliveryTimeUnitWorkingDays),
+		},
+		{
+			Name:         "RoyalPackages",
+			ServiceID:    "1",
+			Markup:       carrierpricing.NewMoney(50, carrierpricing.CurrencyGBP),
+			DeliveryTime: carrierpricing.NewDeliveryDuration(1, carrierpricing.DeliveryTimeUnitWorkingDays),
+		},
+	}}
+	partners := mockFinder{services: []carrierpricing.CarrierService{
+		{
+			Name:         "royalpackages",
+			ServiceID:    "1",
+			Markup:       carrierpricing.NewMoney(45, carrierpricing.CurrencyGBP),
+			DeliveryTime: carrierpricing.NewDeliveryDuration(1, carrierpricing.DeliveryTimeUnitWorkingDays),
+		},
+		{
+			Name:         "RoyalPackages",
+			ServiceID:    "express",
+			Markup:       carrierpricing.NewMoney(60, carrierpricing.CurrencyGBP),
+			DeliveryTime: carrierpricing.NewDeliveryDuration(1, carrierpricing.DeliveryTimeUnitWorkingDays),
+		},
+		{
+			Name:         "RoyalPackages",
+			ServiceID:    "2",
+			Markup:       carrierpricing.NewMoney(5, carrierpricing.CurrencyGBP),
+			DeliveryTime: carrierpricing.NewDeliveryDuration(3, carrierpricing.DeliveryTimeUnitWorkingDays),
+		},
+		{
+			Name:         "Hercules",
+			ServiceID:    "1",
+			Markup:       carrierpricing.NewMoney(35, carrierpricing.CurrencyGBP),
+			DeliveryTime: carrierpricing.NewDeliveryDuration(5, carrierpricing.DeliveryTimeUnitWorkingDays),
+		},
+	}}
+	unidentified := mockFinder{services: []carrierpricing.CarrierService{
+		{
+			Name:         "RoyalPackages",
+			Markup:       carrierpricing.NewMoney(50, carrierpricing.CurrencyGBP),
+			DeliveryTime: carrierpricing.NewDeliveryDuration(1, carrierpricing.DeliveryTimeUnitWorkingDays),
+		},
+	}}
+	failing := mockFinder{err: errors.New("database unavailable")}
+	empty := mockFinder{services: []carrierpricing.CarrierService{}}
+	slow := mockFinder{services: partners.services, delay: time.Second}
+	fallback := NewCSFFromStaticData()
+
+	tests := []struct {
+		Description           string
+		Finders               []carrierpricing.CarrierServiceFinder
+		Fallback              carrierpricing.CarrierServiceFinder
+		ExpectedServiceNames  []string
+		ExpectedServiceMarkup []int64
+		ExpectError           bool
+	}{
+		{
+			Description:           "carrier services merged, duplicates taken from the first finder",
+			Finders:               []carrierpricing.CarrierServiceFinder{ownFleet, partners},
+			Fallback:              fallback,
+			ExpectedServiceNames:  []string{"OwnVans", "RoyalPackages", "RoyalPackages", "RoyalPackages", "Hercules"},
+			ExpectedServiceMarkup: []int64{20, 50, 60, 5, 35},
+		},
+		{
+			Description:           "carrier services without service ID never merged",
+			Finders:               []carrierpricing.CarrierServiceFinder{unidentified, unidentified},
+			ExpectedServiceNames:  []string{"RoyalPackages", "RoyalPackages"},
+			ExpectedServiceMarkup: []int64{50, 50},
+		},
+		{
+			Description:           "failing finder ignored",
+			Finders:               []carrierpricing.CarrierServiceFinder{failing, partners},
+			Fallback:              fallback,
+			ExpectedServiceNames:  []string{"royalpackages", "RoyalPackages", "RoyalPackages", "Hercules"},
+			ExpectedServiceMarkup: []int64{45, 60, 5, 35},
+		},
+		{
+			Description:           "finder not responding in time ignored",
+			Finders:               []carrierpricing.CarrierServiceFinder{slow, ownFleet},
+			ExpectedServiceNames:  []string{"OwnVans", "RoyalPackages"},
+			ExpectedServiceMarkup: []int64{20, 50},
+		},
+		{
+			Description: "all finders failing, no fallback",
+			Finders:     []carrierpricing.CarrierServiceFinder{failing, failing},
+			ExpectError: true,
+		},
+		{
+			Description:           "all finders failing, fallback used",
+			Finders:               []carrierpricing.CarrierServiceFinder{failing, failing},
+			Fallback:              fallback,
+			ExpectedServiceNames:  []string{"RoyalPackages", "Hercules", "CollectTimes"},
+			ExpectedServiceMarkup: []int64{80, 35, 70},
+		},
+		{
+			Description:           "no carrier services found, fallback used",
+			Finders:               []carrierpricing.CarrierServiceFinder{empty, failing},
+			Fallback:              fallback,
+			ExpectedServiceNames:  []string{"RoyalPackages", "Hercules", "CollectTimes"},
+			ExpectedServiceMarkup: []int64{80, 35, 70},
+		},
+		{
+			Description:           "no carrier services found, fallback failing",
+			Finders:               []carrierpricing.CarrierServiceFinder{empty},
+			Fallback:              failing,
+			ExpectedServiceNames:  []string{},
+			ExpectedServiceMarkup: []int64{},
+		},
+		{
+			Description: "all finders and fallback failing",
+			Finders:     []carrierpricing.CarrierServiceFinder{failing},
+			Fallback:    failing,
+			ExpectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		csf := NewCompositeCSF(tc.Finders, tc.Fallback)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		carrierServices, err := csf.FindCarrierServices(ctx, carrierpricing.CarrierQuery{Vehicle: carrierpricing.VehicleTypeSmallVan})
+		cancel()
+
+		if tc.ExpectError {
+			if err == nil {
+				t.Fatalf("%s: expected error, received carrier services %+v", tc.Description, carrierServices)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", tc.Description, err)
+		}
+
+		serviceNames := []string{}
+		serviceMarkup := []int64{}
+		for _, carrierService := range carrierServices {
+			serviceNames = append(serviceNames, carrierService.Name)
+			serviceMarkup = append(serviceMarkup, carrierService.Markup.Amount)
+		}
+
+		if !reflect.DeepEqual(serviceNames, tc.ExpectedServiceNames) || !reflect.DeepEqual(serviceMarkup, tc.ExpectedServiceMarkup) {
+			t.Fatalf(
+				"%s: expected carrier services %v with markup %v, received: %v with markup %v",
+				tc.Description, tc.ExpectedServiceNames, tc.ExpectedServiceMarkup, serviceNames, serviceMarkup,
+			)
+		}
+	}
+}
+
+// mockFinder returns the given carrier services, or error, after the given
+// delay, regardless of the context.
+type mockFinder struct {
+	services []carrierpricing.CarrierService
+	err      error
+	delay    time.Duration
+}
+
+func (mf mockFinder) FindCarrierServicesForVehicle(vehicleType string) []carrierpricing.CarrierService {
+	carrierServices, _ := mf.FindCarrierServices(context.Background(), carrierpricing.CarrierQuery{Vehicle: vehicleType})
+	return carrierServices
+}
+
+func (mf mockFinder) FindCarrierServices(ctx context.Context, query carrierpricing.CarrierQuery) ([]carrierpricing.CarrierService, error) {
+	time.Sleep(mf.delay)
+	return mf.services, mf.err
+}
@@ -0,0 +1,252 @@
+package carrierservicefinders
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/giefferre/carrierpricing"
+)
+
+// ListCarriers returns all the carriers currently loaded, in the order they are
+// listed in the file.
+func (csf *CSFFromJSONFile) ListCarriers() ([]carrierpricing.CarrierDefinition, error) {
+	snapshot := csf.snapshot.Load().(*csfSnapshot)
+
+	carriers := make([]carrierpricing.CarrierDefinition, len(snapshot.carriers))
+	for i, carrier := range snapshot.carriers {
+		carriers[i] = carrier.definition()
+	}
+
+	return carriers, nil
+}
+
+// GetCarrier returns the loaded carrier with the given name, regardless of its
+// case; carrierpricing.ErrCarrierNotFound is returned when there is no such carrier.
+func (csf *CSFFromJSONFile) GetCarrier(name string) (*carrierpricing.CarrierDefinition, error) {
+	snapshot := csf.snapshot.Load().(*csfSnapshot)
+
+	i := findCarrier(snapshot.carriers, name)
+	if i < 0 {
+		return nil, carrierpricing.ErrCarrierNotFound
+	}
+
+	definition := snapshot.carriers[i].definition()
+	return &definition, nil
+}
+
+// CreateCarrier appends the given carrier to the file, as long as no other
+// carrier has the same name, returning it as written.
+func (csf *CSFFromJSONFile) CreateCarrier(definition *carrierpricing.CarrierDefinition) (*carrierpricing.CarrierDefinition, error) {
+	newCarrier := carrier{}
+
+	err := csf.change(func(carriers []carrier) ([]carrier, error) {
+		if findCarrier(carriers, definition.Name) >= 0 {
+			return nil, carrierpricing.ErrCarrierAlreadyExists
+		}
+
+		var err error
+		newCarrier, err = carrierFromDefinition(definition)
+		if err != nil {
+			return nil, err
+		}
+
+		return append(carriers, newCarrier), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	created := newCarrier.definition()
+	return &created, nil
+}
+
+// UpdateCarrier replaces the carrier with the given name, keeping its position
+// in the file and its dispatcher, as long as its ETag is the given one; the
+// carrier is returned as written.
+func (csf *CSFFromJSONFile) UpdateCarrier(name, etag string, definition *carrierpricing.CarrierDefinition) (*carrierpricing.CarrierDefinition, error) {
+	updatedCarrier := carrier{}
+
+	err := csf.change(func(carriers []carrier) ([]carrier, error) {
+		i, err := findCarrierVersion(carriers, name, etag)
+		if err != nil {
+			return nil, err
+		}
+
+		if j := findCarrier(carriers, definition.Name); j >= 0 && j != i {
+			return nil, carrierpricing.ErrCarrierAlreadyExists
+		}
+
+		updatedCarrier, err = carrierFromDefinition(definition)
+		if err != nil {
+			return nil, err
+		}
+		updatedCarrier.Dispatcher = carriers[i].Dispatcher
+
+		carriers[i] = updatedCarrier
+		return carriers, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	updated := updatedCarrier.definition()
+	return &updated, nil
+}
+
+// DeleteCarrier removes the carrier with the given name from the file, as long
+// as its ETag is the given one.
+func (csf *CSFFromJSONFile) DeleteCarrier(name, etag string) error {
+	return csf.change(func(carriers []carrier) ([]carrier, error) {
+		i, err := findCarrierVersion(carriers, name, etag)
+		if err != nil {
+			return nil, err
+		}
+
+		return append(carriers[:i], carriers[i+1:]...), nil
+	})
+}
+
+// change applies the given change to a copy of the loaded carriers, writing the
+// changed ones to the file and then replacing the loaded ones, so that they are
+// used by the following quotes. Nothing is changed when apply returns an error
+// or the file cannot be written.
+func (csf *CSFFromJSONFile) change(apply func(carriers []carrier) ([]carrier, error)) error {
+	csf.mutex.Lock()
+	defer csf.mutex.Unlock()
+
+	snapshot := csf.snapshot.Load().(*csfSnapshot)
+
+	carriers, err := apply(append([]carrier{}, snapshot.carriers...))
+	if err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(carriers, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	err = writeFileAtomically(csf.jsonFilePath, append(content, '\n'))
+	if err != nil {
+		return err
+	}
+
+	csf.snapshot.Store(newCSFSnapshot(carriers))
+	return nil
+}
+
+// findCarrier returns the position of the carrier with the given name, -1 when
+// there is no such carrier.
+func findCarrier(carriers []carrier, name string) int {
+	for i, carrier := range carriers {
+		if strings.EqualFold(strings.TrimSpace(carrier.Name), strings.TrimSpace(name)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// findCarrierVersion returns the position of the carrier with the given name,
+// as long as its ETag is the given one.
+func findCarrierVersion(carriers []carrier, name, etag string) (int, error) {
+	i := findCarrier(carriers, name)
+	if i < 0 {
+		return -1, carrierpricing.ErrCarrierNotFound
+	}
+
+	definition := carriers[i].definition()
+	if definition.ETag() != etag {
+		return -1, carrierpricing.ErrCarrierModified
+	}
+
+	return i, nil
+}
+
+// definition returns the CarrierDefinition of the carrier.
+func (c carrier) definition() carrierpricing.CarrierDefinition {
+	services := make([]carrierpricing.ServiceDefinition, len(c.Services))
+	for i, service := range c.Services {
+		services[i] = carrierpricing.ServiceDefinition(service)
+	}
+
+	return carrierpricing.CarrierDefinition{
+		Name:         c.Name,
+		Currency:     c.Currency,
+		BasePrice:    c.BasePrice,
+		WorkingHours: c.WorkingHours,
+		CutOff:       c.CutOff,
+		Services:     services,
+	}
+}
+
+// carrierFromDefinition returns the carrier of the given CarrierDefinition,
+// checked as the carriers of a file are (see ValidateCarriersJSON), whose
+// services without an ID are identified as in NewCSFFromJSONFile; the error
+// wraps carrierpricing.ErrInvalidCarrier, listing all the problems found.
+func carrierFromDefinition(definition *carrierpricing.CarrierDefinition) (carrier, error) {
+	services := make([]service, len(definition.Services))
+	for i, serviceDefinition := range definition.Services {
+		services[i] = service(serviceDefinition)
+	}
+
+	identifyServices(services)
+
+	newCarrier := carrier{
+		Name:         strings.TrimSpace(definition.Name),
+		Currency:     definition.Currency,
+		BasePrice:    definition.BasePrice,
+		WorkingHours: definition.WorkingHours,
+		CutOff:       definition.CutOff,
+		Services:     services,
+	}
+
+	content, err := json.Marshal([]carrier{newCarrier})
+	if err != nil {
+		return carrier{}, err
+	}
+
+	problems := ValidateCarriersJSON(content)
+	if len(problems) > 0 {
+		// the carrier is validated on its own, hence its path is made relative to it
+		messages := make([]string, len(problems))
+		for i, problem := range problems {
+			messages[i] = strings.Replace(problem.Path, "$[0]", "$", 1) + ": " + problem.Message
+		}
+		return carrier{}, fmt.Errorf("%w: %s", carrierpricing.ErrInvalidCarrier, strings.Join(messages, "; "))
+	}
+
+	return newCarrier, nil
+}
+
+// writeFileAtomically replaces the file at the given path with the given
+// content, keeping its permissions; the content is written to a temporary file
+// renamed afterwards, so that the file is never read while half written.
+func writeFileAtomically(path string, content []byte) error {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	tempFile, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+
+	_, err = tempFile.Write(content)
+	if err == nil {
+		err = tempFile.Chmod(fileInfo.Mode())
+	}
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile.Name(), path)
+}
@@ -0,0 +1,186 @@
+package carrierservicefinders
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/giefferre/carrierpricing"
+)
+
+const catalogueFixture = `[
+    {
+        "carrier_name": "RoyalPackages",
+        "base_price": 30,
+        "services": [
+            {
+                "delivery_time": {"value": 1, "unit": "working_days"},
+                "markup": 50,
+                "vehicles": ["small_van"]
+            }
+        ],
+        "dispatcher": {"url": "http://royalpackages.example"}
+    },
+    {
+        "carrier_name": "CollectTimes",
+        "base_price": 50,
+        "services": [
+            {
+                "delivery_time": {"value": 2, "unit": "hours"},
+                "markup": 20,
+                "vehicles": ["bicycle"]
+            }
+        ]
+    }
+]`
+
+func TestCSFFromJSONFileCatalogue(t *testing.T) {
+	directory, err := ioutil.TempDir("", "csfcatalogue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	jsonFilePath := filepath.Join(directory, "carriers.json")
+	err = ioutil.WriteFile(jsonFilePath, []byte(catalogueFixture), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csf, err := NewCSFFromJSONFile(jsonFilePath)
+	if err != nil {
+		t.Fatalf("NewCSFFromJSONFile returned error %v", err)
+	}
+
+	newCarrier := &carrierpricing.CarrierDefinition{
+		Name:      "Zippy",
+		BasePrice: 5,
+		Services: []carrierpricing.ServiceDefinition{
+			{
+				DeliveryTime: carrierpricing.NewDeliveryDuration(1, carrierpricing.DeliveryTimeUnitHours),
+				Markup:       3,
+				Vehicles:     []string{carrierpricing.VehicleTypeBicycle},
+			},
+		},
+	}
+
+	createdCarrier, err := csf.CreateCarrier(newCarrier)
+	if err != nil {
+		t.Fatalf("CreateCarrier returned error %v", err)
+	}
+	if createdCarrier.Name != "Zippy" || createdCarrier.Services[0].ID != "1" {
+		t.Fatalf("expected the carrier as written, with its service identified, received: %+v", createdCarrier)
+	}
+	assertServiceNames(t, "carrier created", csf, carrierpricing.VehicleTypeBicycle, []string{"CollectTimes", "Zippy"})
+
+	_, err = csf.CreateCarrier(&carrierpricing.CarrierDefinition{Name: "zippy", Services: newCarrier.Services})
+	if !errors.Is(err, carrierpricing.ErrCarrierAlreadyExists) {
+		t.Fatalf("expected error '%v' creating a duplicate carrier, received: '%v'", carrierpricing.ErrCarrierAlreadyExists, err)
+	}
+
+	invalidCarrier := &carrierpricing.CarrierDefinition{
+		Name:      "Invalid",
+		BasePrice: -1,
+		Services:  []carrierpricing.ServiceDefinition{{DeliveryTime: newCarrier.Services[0].DeliveryTime, Vehicles: []string{"smal_van"}}},
+	}
+	_, err = csf.CreateCarrier(invalidCarrier)
+	if !errors.Is(err, carrierpricing.ErrInvalidCarrier) ||
+		!strings.Contains(err.Error(), "$.base_price") ||
+		!strings.Contains(err.Error(), "$.services[0].vehicles[0]") {
+		t.Fatalf("expected error '%v' listing all the problems, received: '%v'", carrierpricing.ErrInvalidCarrier, err)
+	}
+
+	royalPackages, err := csf.GetCarrier("royalpackages")
+	if err != nil {
+		t.Fatalf("GetCarrier returned error %v", err)
+	}
+	etag := royalPackages.ETag()
+
+	// services listed without ids are identified by their position
+	if royalPackages.Services[0].ID != "1" {
+		t.Fatalf("expected service id '1', received: '%s'", royalPackages.Services[0].ID)
+	}
+
+	royalPackages.Services[0].Vehicles = append(royalPackages.Services[0].Vehicles, carrierpricing.VehicleTypeBicycle)
+	royalPackages.Services = append(royalPackages.Services, carrierpricing.ServiceDefinition{
+		DeliveryTime: carrierpricing.NewDeliveryDuration(3, carrierpricing.DeliveryTimeUnitWorkingDays),
+		Markup:       5,
+		Vehicles:     []string{carrierpricing.VehicleTypeSmallVan},
+	})
+	updatedCarrier, err := csf.UpdateCarrier("RoyalPackages", etag, royalPackages)
+	if err != nil {
+		t.Fatalf("UpdateCarrier returned error %v", err)
+	}
+	if current, _ := csf.GetCarrier("RoyalPackages"); !reflect.DeepEqual(updatedCarrier, current) {
+		t.Fatalf("expected the carrier as written '%+v', received: '%+v'", current, updatedCarrier)
+	}
+	assertServiceNames(t, "carrier updated", csf, carrierpricing.VehicleTypeBicycle, []string{"RoyalPackages", "CollectTimes", "Zippy"})
+
+	_, err = csf.UpdateCarrier("RoyalPackages", etag, royalPackages)
+	if !errors.Is(err, carrierpricing.ErrCarrierModified) {
+		t.Fatalf("expected error '%v' updating with an outdated ETag, received: '%v'", carrierpricing.ErrCarrierModified, err)
+	}
+
+	royalPackages, err = csf.GetCarrier("RoyalPackages")
+	if err != nil {
+		t.Fatalf("GetCarrier returned error %v", err)
+	}
+	etag = royalPackages.ETag()
+
+	royalPackages.Name = "CollectTimes"
+	_, err = csf.UpdateCarrier("RoyalPackages", etag, royalPackages)
+	if !errors.Is(err, carrierpricing.ErrCarrierAlreadyExists) {
+		t.Fatalf("expected error '%v' renaming as another carrier, received: '%v'", carrierpricing.ErrCarrierAlreadyExists, err)
+	}
+
+	collectTimes, err := csf.GetCarrier("CollectTimes")
+	if err != nil {
+		t.Fatalf("GetCarrier returned error %v", err)
+	}
+
+	err = csf.DeleteCarrier("CollectTimes", collectTimes.ETag())
+	if err != nil {
+		t.Fatalf("DeleteCarrier returned error %v", err)
+	}
+	assertServiceNames(t, "carrier deleted", csf, carrierpricing.VehicleTypeBicycle, []string{"RoyalPackages", "Zippy"})
+
+	_, err = csf.GetCarrier("CollectTimes")
+	if err != carrierpricing.ErrCarrierNotFound {
+		t.Fatalf("expected error '%v' getting a deleted carrier, received: '%v'", carrierpricing.ErrCarrierNotFound, err)
+	}
+
+	// the changes have been written to the file, keeping the dispatcher
+	content, err := ioutil.ReadFile(jsonFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := ValidateCarriersJSON(content); len(problems) > 0 {
+		t.Fatalf("expected a valid file, received problems %v", problems)
+	}
+	if !strings.Contains(string(content), "http://royalpackages.example") {
+		t.Fatalf("expected the dispatcher to be kept, received file %s", content)
+	}
+	if rawConfig, found := csf.DispatcherConfig("royalpackages"); !found || !strings.Contains(string(rawConfig), "http://royalpackages.example") {
+		t.Fatalf("expected the dispatcher of the updated carrier, received: '%s'", rawConfig)
+	}
+
+	reloaded, err := NewCSFFromJSONFile(jsonFilePath)
+	if err != nil {
+		t.Fatalf("NewCSFFromJSONFile returned error %v", err)
+	}
+	assertServiceNames(t, "file reloaded", reloaded, carrierpricing.VehicleTypeBicycle, []string{"RoyalPackages", "Zippy"})
+
+	// the ids given to the services have been written to the file
+	carrierServices := reloaded.FindCarrierServicesForVehicle(carrierpricing.VehicleTypeSmallVan)
+	serviceIDs := []string{}
+	for _, carrierService := range carrierServices {
+		serviceIDs = append(serviceIDs, carrierService.Name+"/"+carrierService.ServiceID)
+	}
+	if expected := []string{"RoyalPackages/1", "RoyalPackages/2"}; !reflect.DeepEqual(serviceIDs, expected) {
+		t.Fatalf("expected services %v, received: %v", expected, serviceIDs)
+	}
+}
@@ -1,69 +1,319 @@
 package carrierservicefinders
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/giefferre/carrierpricing"
+	"github.com/giefferre/carrierpricing/postcode"
 )
 
-// CSFFromJSONFile implements the carrierpricing.CarrierService interface;
-// the source of data is a single JSON encoded file from local storage.
+// coverageAreaRegexp matches the postcode areas (e.g. "SW") and districts
+// (e.g. "SW1A") services may declare to cover.
+var coverageAreaRegexp = regexp.MustCompile(`^[A-Z]{1,2}([0-9][0-9A-Z]?)?$`)
+
+// CSFFromJSONFile implements both the carrierpricing.CarrierServiceFinder and
+// the carrierpricing.CarrierServiceFinderV2 interfaces; the source of data is a
+// single JSON encoded file from local storage.
+// Carrier services are indexed by vehicle type; the file can be reloaded while
+// carrier services are being found, atomically replacing the loaded ones.
+// CSFFromJSONFile also implements the carrierpricing.CarrierCatalogue interface,
+// writing the changes to the file.
 type CSFFromJSONFile struct {
-	carriers []carrier
+	jsonFilePath string
+	snapshot     atomic.Value
+
+	// mutex serialises reloads and changes, so that none of them is lost.
+	mutex sync.Mutex
+}
+
+// csfSnapshot is the content of the file as loaded at a given time: the
+// carriers, and their services by vehicle type, in the order they are listed
+// in the file.
+type csfSnapshot struct {
+	carriers          []carrier
+	servicesByVehicle map[string][]indexedService
+}
+
+// indexedService is a CarrierService together with the service it comes from,
+// used to check whether it can carry out a delivery.
+type indexedService struct {
+	carrierService carrierpricing.CarrierService
+	service        service
 }
 
 // NewCSFFromJSONFile returns a fresh CSFFromJSONFile object having the list of available
 // carriers loaded in memory. An error is returned if the file is not found or
 // it does not contain valid objects.
+// Base prices and markups are expressed in minor units of the optional carrier
+// "currency", GBP when not specified. Delivery times must state their unit;
+// carriers may list their "working_hours" and "cut_off" time, used to estimate
+// when parcels will be delivered. Services may restrict the deliveries they
+// carry out via "coverage_areas", the postcode areas or districts all the
+// stops must be in, "max_weight_kg" and "max_length_cm", the limits of each
+// parcel, and "operating_hours", the UK local times parcels can be collected at.
+// Services are identified by their "id", unique among the services of the
+// carrier; services without it are given the one following the highest numeric
+// ID of the carrier, which is their position when no service has an ID.
 func NewCSFFromJSONFile(jsonFilePath string) (*CSFFromJSONFile, error) {
-	jsonFileContent, err := ioutil.ReadFile(jsonFilePath)
+	csf := &CSFFromJSONFile{
+		jsonFilePath: jsonFilePath,
+	}
+
+	err := csf.Reload()
 	if err != nil {
 		return nil, err
 	}
 
+	return csf, nil
+}
+
+// Reload loads the file again, replacing the carrier services currently used;
+// if the file is not found or it does not contain valid objects, an error is
+// returned and the current carrier services are kept. A *ValidationError lists
+// all the problems found in the file (see ValidateCarriersJSON).
+func (csf *CSFFromJSONFile) Reload() error {
+	csf.mutex.Lock()
+	defer csf.mutex.Unlock()
+
+	jsonFileContent, err := ioutil.ReadFile(csf.jsonFilePath)
+	if err != nil {
+		return err
+	}
+
+	if problems := ValidateCarriersJSON(jsonFileContent); len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+
 	carriers := []carrier{}
 	err = json.Unmarshal(jsonFileContent, &carriers)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	for _, carrier := range carriers {
+		identifyServices(carrier.Services)
+	}
+
+	csf.snapshot.Store(newCSFSnapshot(carriers))
+	return nil
+}
+
+// Watch checks every interval whether the file has been modified, reloading it
+// when so, until the given context is done. onReload, when not nil, is called
+// with the result of each reload; the current carrier services are kept when
+// the file is not valid, until it is modified again.
+func (csf *CSFFromJSONFile) Watch(ctx context.Context, interval time.Duration, onReload func(err error)) {
+	lastModified := csf.modified()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		modified := csf.modified()
+		if modified == lastModified {
+			continue
+		}
+		lastModified = modified
+
+		err := csf.Reload()
+		if onReload != nil {
+			onReload(err)
+		}
 	}
+}
 
-	return &CSFFromJSONFile{
-		carriers: carriers,
-	}, nil
+// modified returns a string identifying the current version of the file, made
+// of its modification time and size; it is empty when the file is not found.
+func (csf *CSFFromJSONFile) modified() string {
+	fileInfo, err := os.Stat(csf.jsonFilePath)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d", fileInfo.ModTime().UnixNano(), fileInfo.Size())
 }
 
 // FindCarrierServicesForVehicle finds CarrierService objects for the given vehicleType.
 func (csf *CSFFromJSONFile) FindCarrierServicesForVehicle(vehicleType string) []carrierpricing.CarrierService {
+	return csf.findCarrierServices(carrierpricing.CarrierQuery{Vehicle: vehicleType})
+}
+
+// FindCarrierServices finds CarrierService objects for the vehicle of the given
+// query, whose services cover its postcodes and can carry its parcels at its
+// pickup time.
+func (csf *CSFFromJSONFile) FindCarrierServices(ctx context.Context, query carrierpricing.CarrierQuery) ([]carrierpricing.CarrierService, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return csf.findCarrierServices(query), nil
+}
+
+func (csf *CSFFromJSONFile) findCarrierServices(query carrierpricing.CarrierQuery) []carrierpricing.CarrierService {
+	snapshot := csf.snapshot.Load().(*csfSnapshot)
+
 	carrierServices := []carrierpricing.CarrierService{}
+	for _, indexed := range snapshot.servicesByVehicle[query.Vehicle] {
+		if indexed.service.matches(query) {
+			carrierServices = append(carrierServices, indexed.carrierService)
+		}
+	}
 
-	// this surely is NOT the most efficient way to store this data,
-	// but it is good enough for the purpose of this simple application.
-	for _, carrier := range csf.carriers {
+	return carrierServices
+}
+
+// DispatcherConfig returns the "dispatcher" object of the loaded carrier with
+// the given name, regardless of its case, so that the carriers currently loaded
+// are dispatched as configured (see carrierdispatchers.NewCDRegistryFromSource);
+// false is returned when there is no such carrier or it has no dispatcher.
+func (csf *CSFFromJSONFile) DispatcherConfig(carrierName string) (json.RawMessage, bool) {
+	snapshot := csf.snapshot.Load().(*csfSnapshot)
+
+	i := findCarrier(snapshot.carriers, carrierName)
+	if i < 0 || len(snapshot.carriers[i].Dispatcher) == 0 {
+		return nil, false
+	}
+
+	return snapshot.carriers[i].Dispatcher, true
+}
+
+// newCSFSnapshot indexes the carrier services of the given carriers by vehicle type.
+func newCSFSnapshot(carriers []carrier) *csfSnapshot {
+	snapshot := &csfSnapshot{
+		carriers:          carriers,
+		servicesByVehicle: map[string][]indexedService{},
+	}
+
+	for _, carrier := range carriers {
 		for _, service := range carrier.Services {
+			carrierService := carrier.carrierService(service)
+
 			for _, vehicle := range service.Vehicles {
-				if vehicleType == vehicle {
-					carrierServices = append(carrierServices, carrierpricing.CarrierService{
-						Name:         carrier.Name,
-						Markup:       carrier.BasePrice + service.Markup,
-						DeliveryTime: service.DeliveryTime,
-					})
-				}
+				snapshot.servicesByVehicle[vehicle] = append(
+					snapshot.servicesByVehicle[vehicle],
+					indexedService{carrierService: carrierService, service: service},
+				)
 			}
 		}
 	}
 
-	return carrierServices
+	return snapshot
 }
 
 type carrier struct {
-	Name      string    `json:"carrier_name"`
-	BasePrice int64     `json:"base_price"`
-	Services  []service `json:"services"`
+	Name         string                       `json:"carrier_name"`
+	Currency     string                       `json:"currency,omitempty"`
+	BasePrice    int64                        `json:"base_price"`
+	WorkingHours *carrierpricing.WorkingHours `json:"working_hours,omitempty"`
+	CutOff       string                       `json:"cut_off,omitempty"`
+	Services     []service                    `json:"services"`
+
+	// Dispatcher is not used to find carrier services, but it is kept as is
+	// when the carrier is changed via the catalogue (see DispatcherConfig).
+	Dispatcher json.RawMessage `json:"dispatcher,omitempty"`
+}
+
+// carrierService returns the CarrierService of the given service of the carrier.
+func (c carrier) carrierService(s service) carrierpricing.CarrierService {
+	return carrierpricing.CarrierService{
+		Name:          c.Name,
+		ServiceID:     s.ID,
+		Markup:        carrierpricing.NewMoney(c.BasePrice+s.Markup, c.currency()),
+		BasePrice:     carrierpricing.NewMoney(c.BasePrice, c.currency()),
+		ServiceMarkup: carrierpricing.NewMoney(s.Markup, c.currency()),
+		DeliveryTime:  s.DeliveryTime,
+		WorkingHours:  c.WorkingHours,
+		CutOff:        c.CutOff,
+	}
+}
+
+// identifyServices sets the ID of the given services not having one, following
+// the highest numeric ID of the others: services listed without IDs are
+// identified by their position, from "1".
+func identifyServices(services []service) {
+	lastID := 0
+	for _, service := range services {
+		if id, err := strconv.Atoi(service.ID); err == nil && id > lastID {
+			lastID = id
+		}
+	}
+
+	for i := range services {
+		if services[i].ID == "" {
+			lastID++
+			services[i].ID = strconv.Itoa(lastID)
+		}
+	}
+}
+
+// currency returns the ISO 4217 code of the currency base price and markups are
+// expressed in, in minor units; when not specified, GBP is assumed.
+func (c carrier) currency() string {
+	if c.Currency == "" {
+		return carrierpricing.CurrencyGBP
+	}
+	return c.Currency
 }
 
 type service struct {
-	DeliveryTime int64    `json:"delivery_time"`
-	Markup       int64    `json:"markup"`
-	Vehicles     []string `json:"vehicles"`
+	ID             string                          `json:"id,omitempty"`
+	DeliveryTime   carrierpricing.DeliveryDuration `json:"delivery_time"`
+	Markup         int64                           `json:"markup"`
+	Vehicles       []string                        `json:"vehicles"`
+	CoverageAreas  []string                        `json:"coverage_areas,omitempty"`
+	MaxWeightKg    float64                         `json:"max_weight_kg,omitempty"`
+	MaxLengthCm    float64                         `json:"max_length_cm,omitempty"`
+	OperatingHours *carrierpricing.WorkingHours    `json:"operating_hours,omitempty"`
+}
+
+// matches returns true if the service can carry out the delivery of the given
+// query, regardless of the vehicle; what the query does not state is not checked.
+func (s service) matches(query carrierpricing.CarrierQuery) bool {
+	if len(s.CoverageAreas) > 0 {
+		for _, stop := range query.Postcodes() {
+			if !s.covers(stop) {
+				return false
+			}
+		}
+	}
+
+	for _, parcel := range query.Parcels {
+		if s.MaxWeightKg > 0 && parcel.WeightKg > s.MaxWeightKg {
+			return false
+		}
+		if s.MaxLengthCm > 0 && math.Max(parcel.LengthCm, math.Max(parcel.WidthCm, parcel.HeightCm)) > s.MaxLengthCm {
+			return false
+		}
+	}
+
+	if s.OperatingHours != nil && query.PickupTime != nil && !s.OperatingHours.IncludesTimeOfDay(*query.PickupTime) {
+		return false
+	}
+
+	return true
+}
+
+// covers returns true if the given postcode is in one of the coverage areas of the service.
+func (s service) covers(stop postcode.Postcode) bool {
+	for _, area := range s.CoverageAreas {
+		if strings.EqualFold(area, stop.Area) || strings.EqualFold(area, stop.District) {
+			return true
+		}
+	}
+	return false
 }
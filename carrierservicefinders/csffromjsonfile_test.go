@@ -0,0 +1,451 @@
+package carrierservicefinders
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/giefferre/carrierpricing"
+	"github.com/giefferre/carrierpricing/postcode"
+)
+
+const filtersFixture = `[
+    {
+        "carrier_name": "RoyalPackages",
+        "base_price": 30,
+        "services": [
+            {
+                "id": "east",
+                "delivery_time": {"value": 1, "unit": "working_days"},
+                "markup": 10,
+                "vehicles": ["small_van"],
+                "coverage_areas": ["E"]
+            },
+            {
+                "id": "city",
+                "delivery_time": {"value": 1, "unit": "working_days"},
+                "markup": 20,
+                "vehicles": ["small_van"],
+                "coverage_areas": ["SW", "EC1A"]
+            },
+            {
+                "id": "light",
+                "delivery_time": {"value": 1, "unit": "working_days"},
+                "markup": 30,
+                "vehicles": ["small_van"],
+                "max_weight_kg": 5
+            },
+            {
+                "id": "short",
+                "delivery_time": {"value": 1, "unit": "working_days"},
+                "markup": 40,
+                "vehicles": ["small_van"],
+                "max_length_cm": 50
+            },
+            {
+                "id": "morning",
+                "delivery_time": {"value": 1, "unit": "working_days"},
+                "markup": 50,
+                "vehicles": ["small_van"],
+                "operating_hours": {"from": "08:00", "to": "12:00"}
+            }
+        ]
+    }
+]`
+
+func TestCSFFromJSONFileFindCarrierServices(t *testing.T) {
+	directory, err := ioutil.TempDir("", "csffromjsonfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	jsonFilePath := filepath.Join(directory, "carriers.json")
+	err = ioutil.WriteFile(jsonFilePath, []byte(filtersFixture), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csf, err := NewCSFFromJSONFile(jsonFilePath)
+	if err != nil {
+		t.Fatalf("NewCSFFromJSONFile returned error %v", err)
+	}
+
+	// January, so that UK local time is UTC
+	morning := time.Date(2026, 1, 15, 8, 0, 0, 0, time.UTC)
+	beforeNoon := time.Date(2026, 1, 15, 11, 59, 0, 0, time.UTC)
+	noon := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		Vehicle            string
+		PickupPostcode     string
+		DeliveryPostcodes  []string
+		Parcels            []carrierpricing.Parcel
+		PickupTime         *time.Time
+		ExpectedServiceIDs []string
+	}{
+		// case #1 nothing but the vehicle is checked
+		{
+			Vehicle:            carrierpricing.VehicleTypeSmallVan,
+			ExpectedServiceIDs: []string{"east", "city", "light", "short", "morning"},
+		},
+		// case #2 vehicle not used by any service
+		{
+			Vehicle:            carrierpricing.VehicleTypeBicycle,
+			ExpectedServiceIDs: []string{},
+		},
+		// case #3 all the stops in a covered area
+		{
+			Vehicle:            carrierpricing.VehicleTypeSmallVan,
+			PickupPostcode:     "E1 6AN",
+			DeliveryPostcodes:  []string{"E2 8AA"},
+			ExpectedServiceIDs: []string{"east", "light", "short", "morning"},
+		},
+		// case #4 area "E" does not cover district "EC1A", whose area is "EC"
+		{
+			Vehicle:            carrierpricing.VehicleTypeSmallVan,
+			PickupPostcode:     "SW1A 1AA",
+			DeliveryPostcodes:  []string{"EC1A 1BB"},
+			ExpectedServiceIDs: []string{"city", "light", "short", "morning"},
+		},
+		// case #5 district "EC1A" does not cover district "EC2A"
+		{
+			Vehicle:            carrierpricing.VehicleTypeSmallVan,
+			PickupPostcode:     "SW1A 1AA",
+			DeliveryPostcodes:  []string{"EC2A 3LT"},
+			ExpectedServiceIDs: []string{"light", "short", "morning"},
+		},
+		// case #6 one of many stops not covered
+		{
+			Vehicle:            carrierpricing.VehicleTypeSmallVan,
+			PickupPostcode:     "E1 6AN",
+			DeliveryPostcodes:  []string{"E2 8AA", "EC1A 1BB"},
+			ExpectedServiceIDs: []string{"light", "short", "morning"},
+		},
+		// case #7 parcel as heavy as the limit
+		{
+			Vehicle:            carrierpricing.VehicleTypeSmallVan,
+			Parcels:            []carrierpricing.Parcel{{WeightKg: 5, LengthCm: 10, WidthCm: 10, HeightCm: 10}},
+			ExpectedServiceIDs: []string{"east", "city", "light", "short", "morning"},
+		},
+		// case #8 parcel heavier than the limit
+		{
+			Vehicle:            carrierpricing.VehicleTypeSmallVan,
+			Parcels:            []carrierpricing.Parcel{{WeightKg: 5.5, LengthCm: 10, WidthCm: 10, HeightCm: 10}},
+			ExpectedServiceIDs: []string{"east", "city", "short", "morning"},
+		},
+		// case #9 all the dimensions as long as the limit
+		{
+			Vehicle:            carrierpricing.VehicleTypeSmallVan,
+			Parcels:            []carrierpricing.Parcel{{WeightKg: 1, LengthCm: 50, WidthCm: 50, HeightCm: 50}},
+			ExpectedServiceIDs: []string{"east", "city", "light", "short", "morning"},
+		},
+		// case #10 the longest dimension, not the length, exceeding the limit
+		{
+			Vehicle:            carrierpricing.VehicleTypeSmallVan,
+			Parcels:            []carrierpricing.Parcel{{WeightKg: 1, LengthCm: 20, WidthCm: 60, HeightCm: 10}},
+			ExpectedServiceIDs: []string{"east", "city", "light", "morning"},
+		},
+		// case #11 one of many parcels exceeding the limits
+		{
+			Vehicle: carrierpricing.VehicleTypeSmallVan,
+			Parcels: []carrierpricing.Parcel{
+				{WeightKg: 1, LengthCm: 10, WidthCm: 10, HeightCm: 10},
+				{WeightKg: 6, LengthCm: 10, WidthCm: 10, HeightCm: 70},
+			},
+			ExpectedServiceIDs: []string{"east", "city", "morning"},
+		},
+		// case #12 pickup exactly at the opening time
+		{
+			Vehicle:            carrierpricing.VehicleTypeSmallVan,
+			PickupTime:         &morning,
+			ExpectedServiceIDs: []string{"east", "city", "light", "short", "morning"},
+		},
+		// case #13 pickup right before the closing time
+		{
+			Vehicle:            carrierpricing.VehicleTypeSmallVan,
+			PickupTime:         &beforeNoon,
+			ExpectedServiceIDs: []string{"east", "city", "light", "short", "morning"},
+		},
+		// case #14 pickup exactly at the closing time
+		{
+			Vehicle:            carrierpricing.VehicleTypeSmallVan,
+			PickupTime:         &noon,
+			ExpectedServiceIDs: []string{"east", "city", "light", "short"},
+		},
+	}
+
+	for i, tc := range tests {
+		query := carrierpricing.CarrierQuery{
+			Vehicle:    tc.Vehicle,
+			Parcels:    tc.Parcels,
+			PickupTime: tc.PickupTime,
+		}
+
+		if tc.PickupPostcode != "" {
+			query.PickupPostcode, err = postcode.Parse(tc.PickupPostcode)
+			if err != nil {
+				t.Fatalf("case #%d: %v", i+1, err)
+			}
+		}
+
+		for _, deliveryPostcode := range tc.DeliveryPostcodes {
+			delivery, err := postcode.Parse(deliveryPostcode)
+			if err != nil {
+				t.Fatalf("case #%d: %v", i+1, err)
+			}
+			query.DeliveryPostcodes = append(query.DeliveryPostcodes, *delivery)
+		}
+
+		carrierServices, err := csf.FindCarrierServices(context.Background(), query)
+		if err != nil {
+			t.Fatalf("case #%d: FindCarrierServices returned error %v", i+1, err)
+		}
+
+		serviceIDs := []string{}
+		for _, carrierService := range carrierServices {
+			serviceIDs = append(serviceIDs, carrierService.ServiceID)
+		}
+
+		if !reflect.DeepEqual(serviceIDs, tc.ExpectedServiceIDs) {
+			t.Fatalf("case #%d: expected services %v, received: %v", i+1, tc.ExpectedServiceIDs, serviceIDs)
+		}
+	}
+}
+
+func TestNewCSFSnapshot(t *testing.T) {
+	carriers := []carrier{
+		{
+			Name:      "RoyalPackages",
+			BasePrice: 30,
+			Services: []service{
+				{ID: "1", Markup: 50, Vehicles: []string{"small_van", "large_van"}},
+				{ID: "2", Markup: 5, Vehicles: []string{"large_van"}},
+			},
+		},
+		{
+			Name:      "CollectTimes",
+			BasePrice: 50,
+			Services: []service{
+				{ID: "1", Markup: 20, Vehicles: []string{"bicycle", "small_van"}},
+			},
+		},
+	}
+
+	snapshot := newCSFSnapshot(carriers)
+
+	servicesByVehicle := map[string][]string{}
+	for vehicle, indexedServices := range snapshot.servicesByVehicle {
+		for _, indexed := range indexedServices {
+			servicesByVehicle[vehicle] = append(
+				servicesByVehicle[vehicle],
+				indexed.carrierService.Name+"/"+indexed.carrierService.ServiceID,
+			)
+
+			if indexed.carrierService.ServiceID != indexed.service.ID {
+				t.Fatalf("expected the carrier service %+v to be indexed with its service, received: %+v", indexed.carrierService, indexed.service)
+			}
+		}
+	}
+
+	// services are indexed by each of their vehicles, in the order they are listed
+	expected := map[string][]string{
+		"small_van": {"RoyalPackages/1", "CollectTimes/1"},
+		"large_van": {"RoyalPackages/1", "RoyalPackages/2"},
+		"bicycle":   {"CollectTimes/1"},
+	}
+	if !reflect.DeepEqual(servicesByVehicle, expected) {
+		t.Fatalf("expected services by vehicle %v, received: %v", expected, servicesByVehicle)
+	}
+
+	royalPackagesLargeVan := snapshot.servicesByVehicle["large_van"][1].carrierService
+	if royalPackagesLargeVan.Markup != carrierpricing.NewMoney(35, carrierpricing.CurrencyGBP) ||
+		royalPackagesLargeVan.BasePrice != carrierpricing.NewMoney(30, carrierpricing.CurrencyGBP) ||
+		royalPackagesLargeVan.ServiceMarkup != carrierpricing.NewMoney(5, carrierpricing.CurrencyGBP) {
+		t.Fatalf("unexpected carrier service %+v", royalPackagesLargeVan)
+	}
+
+	if !reflect.DeepEqual(snapshot.carriers, carriers) {
+		t.Fatalf("expected carriers %+v, received: %+v", carriers, snapshot.carriers)
+	}
+}
+
+func TestCSFFromJSONFileReload(t *testing.T) {
+	directory, err := ioutil.TempDir("", "csffromjsonfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	jsonFilePath := filepath.Join(directory, "carriers.json")
+	err = ioutil.WriteFile(jsonFilePath, []byte(`[
+    {
+        "carrier_name": "RoyalPackages",
+        "base_price": 30,
+        "services": [
+            {"delivery_time": {"value": 1, "unit": "working_days"}, "markup": 50, "vehicles": ["small_van"]}
+        ]
+    }
+]`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csf, err := NewCSFFromJSONFile(jsonFilePath)
+	if err != nil {
+		t.Fatalf("NewCSFFromJSONFile returned error %v", err)
+	}
+	assertServiceNames(t, "file loaded", csf, carrierpricing.VehicleTypeSmallVan, []string{"RoyalPackages"})
+
+	// the snapshot is replaced once the file is reloaded
+	err = ioutil.WriteFile(jsonFilePath, []byte(`[
+    {
+        "carrier_name": "CollectTimes",
+        "base_price": 50,
+        "services": [
+            {"delivery_time": {"value": 2, "unit": "hours"}, "markup": 20, "vehicles": ["small_van", "bicycle"]}
+        ]
+    }
+]`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertServiceNames(t, "file changed", csf, carrierpricing.VehicleTypeSmallVan, []string{"RoyalPackages"})
+
+	err = csf.Reload()
+	if err != nil {
+		t.Fatalf("Reload returned error %v", err)
+	}
+	assertServiceNames(t, "file reloaded", csf, carrierpricing.VehicleTypeSmallVan, []string{"CollectTimes"})
+	assertServiceNames(t, "file reloaded", csf, carrierpricing.VehicleTypeBicycle, []string{"CollectTimes"})
+
+	// invalid files are not loaded, keeping the previous snapshot
+	err = ioutil.WriteFile(jsonFilePath, []byte(`[{"carrier_name": "Zippy", "services": []}]`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = csf.Reload()
+	if _, ok := err.(*ValidationError); !ok {
+		t.Fatalf("expected a *ValidationError reloading an invalid file, received: '%v'", err)
+	}
+	assertServiceNames(t, "invalid file", csf, carrierpricing.VehicleTypeSmallVan, []string{"CollectTimes"})
+
+	err = os.Remove(jsonFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = csf.Reload()
+	if !os.IsNotExist(err) {
+		t.Fatalf("expected a not exist error reloading a removed file, received: '%v'", err)
+	}
+	assertServiceNames(t, "file removed", csf, carrierpricing.VehicleTypeSmallVan, []string{"CollectTimes"})
+}
+
+func TestCSFFromJSONFileWatch(t *testing.T) {
+	directory, err := ioutil.TempDir("", "csffromjsonfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	jsonFilePath := filepath.Join(directory, "carriers.json")
+	err = ioutil.WriteFile(jsonFilePath, []byte(`[
+    {
+        "carrier_name": "RoyalPackages",
+        "base_price": 30,
+        "services": [
+            {"delivery_time": {"value": 1, "unit": "working_days"}, "markup": 50, "vehicles": ["small_van"]}
+        ]
+    }
+]`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csf, err := NewCSFFromJSONFile(jsonFilePath)
+	if err != nil {
+		t.Fatalf("NewCSFFromJSONFile returned error %v", err)
+	}
+
+	reloads := make(chan error)
+	ctx, cancel := context.WithCancel(context.Background())
+	watching := make(chan struct{})
+	go func() {
+		csf.Watch(ctx, 10*time.Millisecond, func(err error) { reloads <- err })
+		close(watching)
+	}()
+
+	// Watch compares the file with the version it finds when started
+	time.Sleep(100 * time.Millisecond)
+
+	// the file is reloaded once modified; contents differ in size, so that the
+	// change is noticed regardless of the resolution of modification times
+	err = ioutil.WriteFile(jsonFilePath, []byte(`[
+    {
+        "carrier_name": "CollectTimes",
+        "base_price": 50,
+        "services": [
+            {"delivery_time": {"value": 2, "unit": "hours"}, "markup": 20, "vehicles": ["small_van"]},
+            {"delivery_time": {"value": 1, "unit": "working_days"}, "markup": 5, "vehicles": ["small_van"]}
+        ]
+    }
+]`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err = <-reloads:
+		if err != nil {
+			t.Fatalf("expected the file to be reloaded, received error %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the file to be reloaded once modified")
+	}
+	assertServiceNames(t, "file modified", csf, carrierpricing.VehicleTypeSmallVan, []string{"CollectTimes", "CollectTimes"})
+
+	// invalid files are reported, keeping the previous snapshot
+	err = ioutil.WriteFile(jsonFilePath, []byte(`[{"carrier_name": "Zippy"`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err = <-reloads:
+		if _, ok := err.(*ValidationError); !ok {
+			t.Fatalf("expected a *ValidationError reloading an invalid file, received: '%v'", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the invalid file to be reported once modified")
+	}
+	assertServiceNames(t, "invalid file", csf, carrierpricing.VehicleTypeSmallVan, []string{"CollectTimes", "CollectTimes"})
+
+	cancel()
+	select {
+	case <-watching:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Watch to return once the context is done")
+	}
+}
+
+func assertServiceNames(t *testing.T, description string, csf carrierpricing.CarrierServiceFinderV2, vehicle string, expectedNames []string) {
+	carrierServices, err := csf.FindCarrierServices(context.Background(), carrierpricing.CarrierQuery{Vehicle: vehicle})
+	if err != nil {
+		t.Fatalf("%s: FindCarrierServices returned error %v", description, err)
+	}
+
+	names := []string{}
+	for _, carrierService := range carrierServices {
+		names = append(names, carrierService.Name)
+	}
+
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Fatalf("%s: expected carrier services %v, received: %v", description, expectedNames, names)
+	}
+}
@@ -0,0 +1,312 @@
+package carrierservicefinders
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/giefferre/carrierpricing"
+)
+
+// sqlMigrations are the statements creating the schema used by CSFFromSQL, by
+// version; a new version must be appended for each change to the schema, as
+// the applied ones are recorded in csf_schema_migrations and never run again.
+//
+//   - carriers: the carriers, with their base price and markups in minor units
+//     of their currency, their optional working hours and cut off time
+//   - services: the services of each carrier, with their delivery time, markup
+//     and optional limits and operating hours
+//   - service_vehicles: the vehicles each service can be carried out with
+//   - service_coverage_areas: the postcode areas or districts each service is
+//     restricted to; services without coverage areas deliver everywhere
+var sqlMigrations = [][]string{
+	// version 1
+	{
+		`CREATE TABLE carriers (
+			id INTEGER PRIMARY KEY,
+			name TEXT NOT NULL UNIQUE,
+			currency TEXT NOT NULL DEFAULT 'GBP',
+			base_price INTEGER NOT NULL DEFAULT 0 CHECK (base_price >= 0),
+			working_hours_from TEXT,
+			working_hours_to TEXT,
+			cut_off TEXT
+		)`,
+		`CREATE TABLE services (
+			id INTEGER PRIMARY KEY,
+			carrier_id INTEGER NOT NULL REFERENCES carriers (id) ON DELETE CASCADE,
+			delivery_time_value INTEGER NOT NULL CHECK (delivery_time_value >= 0),
+			delivery_time_unit TEXT NOT NULL CHECK (delivery_time_unit IN ('minutes', 'hours', 'working_days')),
+			markup INTEGER NOT NULL DEFAULT 0 CHECK (markup >= 0),
+			max_weight_kg REAL CHECK (max_weight_kg >= 0),
+			max_length_cm REAL CHECK (max_length_cm >= 0),
+			operating_hours_from TEXT,
+			operating_hours_to TEXT
+		)`,
+		`CREATE TABLE service_vehicles (
+			service_id INTEGER NOT NULL REFERENCES services (id) ON DELETE CASCADE,
+			vehicle TEXT NOT NULL,
+			PRIMARY KEY (service_id, vehicle)
+		)`,
+		`CREATE INDEX service_vehicles_vehicle ON service_vehicles (vehicle)`,
+		`CREATE TABLE service_coverage_areas (
+			service_id INTEGER NOT NULL REFERENCES services (id) ON DELETE CASCADE,
+			area TEXT NOT NULL,
+			PRIMARY KEY (service_id, area)
+		)`,
+	},
+}
+
+const (
+	sqlSelectServicesForVehicle = `SELECT
+			c.name, c.currency, c.base_price, c.working_hours_from, c.working_hours_to, c.cut_off,
+			s.id, s.delivery_time_value, s.delivery_time_unit, s.markup,
+			s.max_weight_kg, s.max_length_cm, s.operating_hours_from, s.operating_hours_to
+		FROM service_vehicles sv
+		JOIN services s ON s.id = sv.service_id
+		JOIN carriers c ON c.id = s.carrier_id
+		WHERE sv.vehicle = ?
+		ORDER BY c.id, s.id`
+
+	sqlSelectCoverageAreasForVehicle = `SELECT ca.service_id, ca.area
+		FROM service_coverage_areas ca
+		JOIN service_vehicles sv ON sv.service_id = ca.service_id
+		WHERE sv.vehicle = ?
+		ORDER BY ca.service_id, ca.area`
+)
+
+// MigrateCarriersSchema creates or updates the schema used by CSFFromSQL in the
+// given database, applying the migrations not applied yet, each one in its own
+// transaction. The statements are written for SQLite; the database driver must
+// support "?" placeholders.
+func MigrateCarriersSchema(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS csf_schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at TEXT NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+
+	var currentVersion int
+	err = db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM csf_schema_migrations`).Scan(&currentVersion)
+	if err != nil {
+		return err
+	}
+
+	for i := currentVersion; i < len(sqlMigrations); i++ {
+		err = applyMigration(db, i+1, sqlMigrations[i])
+		if err != nil {
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+func applyMigration(db *sql.DB, version int, statements []string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, statement := range statements {
+		_, err = tx.Exec(statement)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO csf_schema_migrations (version, applied_at) VALUES (?, ?)`,
+		version, time.Now().UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CSFFromSQL implements both the carrierpricing.CarrierServiceFinder and the
+// carrierpricing.CarrierServiceFinderV2 interfaces; the source of data is a
+// database, whose schema is created by MigrateCarriersSchema. Carrier services
+// are found via prepared queries, by vehicle, and filtered as CSFFromJSONFile
+// does; they are identified by the id of their row in the services table.
+type CSFFromSQL struct {
+	selectServices      *sql.Stmt
+	selectCoverageAreas *sql.Stmt
+}
+
+// NewCSFFromSQL returns a new CSFFromSQL object querying the given database,
+// whose schema must be up to date. An error is returned if the queries cannot
+// be prepared.
+func NewCSFFromSQL(db *sql.DB) (*CSFFromSQL, error) {
+	selectServices, err := db.Prepare(sqlSelectServicesForVehicle)
+	if err != nil {
+		return nil, err
+	}
+
+	selectCoverageAreas, err := db.Prepare(sqlSelectCoverageAreasForVehicle)
+	if err != nil {
+		selectServices.Close()
+		return nil, err
+	}
+
+	return &CSFFromSQL{
+		selectServices:      selectServices,
+		selectCoverageAreas: selectCoverageAreas,
+	}, nil
+}
+
+// Close releases the prepared queries; the database is not closed.
+func (csf *CSFFromSQL) Close() error {
+	err := csf.selectServices.Close()
+	if coverageErr := csf.selectCoverageAreas.Close(); err == nil {
+		err = coverageErr
+	}
+	return err
+}
+
+// FindCarrierServicesForVehicle finds CarrierService objects for the given
+// vehicleType; as failures cannot be reported, no carrier services are
+// returned when the database cannot be queried.
+func (csf *CSFFromSQL) FindCarrierServicesForVehicle(vehicleType string) []carrierpricing.CarrierService {
+	carrierServices, err := csf.FindCarrierServices(context.Background(), carrierpricing.CarrierQuery{Vehicle: vehicleType})
+	if err != nil {
+		return []carrierpricing.CarrierService{}
+	}
+	return carrierServices
+}
+
+// FindCarrierServices finds CarrierService objects for the vehicle of the given
+// query, whose services cover its postcodes and can carry its parcels at its
+// pickup time. An error is returned if the database cannot be queried or it
+// contains invalid values.
+func (csf *CSFFromSQL) FindCarrierServices(ctx context.Context, query carrierpricing.CarrierQuery) ([]carrierpricing.CarrierService, error) {
+	coverageAreas, err := csf.findCoverageAreas(ctx, query.Vehicle)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := csf.selectServices.QueryContext(ctx, query.Vehicle)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	carrierServices := []carrierpricing.CarrierService{}
+	for rows.Next() {
+		var (
+			carrier                                  carrier
+			service                                  service
+			serviceID                                int64
+			workingHoursFrom, workingHoursTo, cutOff sql.NullString
+			maxWeightKg, maxLengthCm                 sql.NullFloat64
+			operatingHoursFrom, operatingHoursTo     sql.NullString
+		)
+
+		err = rows.Scan(
+			&carrier.Name, &carrier.Currency, &carrier.BasePrice, &workingHoursFrom, &workingHoursTo, &cutOff,
+			&serviceID, &service.DeliveryTime.Value, &service.DeliveryTime.Unit, &service.Markup,
+			&maxWeightKg, &maxLengthCm, &operatingHoursFrom, &operatingHoursTo,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		carrier.WorkingHours = nullableHours(workingHoursFrom, workingHoursTo)
+		carrier.CutOff = cutOff.String
+		service.MaxWeightKg = maxWeightKg.Float64
+		service.MaxLengthCm = maxLengthCm.Float64
+		service.OperatingHours = nullableHours(operatingHoursFrom, operatingHoursTo)
+		service.ID = strconv.FormatInt(serviceID, 10)
+		service.CoverageAreas = coverageAreas[serviceID]
+
+		err = validateSQLService(carrier, service)
+		if err != nil {
+			return nil, fmt.Errorf("carrier %s, service %d: %w", carrier.Name, serviceID, err)
+		}
+
+		if service.matches(query) {
+			carrierServices = append(carrierServices, carrier.carrierService(service))
+		}
+	}
+
+	return carrierServices, rows.Err()
+}
+
+// findCoverageAreas returns the coverage areas of the services of the given
+// vehicle, by service ID.
+func (csf *CSFFromSQL) findCoverageAreas(ctx context.Context, vehicleType string) (map[int64][]string, error) {
+	rows, err := csf.selectCoverageAreas.QueryContext(ctx, vehicleType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	coverageAreas := map[int64][]string{}
+	for rows.Next() {
+		var (
+			serviceID int64
+			area      string
+		)
+
+		err = rows.Scan(&serviceID, &area)
+		if err != nil {
+			return nil, err
+		}
+
+		coverageAreas[serviceID] = append(coverageAreas[serviceID], area)
+	}
+
+	return coverageAreas, rows.Err()
+}
+
+// nullableHours returns the WorkingHours of the given columns, nil when not set.
+func nullableHours(from, to sql.NullString) *carrierpricing.WorkingHours {
+	if !from.Valid && !to.Valid {
+		return nil
+	}
+	return &carrierpricing.WorkingHours{From: from.String, To: to.String}
+}
+
+// validateSQLService returns an error if the given carrier or service, read from
+// the database, contain values the schema cannot constrain.
+func validateSQLService(carrier carrier, service service) error {
+	if !carrierpricing.IsValidCurrency(carrier.currency()) {
+		return fmt.Errorf("invalid currency provided %q", carrier.Currency)
+	}
+
+	if carrier.WorkingHours != nil {
+		if err := carrier.WorkingHours.Validate(); err != nil {
+			return fmt.Errorf("working hours: %w", err)
+		}
+	}
+
+	if carrier.CutOff != "" {
+		if _, err := time.Parse("15:04", carrier.CutOff); err != nil {
+			return fmt.Errorf("invalid cut off time %q, expected HH:MM", carrier.CutOff)
+		}
+	}
+
+	if err := service.DeliveryTime.Validate(); err != nil {
+		return err
+	}
+
+	for _, area := range service.CoverageAreas {
+		if !coverageAreaRegexp.MatchString(area) {
+			return fmt.Errorf("invalid coverage area provided %q, expected a postcode area or district", area)
+		}
+	}
+
+	if service.OperatingHours != nil {
+		if err := service.OperatingHours.Validate(); err != nil {
+			return fmt.Errorf("operating hours: %w", err)
+		}
+	}
+
+	return nil
+}
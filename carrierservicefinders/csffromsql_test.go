@@ -0,0 +1,214 @@
+//go:build cgo
+// +build cgo
+
+package carrierservicefinders
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/giefferre/carrierpricing"
+	"github.com/giefferre/carrierpricing/postcode"
+)
+
+const sqlFixtures = `
+INSERT INTO carriers (id, name, currency, base_price, working_hours_from, working_hours_to, cut_off) VALUES
+	(1, 'RoyalPackages', 'GBP', 30, '07:00', '19:00', '17:30'),
+	(2, 'CollectTimes', 'GBP', 50, NULL, NULL, NULL);
+
+INSERT INTO services (id, carrier_id, delivery_time_value, delivery_time_unit, markup, max_weight_kg, max_length_cm, operating_hours_from, operating_hours_to) VALUES
+	(1, 1, 1, 'working_days', 50, 30, 150, NULL, NULL),
+	(2, 1, 3, 'working_days', 5, NULL, NULL, NULL, NULL),
+	(3, 2, 2, 'hours', 20, NULL, NULL, '09:00', '17:00');
+
+INSERT INTO service_vehicles (service_id, vehicle) VALUES
+	(1, 'small_van'), (1, 'bicycle'), (2, 'bicycle'), (3, 'bicycle');
+
+INSERT INTO service_coverage_areas (service_id, area) VALUES
+	(3, 'SW'), (3, 'EC');
+`
+
+func TestCSFFromSQL(t *testing.T) {
+	db := newTestDatabase(t)
+	defer db.Close()
+
+	csf, err := NewCSFFromSQL(db)
+	if err != nil {
+		t.Fatalf("NewCSFFromSQL returned error %v", err)
+	}
+	defer csf.Close()
+
+	royalPackagesNextDay := carrierpricing.CarrierService{
+		Name:          "RoyalPackages",
+		ServiceID:     "1",
+		Markup:        carrierpricing.NewMoney(80, carrierpricing.CurrencyGBP),
+		BasePrice:     carrierpricing.NewMoney(30, carrierpricing.CurrencyGBP),
+		ServiceMarkup: carrierpricing.NewMoney(50, carrierpricing.CurrencyGBP),
+		DeliveryTime:  carrierpricing.NewDeliveryDuration(1, carrierpricing.DeliveryTimeUnitWorkingDays),
+		WorkingHours:  &carrierpricing.WorkingHours{From: "07:00", To: "19:00"},
+		CutOff:        "17:30",
+	}
+	royalPackagesStandard := royalPackagesNextDay
+	royalPackagesStandard.ServiceID = "2"
+	royalPackagesStandard.Markup = carrierpricing.NewMoney(35, carrierpricing.CurrencyGBP)
+	royalPackagesStandard.ServiceMarkup = carrierpricing.NewMoney(5, carrierpricing.CurrencyGBP)
+	royalPackagesStandard.DeliveryTime = carrierpricing.NewDeliveryDuration(3, carrierpricing.DeliveryTimeUnitWorkingDays)
+	collectTimesCourier := carrierpricing.CarrierService{
+		Name:          "CollectTimes",
+		ServiceID:     "3",
+		Markup:        carrierpricing.NewMoney(70, carrierpricing.CurrencyGBP),
+		BasePrice:     carrierpricing.NewMoney(50, carrierpricing.CurrencyGBP),
+		ServiceMarkup: carrierpricing.NewMoney(20, carrierpricing.CurrencyGBP),
+		DeliveryTime:  carrierpricing.NewDeliveryDuration(2, carrierpricing.DeliveryTimeUnitHours),
+	}
+
+	london, _ := postcode.Parse("SW1A1AA")
+	manchester, _ := postcode.Parse("M11AA")
+	morning := time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC)
+	evening := time.Date(2026, 10, 15, 18, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		Query    carrierpricing.CarrierQuery
+		Expected []carrierpricing.CarrierService
+	}{
+		// case #1 vehicle only
+		{
+			Query:    carrierpricing.CarrierQuery{Vehicle: carrierpricing.VehicleTypeBicycle},
+			Expected: []carrierpricing.CarrierService{royalPackagesNextDay, royalPackagesStandard, collectTimesCourier},
+		},
+		// case #2 single service
+		{
+			Query:    carrierpricing.CarrierQuery{Vehicle: carrierpricing.VehicleTypeSmallVan},
+			Expected: []carrierpricing.CarrierService{royalPackagesNextDay},
+		},
+		// case #3 no services
+		{
+			Query:    carrierpricing.CarrierQuery{Vehicle: carrierpricing.VehicleTypeLargeVan},
+			Expected: []carrierpricing.CarrierService{},
+		},
+		// case #4 delivery outside the coverage areas
+		{
+			Query: carrierpricing.CarrierQuery{
+				Vehicle:           carrierpricing.VehicleTypeBicycle,
+				PickupPostcode:    london,
+				DeliveryPostcodes: []postcode.Postcode{*manchester},
+			},
+			Expected: []carrierpricing.CarrierService{royalPackagesNextDay, royalPackagesStandard},
+		},
+		// case #5 parcel too heavy, collected outside the operating hours
+		{
+			Query: carrierpricing.CarrierQuery{
+				Vehicle:    carrierpricing.VehicleTypeBicycle,
+				Parcels:    []carrierpricing.Parcel{{WeightKg: 40}},
+				PickupTime: &evening,
+			},
+			Expected: []carrierpricing.CarrierService{royalPackagesStandard},
+		},
+		// case #6 everything matching
+		{
+			Query: carrierpricing.CarrierQuery{
+				Vehicle:           carrierpricing.VehicleTypeBicycle,
+				PickupPostcode:    london,
+				DeliveryPostcodes: []postcode.Postcode{*london},
+				Parcels:           []carrierpricing.Parcel{{WeightKg: 2}},
+				PickupTime:        &morning,
+			},
+			Expected: []carrierpricing.CarrierService{royalPackagesNextDay, royalPackagesStandard, collectTimesCourier},
+		},
+	}
+
+	for i, tc := range tests {
+		result, err := csf.FindCarrierServices(context.Background(), tc.Query)
+		if err != nil {
+			t.Fatalf("case #%d: FindCarrierServices returned error %v", i+1, err)
+		}
+		if !reflect.DeepEqual(result, tc.Expected) {
+			t.Fatalf("case #%d: expected carrier services\n%+v\nreceived:\n%+v", i+1, tc.Expected, result)
+		}
+	}
+
+	if result := csf.FindCarrierServicesForVehicle(carrierpricing.VehicleTypeSmallVan); !reflect.DeepEqual(result, tests[1].Expected) {
+		t.Fatalf("FindCarrierServicesForVehicle expected '%+v', received: '%+v'", tests[1].Expected, result)
+	}
+
+	// failures are reported
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = csf.FindCarrierServices(ctx, carrierpricing.CarrierQuery{Vehicle: carrierpricing.VehicleTypeBicycle}); err == nil {
+		t.Fatal("expected an error for a cancelled context")
+	}
+
+	_, err = db.Exec(`UPDATE carriers SET currency = 'XYZ' WHERE id = 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = csf.FindCarrierServices(context.Background(), carrierpricing.CarrierQuery{Vehicle: carrierpricing.VehicleTypeSmallVan}); err == nil {
+		t.Fatal("expected an error for an invalid currency")
+	}
+
+	db.Close()
+	if _, err = csf.FindCarrierServices(context.Background(), carrierpricing.CarrierQuery{Vehicle: carrierpricing.VehicleTypeSmallVan}); err == nil {
+		t.Fatal("expected an error for a closed database")
+	}
+	if result := csf.FindCarrierServicesForVehicle(carrierpricing.VehicleTypeSmallVan); len(result) != 0 {
+		t.Fatalf("expected no carrier services for a closed database, received: '%+v'", result)
+	}
+}
+
+func TestMigrateCarriersSchema(t *testing.T) {
+	db := newTestDatabase(t)
+	defer db.Close()
+
+	// migrations already applied are not applied again
+	err := MigrateCarriersSchema(db)
+	if err != nil {
+		t.Fatalf("MigrateCarriersSchema returned error %v", err)
+	}
+
+	var version int
+	err = db.QueryRow(`SELECT MAX(version) FROM csf_schema_migrations`).Scan(&version)
+	if err != nil || version != len(sqlMigrations) {
+		t.Fatalf("expected schema version %d, received: %d (%v)", len(sqlMigrations), version, err)
+	}
+
+	// the schema rejects invalid values
+	_, err = db.Exec(`INSERT INTO services (carrier_id, delivery_time_value, delivery_time_unit, markup) VALUES (1, 1, 'days', 0)`)
+	if err == nil {
+		t.Fatal("expected an invalid delivery time unit to be rejected")
+	}
+}
+
+// newTestDatabase returns a new SQLite database, in a temporary file removed
+// when the test ends, with the schema and the fixtures loaded.
+func newTestDatabase(t *testing.T) *sql.DB {
+	directory, err := ioutil.TempDir("", "csffromsql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(directory) })
+
+	db, err := sql.Open("sqlite3", filepath.Join(directory, "carriers.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = MigrateCarriersSchema(db)
+	if err != nil {
+		t.Fatalf("MigrateCarriersSchema returned error %v", err)
+	}
+
+	_, err = db.Exec(sqlFixtures)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
@@ -20,18 +20,21 @@ func (csf *CSFFromStaticData) FindCarrierServicesForVehicle(vehicleType string)
 		availableCarrierServices = []carrierpricing.CarrierService{
 			carrierpricing.CarrierService{
 				Name:         "RoyalPackages",
-				Markup:       80,
-				DeliveryTime: 1,
+				ServiceID:    "1",
+				Markup:       carrierpricing.NewMoney(80, carrierpricing.CurrencyGBP),
+				DeliveryTime: carrierpricing.NewDeliveryDuration(1, carrierpricing.DeliveryTimeUnitWorkingDays),
 			},
 			carrierpricing.CarrierService{
 				Name:         "Hercules",
-				Markup:       35,
-				DeliveryTime: 5,
+				ServiceID:    "1",
+				Markup:       carrierpricing.NewMoney(35, carrierpricing.CurrencyGBP),
+				DeliveryTime: carrierpricing.NewDeliveryDuration(5, carrierpricing.DeliveryTimeUnitWorkingDays),
 			},
 			carrierpricing.CarrierService{
 				Name:         "CollectTimes",
-				Markup:       70,
-				DeliveryTime: 1,
+				ServiceID:    "1",
+				Markup:       carrierpricing.NewMoney(70, carrierpricing.CurrencyGBP),
+				DeliveryTime: carrierpricing.NewDeliveryDuration(1, carrierpricing.DeliveryTimeUnitWorkingDays),
 			},
 		}
 		break
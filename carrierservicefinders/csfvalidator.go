@@ -0,0 +1,360 @@
+package carrierservicefinders
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/giefferre/carrierpricing"
+	"github.com/giefferre/carrierpricing/carrierdispatchers"
+)
+
+// maxAmount is the highest base price or markup, in minor units, considered
+// sane; higher amounts are reported as they most likely are typos.
+const maxAmount = 1000000
+
+var (
+	carrierFields      = []string{"carrier_name", "currency", "base_price", "working_hours", "cut_off", "services", "dispatcher"}
+	serviceFields      = []string{"id", "delivery_time", "markup", "vehicles", "coverage_areas", "max_weight_kg", "max_length_cm", "operating_hours"}
+	deliveryTimeFields = []string{"value", "unit"}
+	hoursFields        = []string{"from", "to"}
+)
+
+// ValidationProblem is a problem found in a carriers file: the JSON path of
+// the invalid value (e.g. "$[0].services[1].vehicles[0]"), the line it is at
+// and what is wrong with it.
+type ValidationProblem struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (vp ValidationProblem) String() string {
+	return fmt.Sprintf("line %d: %s: %s", vp.Line, vp.Path, vp.Message)
+}
+
+// ValidationError is returned when a carriers file is not valid, listing all
+// the problems found in it.
+type ValidationError struct {
+	Problems []ValidationProblem
+}
+
+func (e *ValidationError) Error() string {
+	problems := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		problems[i] = problem.String()
+	}
+	return fmt.Sprintf("%d problem(s) found in carriers: %s", len(e.Problems), strings.Join(problems, "; "))
+}
+
+// ValidateCarriersJSON checks the given content of a carriers file, in the format
+// used by CSFFromJSONFile, returning all the problems found: unknown fields,
+// vehicles not in carrierpricing.ValidVehicleTypes, duplicate carrier names and
+// service ids, services without vehicles, negative or unreasonably high amounts,
+// invalid currencies, delivery times, hours, coverage areas and dispatchers.
+// Problems are sorted by line; nil is returned when the content is valid.
+func ValidateCarriersJSON(content []byte) []ValidationProblem {
+	index := indexJSON(content)
+
+	var document interface{}
+	err := json.Unmarshal(content, &document)
+	if err != nil {
+		var syntaxError *json.SyntaxError
+		if errors.As(err, &syntaxError) {
+			return []ValidationProblem{index.problemAtOffset(syntaxError.Offset, err.Error())}
+		}
+		return []ValidationProblem{index.problem("$", err.Error())}
+	}
+
+	carriers := []carrier{}
+	err = json.Unmarshal(content, &carriers)
+	if err != nil {
+		var typeError *json.UnmarshalTypeError
+		if errors.As(err, &typeError) {
+			return []ValidationProblem{
+				index.problemAtOffset(typeError.Offset, fmt.Sprintf("expected %s, found %s", jsonTypeName(typeError.Type), typeError.Value)),
+			}
+		}
+		return []ValidationProblem{index.problem("$", err.Error())}
+	}
+
+	v := &validator{index: index}
+	carrierPaths := map[string]string{}
+
+	for i, carrier := range carriers {
+		path := fmt.Sprintf("$[%d]", i)
+		v.checkFields(path, carrierFields)
+		v.checkFields(path+".working_hours", hoursFields)
+
+		name := strings.ToLower(strings.TrimSpace(carrier.Name))
+		switch {
+		case name == "":
+			v.report(path+".carrier_name", "carrier_name must be provided")
+		case carrierPaths[name] != "":
+			v.report(path+".carrier_name", fmt.Sprintf("duplicate carrier_name %q, already used by %s", carrier.Name, carrierPaths[name]))
+		default:
+			carrierPaths[name] = path
+		}
+
+		if !carrierpricing.IsValidCurrency(carrier.currency()) {
+			v.report(path+".currency", fmt.Sprintf("invalid currency provided %q", carrier.Currency))
+		}
+
+		v.checkAmount(path+".base_price", carrier.BasePrice)
+
+		if carrier.WorkingHours != nil {
+			if err := carrier.WorkingHours.Validate(); err != nil {
+				v.report(path+".working_hours", err.Error())
+			}
+		}
+
+		if carrier.CutOff != "" {
+			if _, err := time.Parse("15:04", carrier.CutOff); err != nil {
+				v.report(path+".cut_off", fmt.Sprintf("invalid cut off time %q, expected HH:MM", carrier.CutOff))
+			}
+		}
+
+		if len(carrier.Dispatcher) > 0 {
+			if err := carrierdispatchers.ValidateDispatcherConfig(carrier.Dispatcher); err != nil {
+				v.report(path+".dispatcher", err.Error())
+			}
+		}
+
+		if len(carrier.Services) == 0 {
+			v.report(path+".services", "at least one service must be provided")
+		}
+
+		servicePaths := map[string]string{}
+		for j, service := range carrier.Services {
+			servicePath := fmt.Sprintf("%s.services[%d]", path, j)
+			v.checkService(servicePath, service)
+
+			switch {
+			case service.ID == "":
+			case servicePaths[service.ID] != "":
+				v.report(servicePath+".id", fmt.Sprintf("duplicate service id %q, already used by %s", service.ID, servicePaths[service.ID]))
+			default:
+				servicePaths[service.ID] = servicePath
+			}
+		}
+	}
+
+	sort.SliceStable(v.problems, func(i, j int) bool { return v.problems[i].Line < v.problems[j].Line })
+
+	return v.problems
+}
+
+// validator collects the problems found in the carriers of a file.
+type validator struct {
+	index    *jsonIndex
+	problems []ValidationProblem
+}
+
+func (v *validator) report(path, message string) {
+	v.problems = append(v.problems, v.index.problem(path, message))
+}
+
+// checkFields reports the fields of the object at the given path not listed in
+// the given known fields, e.g. misspelled ones.
+func (v *validator) checkFields(path string, knownFields []string) {
+	for _, field := range v.index.fields[path] {
+		if !contains(knownFields, field) {
+			v.report(path+"."+field, fmt.Sprintf("unknown field %q", field))
+		}
+	}
+}
+
+func (v *validator) checkAmount(path string, amount int64) {
+	if amount < 0 || amount > maxAmount {
+		v.report(path, fmt.Sprintf("amount %d out of range, expected between 0 and %d minor units", amount, maxAmount))
+	}
+}
+
+func (v *validator) checkService(path string, service service) {
+	v.checkFields(path, serviceFields)
+	v.checkFields(path+".delivery_time", deliveryTimeFields)
+	v.checkFields(path+".operating_hours", hoursFields)
+
+	if err := service.DeliveryTime.Validate(); err != nil {
+		v.report(path+".delivery_time", err.Error())
+	}
+
+	v.checkAmount(path+".markup", service.Markup)
+
+	if len(service.Vehicles) == 0 {
+		v.report(path+".vehicles", "at least one vehicle must be provided")
+	}
+
+	for k, vehicle := range service.Vehicles {
+		vehiclePath := fmt.Sprintf("%s.vehicles[%d]", path, k)
+		switch {
+		case !contains(carrierpricing.ValidVehicleTypes, vehicle):
+			v.report(vehiclePath, fmt.Sprintf(
+				"invalid vehicle %q, expected one of %s", vehicle, strings.Join(carrierpricing.ValidVehicleTypes, ", "),
+			))
+		case contains(service.Vehicles[:k], vehicle):
+			v.report(vehiclePath, fmt.Sprintf("duplicate vehicle %q", vehicle))
+		}
+	}
+
+	for k, area := range service.CoverageAreas {
+		if !coverageAreaRegexp.MatchString(area) {
+			v.report(
+				fmt.Sprintf("%s.coverage_areas[%d]", path, k),
+				fmt.Sprintf("invalid coverage area provided %q, expected a postcode area or district", area),
+			)
+		}
+	}
+
+	if service.MaxWeightKg < 0 {
+		v.report(path+".max_weight_kg", "limit must be a non negative number")
+	}
+
+	if service.MaxLengthCm < 0 {
+		v.report(path+".max_length_cm", "limit must be a non negative number")
+	}
+
+	if service.OperatingHours != nil {
+		if err := service.OperatingHours.Validate(); err != nil {
+			v.report(path+".operating_hours", err.Error())
+		}
+	}
+}
+
+// jsonIndex maps the JSON paths of a document to the offsets their values
+// start at, and the paths of its objects to their fields.
+type jsonIndex struct {
+	content []byte
+	offsets map[string]int64
+	fields  map[string][]string
+}
+
+// indexJSON returns the jsonIndex of the given content; the index is partial
+// when the content is not valid JSON.
+func indexJSON(content []byte) *jsonIndex {
+	index := &jsonIndex{
+		content: content,
+		offsets: map[string]int64{},
+		fields:  map[string][]string{},
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+
+	var walk func(path string) error
+	walk = func(path string) error {
+		index.offsets[path] = index.valueStart(decoder.InputOffset())
+
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		delimiter, isDelimiter := token.(json.Delim)
+		if !isDelimiter {
+			return nil
+		}
+
+		for i := 0; decoder.More(); i++ {
+			if delimiter == '[' {
+				err = walk(fmt.Sprintf("%s[%d]", path, i))
+			} else {
+				var key json.Token
+				key, err = decoder.Token()
+				if err != nil {
+					return err
+				}
+				index.fields[path] = append(index.fields[path], fmt.Sprint(key))
+				err = walk(fmt.Sprintf("%s.%s", path, key))
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		// closing delimiter
+		_, err = decoder.Token()
+		return err
+	}
+
+	walk("$")
+	return index
+}
+
+// valueStart returns the offset of the first character of the value following
+// the given offset, skipping white spaces and separators.
+func (ji *jsonIndex) valueStart(offset int64) int64 {
+	for offset < int64(len(ji.content)) && strings.ContainsRune(" \t\r\n:,", rune(ji.content[offset])) {
+		offset++
+	}
+	return offset
+}
+
+// line returns the line number of the given offset.
+func (ji *jsonIndex) line(offset int64) int {
+	if offset > int64(len(ji.content)) {
+		offset = int64(len(ji.content))
+	}
+	return bytes.Count(ji.content[:offset], []byte("\n")) + 1
+}
+
+// problem returns a ValidationProblem for the given path; missing values are
+// reported at the line of the closest parent.
+func (ji *jsonIndex) problem(path, message string) ValidationProblem {
+	lookup := path
+	for {
+		if offset, found := ji.offsets[lookup]; found {
+			return ValidationProblem{Path: path, Line: ji.line(offset), Message: message}
+		}
+
+		cut := strings.LastIndexAny(lookup, ".[")
+		if cut <= 0 {
+			return ValidationProblem{Path: path, Line: 1, Message: message}
+		}
+		lookup = lookup[:cut]
+	}
+}
+
+// problemAtOffset returns a ValidationProblem for the value containing the
+// given offset.
+func (ji *jsonIndex) problemAtOffset(offset int64, message string) ValidationProblem {
+	path := "$"
+	var pathOffset int64 = -1
+	for candidate, candidateOffset := range ji.offsets {
+		if candidateOffset < offset && (candidateOffset > pathOffset || (candidateOffset == pathOffset && len(candidate) > len(path))) {
+			path, pathOffset = candidate, candidateOffset
+		}
+	}
+	return ValidationProblem{Path: path, Line: ji.line(offset), Message: message}
+}
+
+// jsonTypeName returns the name of the JSON type values of the given type are
+// decoded from.
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Ptr:
+		return jsonTypeName(t.Elem())
+	}
+	return "number"
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
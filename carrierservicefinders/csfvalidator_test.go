@@ -0,0 +1,138 @@
+package carrierservicefinders
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateCarriersJSON(t *testing.T) {
+	tests := []struct {
+		Content          string
+		ExpectedProblems []ValidationProblem
+	}{
+		// case #1 valid carriers
+		{
+			Content: `[
+    {
+        "carrier_name": "RoyalPackages",
+        "base_price": 30,
+        "working_hours": {"from": "07:00", "to": "19:00"},
+        "services": [
+            {
+                "delivery_time": {"value": 1, "unit": "working_days"},
+                "markup": 50,
+                "vehicles": ["small_van", "large_van"],
+                "coverage_areas": ["SW", "EC2A"]
+            }
+        ]
+    }
+]`,
+		},
+		// case #2 all the problems are reported, sorted by line
+		{
+			Content: `[
+    {
+        "carrier_name": "RoyalPackages",
+        "base_price": -5,
+        "services": [
+            {
+                "delivery_time": {"value": 1, "unit": "working_day"},
+                "markpu": 10,
+                "vehicles": ["smal_van", "bicycle", "bicycle"]
+            },
+            {
+                "delivery_time": {"value": 1, "unit": "hours"},
+                "markup": 2000000,
+                "vehicles": []
+            }
+        ]
+    },
+    {
+        "carrier_name": "royalpackages",
+        "currency": "XYZ",
+        "cut_off": "25:00",
+        "services": []
+    },
+    {
+        "services": [{"delivery_time": {"value": 1, "unit": "hours"}, "vehicles": ["bicycle"], "max_weight_kg": -1}]
+    }
+]`,
+			ExpectedProblems: []ValidationProblem{
+				{Path: "$[0].base_price", Line: 4, Message: "amount -5 out of range, expected between 0 and 1000000 minor units"},
+				{Path: "$[0].services[0].delivery_time", Line: 7, Message: `invalid delivery time unit "working_day"`},
+				{Path: "$[0].services[0].markpu", Line: 8, Message: `unknown field "markpu"`},
+				{Path: "$[0].services[0].vehicles[0]", Line: 9, Message: `invalid vehicle "smal_van", expected one of bicycle, motorbike, parcel_car, small_van, large_van`},
+				{Path: "$[0].services[0].vehicles[2]", Line: 9, Message: `duplicate vehicle "bicycle"`},
+				{Path: "$[0].services[1].markup", Line: 13, Message: "amount 2000000 out of range, expected between 0 and 1000000 minor units"},
+				{Path: "$[0].services[1].vehicles", Line: 14, Message: "at least one vehicle must be provided"},
+				{Path: "$[1].carrier_name", Line: 19, Message: `duplicate carrier_name "royalpackages", already used by $[0]`},
+				{Path: "$[1].currency", Line: 20, Message: `invalid currency provided "XYZ"`},
+				{Path: "$[1].cut_off", Line: 21, Message: `invalid cut off time "25:00", expected HH:MM`},
+				{Path: "$[1].services", Line: 22, Message: "at least one service must be provided"},
+				{Path: "$[2].carrier_name", Line: 24, Message: "carrier_name must be provided"},
+				{Path: "$[2].services[0].max_weight_kg", Line: 25, Message: "limit must be a non negative number"},
+			},
+		},
+		// case #3 syntax error
+		{
+			Content: "[\n    {\"carrier_name\": \"RoyalPackages\",\n    \"base_price\" 30}\n]",
+			ExpectedProblems: []ValidationProblem{
+				{Path: "$[0].base_price", Line: 3, Message: "invalid character '3' after object key"},
+			},
+		},
+		// case #4 value of the wrong type
+		{
+			Content: "[\n    {\"carrier_name\": \"RoyalPackages\",\n    \"base_price\": \"30\"}\n]",
+			ExpectedProblems: []ValidationProblem{
+				{Path: "$[0].base_price", Line: 3, Message: "expected number, found string"},
+			},
+		},
+		// case #5 not a list of carriers
+		{
+			Content: `{"carrier_name": "RoyalPackages"}`,
+			ExpectedProblems: []ValidationProblem{
+				{Path: "$", Line: 1, Message: "expected array, found object"},
+			},
+		},
+		// case #6 duplicate service ids, within the same carrier only
+		{
+			Content: `[
+    {
+        "carrier_name": "RoyalPackages",
+        "services": [
+            {"id": "next-day", "delivery_time": {"value": 1, "unit": "working_days"}, "vehicles": ["bicycle"]},
+            {"id": "standard", "delivery_time": {"value": 3, "unit": "working_days"}, "vehicles": ["bicycle"]},
+            {"id": "next-day", "delivery_time": {"value": 2, "unit": "hours"}, "vehicles": ["bicycle"]}
+        ]
+    },
+    {
+        "carrier_name": "CollectTimes",
+        "services": [{"id": "next-day", "delivery_time": {"value": 1, "unit": "working_days"}, "vehicles": ["bicycle"]}]
+    }
+]`,
+			ExpectedProblems: []ValidationProblem{
+				{Path: "$[0].services[2].id", Line: 7, Message: `duplicate service id "next-day", already used by $[0].services[0]`},
+			},
+		},
+		// case #7 invalid dispatcher
+		{
+			Content: `[
+    {
+        "carrier_name": "RoyalPackages",
+        "services": [{"delivery_time": {"value": 1, "unit": "working_days"}, "vehicles": ["bicycle"]}],
+        "dispatcher": {"type": "ftp", "url": "https://royalpackages.example"}
+    }
+]`,
+			ExpectedProblems: []ValidationProblem{
+				{Path: "$[0].dispatcher", Line: 5, Message: `unsupported type "ftp"`},
+			},
+		},
+	}
+
+	for i, tc := range tests {
+		problems := ValidateCarriersJSON([]byte(tc.Content))
+		if !reflect.DeepEqual(problems, tc.ExpectedProblems) {
+			t.Fatalf("case #%d: expected problems\n%v\nreceived:\n%v", i+1, tc.ExpectedProblems, problems)
+		}
+	}
+}
@@ -1,43 +1,338 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/giefferre/carrierpricing"
+	"github.com/giefferre/carrierpricing/bookingstores"
+	"github.com/giefferre/carrierpricing/carrierdispatchers"
 	"github.com/giefferre/carrierpricing/carrierservicefinders"
+	"github.com/giefferre/carrierpricing/distancecalculators"
+	"github.com/giefferre/carrierpricing/exchangerateproviders"
+	"github.com/giefferre/carrierpricing/holidaycalendars"
 	"github.com/giefferre/carrierpricing/internal/httpserver"
+	"github.com/giefferre/carrierpricing/quotestores"
 )
 
+// defaultCSFReloadInterval is how often the carrierservicefinder file is checked
+// for changes, unless a different interval is set via CSF_RELOAD_INTERVAL.
+const defaultCSFReloadInterval = 10 * time.Second
+
 var (
 	logger               *log.Logger
 	carrierServiceFinder carrierpricing.CarrierServiceFinder
+	csfFromJSONFile      *carrierservicefinders.CSFFromJSONFile
+	csfReloadInterval    = defaultCSFReloadInterval
+	distanceCalculator   carrierpricing.DistanceCalculator
+	pricingRules         *carrierpricing.PricingRules
+	serviceOptions       []carrierpricing.ServiceOption
+	httpServerOptions    []httpserver.HTTPServerOption
 )
 
 func init() {
 	// logger is initialized to print any information on standard out
 	logger = log.New(os.Stdout, "carrierpricing ", log.LstdFlags)
+}
 
+// configure sets up the dependencies of the service from the environment
+// variables, exiting if any of them cannot be set up.
+func configure() {
 	// here we configure the carrierservicefinder;
-	// in this case we want to use a CSFFromJSONFile object, passing the file path
-	// of the source data via CSF_JSON_FILE environment variable.
+	// we want to use a CSFFromJSONFile object, passing the file path of the source
+	// data via CSF_JSON_FILE environment variable, and/or a CSFFromSQL object when
+	// CSF_SQL_DSN environment variable is set, connecting to the database via the
+	// CSF_SQL_DRIVER driver ("sqlite3" when not set). When both are set, their
+	// carrier services are combined via a CompositeCSF object.
 	var err error
+	var carrierServiceFinders []carrierpricing.CarrierServiceFinder
 	jsonFilePath := os.Getenv("CSF_JSON_FILE")
+	sqlDSN := os.Getenv("CSF_SQL_DSN")
 
-	logger.Printf("Trying to use CSFFromJSONFile with file: %s", jsonFilePath)
-	carrierServiceFinder, err = carrierservicefinders.NewCSFFromJSONFile(jsonFilePath)
-	if err != nil {
-		logger.Fatalf("NewCSFFromJSONFile method returned error %v", err)
+	if jsonFilePath != "" || sqlDSN == "" {
+		logger.Printf("Trying to use CSFFromJSONFile with file: %s", jsonFilePath)
+		csfFromJSONFile, err = carrierservicefinders.NewCSFFromJSONFile(jsonFilePath)
+		if err != nil {
+			logger.Fatalf("NewCSFFromJSONFile method returned error %v", err)
+		}
+		carrierServiceFinders = append(carrierServiceFinders, csfFromJSONFile)
+	}
+
+	if sqlDSN != "" {
+		sqlDriver := os.Getenv("CSF_SQL_DRIVER")
+		if sqlDriver == "" {
+			sqlDriver = "sqlite3"
+		}
+
+		logger.Printf("Trying to use CSFFromSQL with driver: %s", sqlDriver)
+		db, err := sql.Open(sqlDriver, sqlDSN)
+		if err != nil {
+			logger.Fatalf("sql.Open method returned error %v", err)
+		}
+
+		err = carrierservicefinders.MigrateCarriersSchema(db)
+		if err != nil {
+			logger.Fatalf("MigrateCarriersSchema method returned error %v", err)
+		}
+
+		csfFromSQL, err := carrierservicefinders.NewCSFFromSQL(db)
+		if err != nil {
+			logger.Fatalf("NewCSFFromSQL method returned error %v", err)
+		}
+		carrierServiceFinders = append(carrierServiceFinders, csfFromSQL)
+	}
+
+	// the carrier services can be found via a fallback finder, when the others
+	// all fail or find none, set via CSF_FALLBACK environment variable: "static"
+	// uses a CSFFromStaticData object; no fallback finder is used when not set.
+	var csfFallback carrierpricing.CarrierServiceFinder
+	switch csfFallbackValue := os.Getenv("CSF_FALLBACK"); csfFallbackValue {
+	case "":
+	case "static":
+		logger.Println("Falling back to CSFFromStaticData")
+		csfFallback = carrierservicefinders.NewCSFFromStaticData()
+	default:
+		logger.Fatalf("invalid CSF_FALLBACK %q, expected \"static\"", csfFallbackValue)
+	}
+
+	carrierServiceFinder = carrierServiceFinders[0]
+	if len(carrierServiceFinders) > 1 || csfFallback != nil {
+		logger.Println("Combining carrier services via CompositeCSF")
+		carrierServiceFinder = carrierservicefinders.NewCompositeCSF(carrierServiceFinders, csfFallback)
+	}
+
+	// the file is reloaded when modified, checking it every CSF_RELOAD_INTERVAL
+	// (e.g. "30s"), 10 seconds when not set; "0" disables the check.
+	if csfReloadIntervalValue := os.Getenv("CSF_RELOAD_INTERVAL"); csfReloadIntervalValue != "" {
+		csfReloadInterval, err = time.ParseDuration(csfReloadIntervalValue)
+		if err != nil {
+			logger.Fatalf("invalid CSF_RELOAD_INTERVAL %q: %v", csfReloadIntervalValue, err)
+		}
+	}
+
+	// the carrierservicefinder is given up on after the duration set via
+	// CSF_TIMEOUT environment variable (e.g. "2s"), 5 seconds when not set.
+	if csfTimeoutValue := os.Getenv("CSF_TIMEOUT"); csfTimeoutValue != "" {
+		csfTimeout, err := time.ParseDuration(csfTimeoutValue)
+		if err != nil {
+			logger.Fatalf("invalid CSF_TIMEOUT %q: %v", csfTimeoutValue, err)
+		}
+		serviceOptions = append(serviceOptions, carrierpricing.WithCarrierServiceFinderTimeout(csfTimeout))
+	}
+
+	// carriers can be managed via the /carriers endpoints, authenticated with the
+	// bearer token set via CARRIERS_API_TOKEN environment variable; changes are
+	// written to the CSF_JSON_FILE file.
+	if carriersAPIToken := os.Getenv("CARRIERS_API_TOKEN"); carriersAPIToken != "" {
+		if csfFromJSONFile == nil {
+			logger.Fatalf("CARRIERS_API_TOKEN requires carriers to be loaded from CSF_JSON_FILE")
+		}
+		serviceOptions = append(serviceOptions, carrierpricing.WithCarrierCatalogue(csfFromJSONFile))
+		httpServerOptions = append(httpServerOptions, httpserver.WithCarriersAPIToken(carriersAPIToken))
+	}
+
+	// bookings can be moved through their lifecycle via POST /bookings/{id}/status,
+	// authenticated with the bearer token set via BOOKINGS_API_TOKEN environment
+	// variable; the endpoint is not served when it is not set.
+	if bookingsAPIToken := os.Getenv("BOOKINGS_API_TOKEN"); bookingsAPIToken != "" {
+		httpServerOptions = append(httpServerOptions, httpserver.WithBookingsAPIToken(bookingsAPIToken))
 	}
 
 	// want to use a simple carrierServiceFinder?
 	// comment lines 21:31 and uncomment the following one
 	// carrierServiceFinder = carrierservicefinders.NewCSFFromStaticData()
+
+	// the distancecalculator uses the centroids of the postcode districts,
+	// loaded from the CSV file whose path is given via DC_CSV_FILE environment variable.
+	csvFilePath := os.Getenv("DC_CSV_FILE")
+
+	logger.Printf("Trying to use DCFromCSVFile with file: %s", csvFilePath)
+	distanceCalculator, err = distancecalculators.NewDCFromCSVFile(csvFilePath)
+	if err != nil {
+		logger.Fatalf("NewDCFromCSVFile method returned error %v", err)
+	}
+
+	// exchange rates are loaded from the JSON file whose path is given via
+	// ERP_JSON_FILE environment variable; when not set, prices can only be
+	// quoted in the currency of the pricing rules.
+	exchangeRatesFilePath := os.Getenv("ERP_JSON_FILE")
+	if exchangeRatesFilePath == "" {
+		logger.Println("ERP_JSON_FILE not set, currency conversion disabled")
+	} else {
+		logger.Printf("Trying to use ERPFromJSONFile with file: %s", exchangeRatesFilePath)
+		exchangeRateProvider, err := exchangerateproviders.NewERPFromJSONFile(exchangeRatesFilePath)
+		if err != nil {
+			logger.Fatalf("NewERPFromJSONFile method returned error %v", err)
+		}
+		serviceOptions = append(serviceOptions, carrierpricing.WithExchangeRateProvider(exchangeRateProvider))
+	}
+
+	// bank holidays are loaded from the JSON file whose path is given via
+	// HOLIDAY_CALENDAR_FILE environment variable; when not set, bank holiday
+	// surcharges are never applied.
+	holidayCalendarFilePath := os.Getenv("HOLIDAY_CALENDAR_FILE")
+	if holidayCalendarFilePath == "" {
+		logger.Println("HOLIDAY_CALENDAR_FILE not set, bank holiday surcharges disabled")
+	} else {
+		logger.Printf("Trying to use HCFromJSONFile with file: %s", holidayCalendarFilePath)
+		holidayCalendar, err := holidaycalendars.NewHCFromJSONFile(holidayCalendarFilePath)
+		if err != nil {
+			logger.Fatalf("NewHCFromJSONFile method returned error %v", err)
+		}
+		serviceOptions = append(serviceOptions, carrierpricing.WithHolidayCalendar(holidayCalendar))
+	}
+
+	// quotes are saved as JSON files in the directory given via QUOTE_STORE_DIR
+	// environment variable, or in memory when not set; they are valid for the
+	// duration given via QUOTE_VALIDITY (e.g. "30m"), 15 minutes when not set.
+	quoteValidity := carrierpricing.DefaultQuoteValidity
+	if quoteValidityValue := os.Getenv("QUOTE_VALIDITY"); quoteValidityValue != "" {
+		quoteValidity, err = time.ParseDuration(quoteValidityValue)
+		if err != nil {
+			logger.Fatalf("invalid QUOTE_VALIDITY %q: %v", quoteValidityValue, err)
+		}
+	}
+
+	quoteStoreDirectory := os.Getenv("QUOTE_STORE_DIR")
+	if quoteStoreDirectory == "" {
+		logger.Println("QUOTE_STORE_DIR not set, quotes are stored in memory")
+		serviceOptions = append(serviceOptions, carrierpricing.WithQuoteStore(quotestores.NewQSInMemory(), quoteValidity))
+	} else {
+		logger.Printf("Trying to use QSJSONDirectory with directory: %s", quoteStoreDirectory)
+		quoteStore, err := quotestores.NewQSJSONDirectory(quoteStoreDirectory)
+		if err != nil {
+			logger.Fatalf("NewQSJSONDirectory method returned error %v", err)
+		}
+		serviceOptions = append(serviceOptions, carrierpricing.WithQuoteStore(quoteStore, quoteValidity))
+	}
+
+	// bookings are kept in memory
+	serviceOptions = append(serviceOptions, carrierpricing.WithBookingStore(bookingstores.NewBSInMemory()))
+
+	// bookings are handed over to the carriers having a "dispatcher" in the
+	// CSF_JSON_FILE file, when set, as currently loaded: reloads and changes made
+	// via the /carriers endpoints are used straight away.
+	if csfFromJSONFile != nil {
+		carrierDispatchers := carrierdispatchers.NewCDRegistryFromSource(csfFromJSONFile, nil)
+		serviceOptions = append(serviceOptions, carrierpricing.WithCarrierDispatchers(carrierDispatchers))
+	}
+
+	// pricing rules are loaded from the JSON file whose path is given via
+	// PRICING_RULES_FILE environment variable; when not set, defaults are used.
+	pricingRulesFilePath := os.Getenv("PRICING_RULES_FILE")
+	if pricingRulesFilePath == "" {
+		logger.Println("PRICING_RULES_FILE not set, using default pricing rules")
+		return
+	}
+
+	logger.Printf("Trying to load pricing rules from file: %s", pricingRulesFilePath)
+	pricingRules, err = carrierpricing.LoadPricingRulesFromJSONFile(pricingRulesFilePath)
+	if err != nil {
+		logger.Fatalf("LoadPricingRulesFromJSONFile method returned error %v", err)
+	}
 }
 
 func main() {
-	carrierPricingService := carrierpricing.NewService(logger, carrierServiceFinder)
-	httpServer := httpserver.NewHTTPServer(logger, carrierPricingService)
+	// "validate" checks carriers files, without starting the application
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(validateCarriers(os.Args[2:]))
+	}
+
+	configure()
+
+	carrierPricingService, err := carrierpricing.NewService(logger, carrierServiceFinder, distanceCalculator, pricingRules, serviceOptions...)
+	if err != nil {
+		logger.Fatalf("NewService method returned error %v", err)
+	}
+
+	httpServer := httpserver.NewHTTPServer(logger, carrierPricingService, httpServerOptions...)
+
+	if csfFromJSONFile != nil && csfReloadInterval > 0 {
+		go csfFromJSONFile.Watch(context.Background(), csfReloadInterval, func(err error) {
+			if err != nil {
+				logger.Printf("CSFFromJSONFile reload returned error %v, keeping current carriers", err)
+				return
+			}
+			logger.Println("carriers reloaded")
+		})
+	}
+
+	go reloadOnSIGHUP(carrierPricingService)
 
 	httpServer.Start()
 }
+
+// reloadOnSIGHUP reloads the carriers and the pricing rules files whenever the
+// process receives a SIGHUP signal; if the new carriers or rules are not valid,
+// the current ones are kept.
+func reloadOnSIGHUP(carrierPricingService *carrierpricing.Service) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	for range signals {
+		if csfFromJSONFile != nil {
+			logger.Println("SIGHUP received, reloading carriers")
+			if err := csfFromJSONFile.Reload(); err != nil {
+				logger.Printf("CSFFromJSONFile reload returned error %v, keeping current carriers", err)
+			}
+		}
+
+		pricingRulesFilePath := os.Getenv("PRICING_RULES_FILE")
+		if pricingRulesFilePath == "" {
+			continue
+		}
+
+		logger.Printf("SIGHUP received, reloading pricing rules from file: %s", pricingRulesFilePath)
+		rules, err := carrierpricing.LoadPricingRulesFromJSONFile(pricingRulesFilePath)
+		if err != nil {
+			logger.Printf("LoadPricingRulesFromJSONFile method returned error %v, keeping current rules", err)
+			continue
+		}
+
+		err = carrierPricingService.SetPricingRules(rules)
+		if err != nil {
+			logger.Printf("SetPricingRules method returned error %v, keeping current rules", err)
+		}
+	}
+}
+
+// validateCarriers checks the given carriers files, or the one given via the
+// CSF_JSON_FILE environment variable when none is given, printing all the
+// problems found; it returns the exit code of the validate command, 1 when
+// any file is not valid.
+func validateCarriers(jsonFilePaths []string) int {
+	if len(jsonFilePaths) == 0 {
+		jsonFilePaths = []string{os.Getenv("CSF_JSON_FILE")}
+	}
+
+	exitCode := 0
+	for _, jsonFilePath := range jsonFilePaths {
+		jsonFileContent, err := ioutil.ReadFile(jsonFilePath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exitCode = 1
+			continue
+		}
+
+		problems := carrierservicefinders.ValidateCarriersJSON(jsonFileContent)
+		for _, problem := range problems {
+			fmt.Fprintf(os.Stderr, "%s:%d: %s: %s\n", jsonFilePath, problem.Line, problem.Path, problem.Message)
+		}
+
+		if len(problems) > 0 {
+			exitCode = 1
+			continue
+		}
+
+		fmt.Printf("%s: OK\n", jsonFilePath)
+	}
+
+	return exitCode
+}
@@ -0,0 +1,8 @@
+//go:build cgo
+// +build cgo
+
+package main
+
+// the SQLite driver, registered as "sqlite3", embeds SQLite via cgo; binaries
+// built with CGO_ENABLED=0 can only use CSFFromSQL with other drivers.
+import _ "github.com/mattn/go-sqlite3"
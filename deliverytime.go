@@ -0,0 +1,296 @@
+package carrierpricing
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/giefferre/carrierpricing/postcode"
+)
+
+// DeliveryTimeUnit is the unit a DeliveryDuration is expressed in.
+type DeliveryTimeUnit string
+
+const (
+	// DeliveryTimeUnitMinutes measures delivery times in minutes of working time.
+	DeliveryTimeUnitMinutes DeliveryTimeUnit = "minutes"
+
+	// DeliveryTimeUnitHours measures delivery times in hours of working time.
+	DeliveryTimeUnitHours DeliveryTimeUnit = "hours"
+
+	// DeliveryTimeUnitWorkingDays measures delivery times in working days after
+	// the day of the collection.
+	DeliveryTimeUnitWorkingDays DeliveryTimeUnit = "working_days"
+)
+
+// maxDaysAhead bounds the search for the next working day, so that a calendar
+// without working days cannot stall the estimation.
+const maxDaysAhead = 366
+
+var (
+	errInvalidDeliveryTimeUnit = errors.New("invalid delivery time unit")
+	errNoWorkingDays           = errors.New("no working days found")
+)
+
+// DeliveryDuration is the time a carrier service takes to deliver a parcel,
+// counted from its collection.
+type DeliveryDuration struct {
+	Value int64            `json:"value"`
+	Unit  DeliveryTimeUnit `json:"unit"`
+}
+
+// NewDeliveryDuration returns a DeliveryDuration of the given value and unit.
+func NewDeliveryDuration(value int64, unit DeliveryTimeUnit) DeliveryDuration {
+	return DeliveryDuration{Value: value, Unit: unit}
+}
+
+// Validate returns an error if the DeliveryDuration has a negative value or an
+// unknown unit.
+func (dd DeliveryDuration) Validate() error {
+	switch dd.Unit {
+	case DeliveryTimeUnitMinutes, DeliveryTimeUnitHours, DeliveryTimeUnitWorkingDays:
+	default:
+		return fmt.Errorf("%w %q", errInvalidDeliveryTimeUnit, dd.Unit)
+	}
+
+	if dd.Value < 0 {
+		return errors.New("delivery time must not be negative")
+	}
+
+	return nil
+}
+
+// Nominal returns the DeliveryDuration as a time.Duration, a working day being
+// counted as 24 hours; it is meant to compare delivery times regardless of the
+// pickup time, not to estimate when a parcel will be delivered.
+func (dd DeliveryDuration) Nominal() time.Duration {
+	switch dd.Unit {
+	case DeliveryTimeUnitMinutes:
+		return time.Duration(dd.Value) * time.Minute
+	case DeliveryTimeUnitHours:
+		return time.Duration(dd.Value) * time.Hour
+	default:
+		return time.Duration(dd.Value) * 24 * time.Hour
+	}
+}
+
+// String returns the DeliveryDuration as a human readable string, e.g. "3 hours".
+func (dd DeliveryDuration) String() string {
+	return fmt.Sprintf("%d %s", dd.Value, dd.Unit)
+}
+
+// WorkingHours are the hours of the working days, Monday to Friday except bank
+// holidays, during which a carrier collects and delivers parcels. From and To
+// are "15:04" formatted UK local times, From being earlier than To.
+type WorkingHours struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Validate returns an error if the WorkingHours are not a valid time range.
+func (wh WorkingHours) Validate() error {
+	from, err := parseClock(wh.From)
+	if err != nil {
+		return fmt.Errorf("from: %v", err)
+	}
+
+	to, err := parseClock(wh.To)
+	if err != nil {
+		return fmt.Errorf("to: %v", err)
+	}
+
+	if from >= to {
+		return errors.New("working hours must end after they start")
+	}
+
+	return nil
+}
+
+// IncludesTimeOfDay returns true if the UK local time of day of t is within the
+// WorkingHours, whatever the day; the WorkingHours are expected to be valid.
+func (wh WorkingHours) IncludesTimeOfDay(t time.Time) bool {
+	from, _ := parseClock(wh.From)
+	to, _ := parseClock(wh.To)
+
+	localTime := t.In(ukLocation)
+	minutes := localTime.Hour()*60 + localTime.Minute()
+
+	return minutes >= from && minutes < to
+}
+
+// DeliveryWindow is the estimated time range a parcel will be delivered in.
+// For services measured in working days, it spans the working hours of the day
+// of the delivery; otherwise, the parcel may be delivered any time between its
+// collection and the deadline.
+type DeliveryWindow struct {
+	Earliest time.Time `json:"earliest"`
+	Latest   time.Time `json:"latest"`
+}
+
+// estimateDelivery returns the DeliveryWindow of a parcel handed to the given
+// carrier service at pickupTime, in UK local time; nil is returned when the
+// pickup time is not known or the delivery cannot be estimated.
+func (s *Service) estimateDelivery(carrierService CarrierService, pickupTime *time.Time, pickup *postcode.Postcode) *DeliveryWindow {
+	if pickupTime == nil {
+		return nil
+	}
+
+	calendar, err := s.newWorkingCalendar(carrierService.WorkingHours, pickup)
+	if err != nil {
+		s.logger.Printf("cannot estimate delivery for carrier service %s: %v\n", carrierService.Name, err)
+		return nil
+	}
+
+	window, err := calendar.deliveryWindow(pickupTime.In(ukLocation), carrierService.CutOff, carrierService.DeliveryTime)
+	if err != nil {
+		s.logger.Printf("cannot estimate delivery for carrier service %s: %v\n", carrierService.Name, err)
+		return nil
+	}
+
+	return window
+}
+
+// workingCalendar tells when a carrier works; a calendar without working hours
+// works around the clock, every day.
+type workingCalendar struct {
+	hasWorkingHours bool
+	from, to        int
+	isBankHoliday   func(day time.Time) bool
+}
+
+func (s *Service) newWorkingCalendar(workingHours *WorkingHours, pickup *postcode.Postcode) (*workingCalendar, error) {
+	calendar := &workingCalendar{
+		isBankHoliday: func(day time.Time) bool { return false },
+	}
+
+	if workingHours == nil {
+		return calendar, nil
+	}
+
+	err := workingHours.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	calendar.hasWorkingHours = true
+	calendar.from, _ = parseClock(workingHours.From)
+	calendar.to, _ = parseClock(workingHours.To)
+
+	if s.holidayCalendar != nil {
+		calendar.isBankHoliday = func(day time.Time) bool {
+			return s.holidayCalendar.IsBankHoliday(day, *pickup)
+		}
+	}
+
+	return calendar, nil
+}
+
+// deliveryWindow returns the DeliveryWindow of a parcel collected at pickupTime
+// and delivered in the given duration; collections after the cut-off time, when
+// set, or outside of the working hours start on the next working day.
+func (wc *workingCalendar) deliveryWindow(pickupTime time.Time, cutOff string, duration DeliveryDuration) (*DeliveryWindow, error) {
+	err := duration.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	day := startOfDay(pickupTime)
+	start := pickupTime
+
+	afterCutOff := false
+	if cutOff != "" {
+		cutOffMinutes, err := parseClock(cutOff)
+		if err != nil {
+			return nil, fmt.Errorf("cut off: %v", err)
+		}
+		afterCutOff = !pickupTime.Before(atMinutes(day, cutOffMinutes))
+	}
+
+	switch {
+	case !wc.isWorkingDay(day) || afterCutOff || !pickupTime.Before(wc.closing(day)):
+		day, err = wc.nextWorkingDay(day)
+		if err != nil {
+			return nil, err
+		}
+		start = wc.opening(day)
+	case pickupTime.Before(wc.opening(day)):
+		start = wc.opening(day)
+	}
+
+	if duration.Unit == DeliveryTimeUnitWorkingDays {
+		window := &DeliveryWindow{Earliest: start}
+		for i := int64(0); i < duration.Value; i++ {
+			day, err = wc.nextWorkingDay(day)
+			if err != nil {
+				return nil, err
+			}
+			window.Earliest = wc.opening(day)
+		}
+		window.Latest = wc.closing(day)
+		return window, nil
+	}
+
+	// deliveries measured in minutes or hours only progress during working hours
+	remaining := duration.Nominal()
+	current := start
+	for {
+		closing := wc.closing(day)
+		if !current.Add(remaining).After(closing) {
+			return &DeliveryWindow{Earliest: start, Latest: current.Add(remaining)}, nil
+		}
+
+		remaining -= closing.Sub(current)
+		day, err = wc.nextWorkingDay(day)
+		if err != nil {
+			return nil, err
+		}
+		current = wc.opening(day)
+	}
+}
+
+func (wc *workingCalendar) isWorkingDay(day time.Time) bool {
+	if !wc.hasWorkingHours {
+		return true
+	}
+	if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		return false
+	}
+	return !wc.isBankHoliday(day)
+}
+
+// nextWorkingDay returns the start of the first working day after the given one.
+func (wc *workingCalendar) nextWorkingDay(day time.Time) (time.Time, error) {
+	for i := 0; i < maxDaysAhead; i++ {
+		day = startOfDay(day.AddDate(0, 0, 1))
+		if wc.isWorkingDay(day) {
+			return day, nil
+		}
+	}
+	return time.Time{}, errNoWorkingDays
+}
+
+func (wc *workingCalendar) opening(day time.Time) time.Time {
+	if !wc.hasWorkingHours {
+		return day
+	}
+	return atMinutes(day, wc.from)
+}
+
+func (wc *workingCalendar) closing(day time.Time) time.Time {
+	if !wc.hasWorkingHours {
+		return startOfDay(day.AddDate(0, 0, 1))
+	}
+	return atMinutes(day, wc.to)
+}
+
+// startOfDay returns the midnight of the day of the given time, in its location.
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// atMinutes returns the time of the given day at the given minutes since midnight.
+func atMinutes(day time.Time, minutes int) time.Time {
+	year, month, date := day.Date()
+	return time.Date(year, month, date, minutes/60, minutes%60, 0, 0, day.Location())
+}
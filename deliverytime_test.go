@@ -0,0 +1,221 @@
+package carrierpricing
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestWorkingCalendarDeliveryWindow(t *testing.T) {
+	officeHours := &workingCalendar{
+		hasWorkingHours: true,
+		from:            8 * 60,
+		to:              18 * 60,
+		isBankHoliday: func(day time.Time) bool {
+			return day.Format("2006-01-02") == "2026-12-25" || day.Format("2006-01-02") == "2026-12-28"
+		},
+	}
+	aroundTheClock := &workingCalendar{
+		isBankHoliday: func(day time.Time) bool { return false },
+	}
+
+	tests := []struct {
+		Calendar         *workingCalendar
+		PickupTime       time.Time
+		CutOff           string
+		Duration         DeliveryDuration
+		ExpectedEarliest time.Time
+		ExpectedLatest   time.Time
+	}{
+		// case #1 next working day
+		{
+			Calendar:         officeHours,
+			PickupTime:       ukTime(2026, 10, 15, 10, 0),
+			CutOff:           "16:00",
+			Duration:         workingDays(1),
+			ExpectedEarliest: ukTime(2026, 10, 16, 8, 0),
+			ExpectedLatest:   ukTime(2026, 10, 16, 18, 0),
+		},
+		// case #2 after the cut-off, collected on friday and delivered on monday
+		{
+			Calendar:         officeHours,
+			PickupTime:       ukTime(2026, 10, 15, 16, 0),
+			CutOff:           "16:00",
+			Duration:         workingDays(1),
+			ExpectedEarliest: ukTime(2026, 10, 19, 8, 0),
+			ExpectedLatest:   ukTime(2026, 10, 19, 18, 0),
+		},
+		// case #3 collected on saturday, delivered on the first working day
+		{
+			Calendar:         officeHours,
+			PickupTime:       ukTime(2026, 10, 17, 10, 0),
+			Duration:         workingDays(0),
+			ExpectedEarliest: ukTime(2026, 10, 19, 8, 0),
+			ExpectedLatest:   ukTime(2026, 10, 19, 18, 0),
+		},
+		// case #4 hours only progress during working hours
+		{
+			Calendar:         officeHours,
+			PickupTime:       ukTime(2026, 10, 15, 15, 0),
+			CutOff:           "16:00",
+			Duration:         NewDeliveryDuration(5, DeliveryTimeUnitHours),
+			ExpectedEarliest: ukTime(2026, 10, 15, 15, 0),
+			ExpectedLatest:   ukTime(2026, 10, 16, 10, 0),
+		},
+		// case #5 collected before the opening
+		{
+			Calendar:         officeHours,
+			PickupTime:       ukTime(2026, 10, 15, 6, 0),
+			Duration:         NewDeliveryDuration(90, DeliveryTimeUnitMinutes),
+			ExpectedEarliest: ukTime(2026, 10, 15, 8, 0),
+			ExpectedLatest:   ukTime(2026, 10, 15, 9, 30),
+		},
+		// case #6 bank holidays are skipped
+		{
+			Calendar:         officeHours,
+			PickupTime:       ukTime(2026, 12, 24, 10, 0),
+			Duration:         workingDays(1),
+			ExpectedEarliest: ukTime(2026, 12, 29, 8, 0),
+			ExpectedLatest:   ukTime(2026, 12, 29, 18, 0),
+		},
+		// case #7 end of british summer time
+		{
+			Calendar:         officeHours,
+			PickupTime:       ukTime(2026, 10, 23, 10, 0),
+			Duration:         workingDays(1),
+			ExpectedEarliest: ukTime(2026, 10, 26, 8, 0),
+			ExpectedLatest:   ukTime(2026, 10, 26, 18, 0),
+		},
+		// case #8 no working hours, delivered overnight
+		{
+			Calendar:         aroundTheClock,
+			PickupTime:       ukTime(2026, 10, 17, 23, 0),
+			Duration:         NewDeliveryDuration(2, DeliveryTimeUnitHours),
+			ExpectedEarliest: ukTime(2026, 10, 17, 23, 0),
+			ExpectedLatest:   ukTime(2026, 10, 18, 1, 0),
+		},
+		// case #9 no working hours, after the cut-off
+		{
+			Calendar:         aroundTheClock,
+			PickupTime:       ukTime(2026, 10, 17, 20, 0),
+			CutOff:           "18:00",
+			Duration:         workingDays(1),
+			ExpectedEarliest: ukTime(2026, 10, 19, 0, 0),
+			ExpectedLatest:   ukTime(2026, 10, 20, 0, 0),
+		},
+	}
+
+	for i, tc := range tests {
+		window, err := tc.Calendar.deliveryWindow(tc.PickupTime, tc.CutOff, tc.Duration)
+		if err != nil {
+			t.Fatalf("case #%d: deliveryWindow returned error %v", i+1, err)
+		}
+
+		if !window.Earliest.Equal(tc.ExpectedEarliest) || !window.Latest.Equal(tc.ExpectedLatest) {
+			t.Fatalf(
+				"case #%d: expected window %v - %v, received: %v - %v",
+				i+1,
+				tc.ExpectedEarliest,
+				tc.ExpectedLatest,
+				window.Earliest,
+				window.Latest,
+			)
+		}
+	}
+}
+
+func TestDeliveryDurationValidate(t *testing.T) {
+	tests := []struct {
+		Duration      DeliveryDuration
+		ExpectedError string
+	}{
+		{workingDays(1), ""},
+		{NewDeliveryDuration(30, DeliveryTimeUnitMinutes), ""},
+		{NewDeliveryDuration(1, ""), "invalid delivery time unit \"\""},
+		{NewDeliveryDuration(1, "weeks"), "invalid delivery time unit \"weeks\""},
+		{NewDeliveryDuration(-1, DeliveryTimeUnitHours), "delivery time must not be negative"},
+	}
+
+	for i, tc := range tests {
+		err := tc.Duration.Validate()
+		if (err == nil && tc.ExpectedError != "") || (err != nil && err.Error() != tc.ExpectedError) {
+			t.Fatalf("case #%d: expected error '%s', received: '%v'", i+1, tc.ExpectedError, err)
+		}
+	}
+}
+
+func TestWorkingHoursIncludesTimeOfDay(t *testing.T) {
+	workingHours := WorkingHours{From: "09:00", To: "17:00"}
+
+	tests := []struct {
+		Time     time.Time
+		Expected bool
+	}{
+		// case #1 opening time, in summer time
+		{Time: ukTime(2026, 7, 1, 9, 0), Expected: true},
+		// case #2 closing time
+		{Time: ukTime(2026, 7, 1, 17, 0), Expected: false},
+		// case #3 UTC time within the hours in UK local time
+		{Time: time.Date(2026, 7, 1, 15, 30, 0, 0, time.UTC), Expected: true},
+		// case #4 UTC time after the hours in UK local time
+		{Time: time.Date(2026, 7, 1, 16, 30, 0, 0, time.UTC), Expected: false},
+		// case #5 weekends are not excluded
+		{Time: ukTime(2026, 7, 4, 12, 0), Expected: true},
+	}
+
+	for i, tc := range tests {
+		if result := workingHours.IncludesTimeOfDay(tc.Time); result != tc.Expected {
+			t.Fatalf("case #%d: expected %v, received: %v", i+1, tc.Expected, result)
+		}
+	}
+}
+
+func TestGetQuotesByCarrierEstimatedDelivery(t *testing.T) {
+	logger := log.New(ioutil.Discard, "", 0)
+	service, err := NewService(logger, &mockCarrierServiceFinder{}, &mockDistanceCalculator{}, nil)
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
+
+	pickupTime := ukTime(2026, 10, 15, 10, 0)
+
+	result, err := service.GetQuotesByCarrier(context.Background(), GetQuotesByCarrierArgs{
+		PickupPostcode:   "SW1A1AA",
+		DeliveryPostcode: "EC2A3LT",
+		Vehicle:          VehicleTypeSmallVan,
+		PickupTime:       &pickupTime,
+	})
+	if err != nil {
+		t.Fatalf("GetQuotesByCarrier returned error %v", err)
+	}
+
+	// mock carrier services have no working hours: every day is a working day
+	expectedLatest := map[string]time.Time{
+		"MockService1": ukTime(2026, 10, 17, 0, 0),
+		"MockService2": ukTime(2026, 10, 21, 0, 0),
+	}
+
+	for _, priceByCarrier := range result.PriceList {
+		if priceByCarrier.EstimatedDelivery == nil {
+			t.Fatalf("expected %s to have an estimated delivery", priceByCarrier.CarrierName)
+		}
+		if !priceByCarrier.EstimatedDelivery.Latest.Equal(expectedLatest[priceByCarrier.CarrierName]) {
+			t.Fatalf(
+				"expected %s to be delivered by %v, received: %v",
+				priceByCarrier.CarrierName,
+				expectedLatest[priceByCarrier.CarrierName],
+				priceByCarrier.EstimatedDelivery.Latest,
+			)
+		}
+	}
+}
+
+func workingDays(value int64) DeliveryDuration {
+	return NewDeliveryDuration(value, DeliveryTimeUnitWorkingDays)
+}
+
+func ukTime(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, ukLocation)
+}
@@ -0,0 +1,11 @@
+package carrierpricing
+
+import (
+	"github.com/giefferre/carrierpricing/postcode"
+)
+
+// DistanceCalculator is a software service used to get the distance, in kilometres,
+// between a pickup and a delivery postcode.
+type DistanceCalculator interface {
+	CalculateDistance(pickup, delivery postcode.Postcode) (float64, error)
+}
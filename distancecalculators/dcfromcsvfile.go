@@ -0,0 +1,159 @@
+package distancecalculators
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/giefferre/carrierpricing/postcode"
+)
+
+// ErrUnknownLocation is returned when no centroid is available neither for the
+// district nor for the area of a postcode.
+var ErrUnknownLocation = errors.New("unknown location for the given postcode")
+
+// earthRadius is the mean radius of the Earth, in kilometres.
+const earthRadius = 6371.0
+
+// DCFromCSVFile implements the carrierpricing.DistanceCalculator interface;
+// the source of data is a CSV file from local storage containing the centroid
+// (latitude and longitude) of each postcode district. Distances are computed
+// as the crow flies, using the haversine formula.
+type DCFromCSVFile struct {
+	districts map[string]centroid
+	areas     map[string]centroid
+}
+
+// NewDCFromCSVFile returns a fresh DCFromCSVFile object having the list of
+// centroids loaded in memory. The file must have a header row followed by
+// rows in the "district,latitude,longitude" format. An error is returned if the
+// file is not found or it does not contain valid rows.
+func NewDCFromCSVFile(csvFilePath string) (*DCFromCSVFile, error) {
+	csvFile, err := os.Open(csvFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer csvFile.Close()
+
+	reader := csv.NewReader(csvFile)
+	reader.FieldsPerRecord = 3
+
+	// skip the header row
+	if _, err := reader.Read(); err != nil {
+		return nil, err
+	}
+
+	districts := map[string]centroid{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		latitude, err := strconv.ParseFloat(record[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid latitude for district %s: %v", record[0], err)
+		}
+
+		longitude, err := strconv.ParseFloat(record[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid longitude for district %s: %v", record[0], err)
+		}
+
+		districts[strings.ToUpper(record[0])] = centroid{
+			latitude:  latitude,
+			longitude: longitude,
+		}
+	}
+
+	return &DCFromCSVFile{
+		districts: districts,
+		areas:     areaCentroids(districts),
+	}, nil
+}
+
+// CalculateDistance returns the distance, in kilometres, between the centroids
+// of the pickup and delivery postcodes. When a district is not present in the
+// data set, the centroid of its area is used instead.
+func (dc *DCFromCSVFile) CalculateDistance(pickup, delivery postcode.Postcode) (float64, error) {
+	from, err := dc.locate(pickup)
+	if err != nil {
+		return 0, err
+	}
+
+	to, err := dc.locate(delivery)
+	if err != nil {
+		return 0, err
+	}
+
+	return haversine(from, to), nil
+}
+
+func (dc *DCFromCSVFile) locate(p postcode.Postcode) (centroid, error) {
+	if c, exists := dc.districts[p.District]; exists {
+		return c, nil
+	}
+
+	if c, exists := dc.areas[p.Area]; exists {
+		return c, nil
+	}
+
+	return centroid{}, fmt.Errorf("%s: %w", p.String(), ErrUnknownLocation)
+}
+
+type centroid struct {
+	latitude  float64
+	longitude float64
+}
+
+// areaCentroids calculates the centroid of each postcode area as the mean of
+// the centroids of its districts.
+func areaCentroids(districts map[string]centroid) map[string]centroid {
+	sums := map[string]centroid{}
+	counts := map[string]float64{}
+
+	for district, c := range districts {
+		// the area is made of the leading letters of the district
+		area := district
+		if i := strings.IndexAny(district, "0123456789"); i >= 0 {
+			area = district[:i]
+		}
+
+		sum := sums[area]
+		sum.latitude += c.latitude
+		sum.longitude += c.longitude
+		sums[area] = sum
+		counts[area]++
+	}
+
+	areas := map[string]centroid{}
+	for area, sum := range sums {
+		areas[area] = centroid{
+			latitude:  sum.latitude / counts[area],
+			longitude: sum.longitude / counts[area],
+		}
+	}
+
+	return areas
+}
+
+// haversine returns the great-circle distance, in kilometres, between two centroids.
+func haversine(from, to centroid) float64 {
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+
+	deltaLatitude := toRadians(to.latitude - from.latitude)
+	deltaLongitude := toRadians(to.longitude - from.longitude)
+
+	a := math.Pow(math.Sin(deltaLatitude/2), 2) +
+		math.Cos(toRadians(from.latitude))*math.Cos(toRadians(to.latitude))*math.Pow(math.Sin(deltaLongitude/2), 2)
+
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
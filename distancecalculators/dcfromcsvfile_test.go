@@ -0,0 +1,208 @@
+package distancecalculators
+
+import (
+	"errors"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/giefferre/carrierpricing/postcode"
+)
+
+func TestHaversine(t *testing.T) {
+	tests := []struct {
+		From             centroid
+		To               centroid
+		ExpectedDistance float64
+	}{
+		// case #1 same point
+		{
+			From:             centroid{latitude: 51.5074, longitude: -0.1278},
+			To:               centroid{latitude: 51.5074, longitude: -0.1278},
+			ExpectedDistance: 0,
+		},
+		// case #2 one degree along the equator
+		{
+			From:             centroid{latitude: 0, longitude: 0},
+			To:               centroid{latitude: 0, longitude: 1},
+			ExpectedDistance: 111.19,
+		},
+		// case #3 from pole to pole
+		{
+			From:             centroid{latitude: 90, longitude: 0},
+			To:               centroid{latitude: -90, longitude: 0},
+			ExpectedDistance: 20015.09,
+		},
+		// case #4 London to Birmingham
+		{
+			From:             centroid{latitude: 51.5074, longitude: -0.1278},
+			To:               centroid{latitude: 52.4797, longitude: -1.9069},
+			ExpectedDistance: 162.86,
+		},
+	}
+
+	for i, tc := range tests {
+		distance := haversine(tc.From, tc.To)
+		if math.Abs(distance-tc.ExpectedDistance) > 0.01 {
+			t.Fatalf("case #%d: expected distance %.2f, received: %.2f", i+1, tc.ExpectedDistance, distance)
+		}
+
+		if reverse := haversine(tc.To, tc.From); math.Abs(reverse-distance) > 1e-9 {
+			t.Fatalf("case #%d: expected the same distance both ways, received: %f and %f", i+1, distance, reverse)
+		}
+	}
+}
+
+func TestDCFromCSVFile(t *testing.T) {
+	tests := []struct {
+		Pickup           string
+		Delivery         string
+		ExpectedDistance float64
+		ExpectedError    error
+	}{
+		// case #1 both districts in the data set
+		{
+			Pickup:           "AB10 1AA",
+			Delivery:         "ab11 5qn",
+			ExpectedDistance: 22.24,
+		},
+		// case #2 same district
+		{
+			Pickup:           "AB10 1AA",
+			Delivery:         "AB10 7JB",
+			ExpectedDistance: 0,
+		},
+		// case #3 delivery district not in the data set, area centroid used
+		{
+			Pickup:           "AB10 1AA",
+			Delivery:         "AB12 4NA",
+			ExpectedDistance: 11.12,
+		},
+		// case #4 pickup district not in the data set, area centroid used
+		{
+			Pickup:           "AB12 4NA",
+			Delivery:         "AB11 5QN",
+			ExpectedDistance: 11.12,
+		},
+		// case #5 unknown area
+		{
+			Pickup:        "AB10 1AA",
+			Delivery:      "ZE1 0AA",
+			ExpectedError: ErrUnknownLocation,
+		},
+	}
+
+	directory, err := ioutil.TempDir("", "dcfromcsvfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	csvFilePath := filepath.Join(directory, "postcode_districts.csv")
+	err = ioutil.WriteFile(csvFilePath, []byte("district,latitude,longitude\nAB10,57.0,-2.0\nab11,57.2,-2.0\nB1,52.4797,-1.9069\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dc, err := NewDCFromCSVFile(csvFilePath)
+	if err != nil {
+		t.Fatalf("NewDCFromCSVFile returned error %v", err)
+	}
+
+	for i, tc := range tests {
+		pickup, err := postcode.Parse(tc.Pickup)
+		if err != nil {
+			t.Fatal(err)
+		}
+		delivery, err := postcode.Parse(tc.Delivery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		distance, err := dc.CalculateDistance(*pickup, *delivery)
+
+		if tc.ExpectedError != nil {
+			if !errors.Is(err, tc.ExpectedError) {
+				t.Fatalf("case #%d: expected error '%v', received: '%v'", i+1, tc.ExpectedError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("case #%d: unexpected error %v", i+1, err)
+		}
+
+		if math.Abs(distance-tc.ExpectedDistance) > 0.01 {
+			t.Fatalf("case #%d: expected distance %.2f, received: %.2f", i+1, tc.ExpectedDistance, distance)
+		}
+	}
+}
+
+func TestNewDCFromCSVFile(t *testing.T) {
+	tests := []struct {
+		Content       string
+		ExpectedError bool
+	}{
+		// case #1 valid rows
+		{
+			Content: "district,latitude,longitude\nAB10,57.1437,-2.1077\nB1,52.4797,-1.9069\n",
+		},
+		// case #2 header only
+		{
+			Content: "district,latitude,longitude\n",
+		},
+		// case #3 empty file
+		{
+			Content:       "",
+			ExpectedError: true,
+		},
+		// case #4 missing field
+		{
+			Content:       "district,latitude,longitude\nAB10,57.1437\n",
+			ExpectedError: true,
+		},
+		// case #5 extra field
+		{
+			Content:       "district,latitude,longitude\nAB10,57.1437,-2.1077,Aberdeen\n",
+			ExpectedError: true,
+		},
+		// case #6 invalid latitude
+		{
+			Content:       "district,latitude,longitude\nAB10,north,-2.1077\n",
+			ExpectedError: true,
+		},
+		// case #7 invalid longitude
+		{
+			Content:       "district,latitude,longitude\nAB10,57.1437,\n",
+			ExpectedError: true,
+		},
+	}
+
+	directory, err := ioutil.TempDir("", "dcfromcsvfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	for i, tc := range tests {
+		csvFilePath := filepath.Join(directory, "postcode_districts.csv")
+		err = ioutil.WriteFile(csvFilePath, []byte(tc.Content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = NewDCFromCSVFile(csvFilePath)
+		if tc.ExpectedError && err == nil {
+			t.Fatalf("case #%d: expected an error", i+1)
+		}
+		if !tc.ExpectedError && err != nil {
+			t.Fatalf("case #%d: unexpected error %v", i+1, err)
+		}
+	}
+
+	_, err = NewDCFromCSVFile(filepath.Join(directory, "missing.csv"))
+	if err == nil {
+		t.Fatal("expected an error loading a missing file")
+	}
+}
@@ -1,6 +1,6 @@
 /*
 Package carrierpricing allows to calculate quotes for carrier pricing
-according to three different methods.
+according to six different methods.
 
 Available methods:
 
@@ -9,5 +9,11 @@ Available methods:
 						available vehicles are: "bicycle", "motorbike", "parcel_car", "small_van", "large_van"
 - GetQuoteByCarrier:	returns the price according to pickup and delivery postcodes and the vehicle used,
 						giving the list of the all available carriers used; all prices are sorted by price
+- GetBestQuotes:		returns the cheapest and the fastest options according to pickup and delivery postcodes,
+						evaluating all the vehicles able to carry the parcel and all the available carriers
+- GetShipmentQuote:		returns the consolidated price of several parcels delivered with the same vehicle,
+						splitting them in more loads when needed; a per-parcel breakdown is included
+- GetRouteQuote:		returns the price of a route from a pickup postcode through several delivery postcodes,
+						optionally reordering them to minimise the total distance
 */
 package carrierpricing
@@ -0,0 +1,179 @@
+package carrierpricing
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+var errExchangeRatesNotAvailable = errors.New("exchange rates not available")
+
+// ExchangeRateProvider is a software service used to convert prices to the
+// currency requested by the caller.
+type ExchangeRateProvider interface {
+	GetExchangeRate(from, to string) (*ExchangeRate, error)
+}
+
+// ExchangeRate indicates how many units of the To currency are worth one unit
+// of the From currency, as of the given time.
+type ExchangeRate struct {
+	From string    `json:"from"`
+	To   string    `json:"to"`
+	Rate float64   `json:"rate"`
+	AsOf time.Time `json:"as_of"`
+}
+
+// convert returns the given amount, expressed in the From currency, in the To
+// currency, rounded to its minor unit according to the given RoundingMode.
+// A nil ExchangeRate leaves the amount unchanged.
+func (er *ExchangeRate) convert(amount Money, rounding RoundingMode) Money {
+	if er == nil {
+		return amount
+	}
+
+	// minor units of the two currencies may have a different number of digits
+	scale := new(big.Rat).SetFrac64(pow10(currencyMinorUnits[er.To]), pow10(currencyMinorUnits[er.From]))
+
+	rate, ok := decimalRat(er.Rate)
+	if !ok {
+		// this can only happen for NaN and infinite rates, which are rejected
+		// by the exchange rate providers
+		scaleFloat, _ := scale.Float64()
+		return NewMoney(int64(rounding.Round(float64(amount.Amount)*er.Rate*scaleFloat)), er.To)
+	}
+
+	converted := new(big.Rat).Mul(new(big.Rat).SetInt64(amount.Amount), rate)
+	converted.Mul(converted, scale)
+
+	return NewMoney(rounding.roundRat(converted), er.To)
+}
+
+// convertBreakdown returns a copy of the given PriceBreakdown with every item
+// converted to the To currency; the rounding adjustment is calculated again so
+// that the items still add up to the converted total.
+func (er *ExchangeRate) convertBreakdown(breakdown *PriceBreakdown, rounding RoundingMode) *PriceBreakdown {
+	if er == nil || breakdown == nil {
+		return breakdown
+	}
+
+	converted := breakdown.copy()
+	converted.BasePrice = er.convert(breakdown.BasePrice, rounding)
+	converted.VehicleMarkup = er.convert(breakdown.VehicleMarkup, rounding)
+	converted.CarrierBasePrice = er.convert(breakdown.CarrierBasePrice, rounding)
+	converted.ServiceMarkup = er.convert(breakdown.ServiceMarkup, rounding)
+	converted.Surcharges = er.convertAdjustments(breakdown.Surcharges, rounding)
+	converted.Discounts = er.convertAdjustments(breakdown.Discounts, rounding)
+	converted.Total = er.convert(breakdown.Total, rounding)
+	converted.RoundingAdjustment = NewMoney(converted.Total.Amount-converted.sumOfItems(), er.To)
+
+	return converted
+}
+
+// convertAdjustments returns a copy of the given adjustments with every amount
+// converted to the To currency.
+func (er *ExchangeRate) convertAdjustments(adjustments []PriceAdjustment, rounding RoundingMode) []PriceAdjustment {
+	if er == nil || adjustments == nil {
+		return adjustments
+	}
+
+	converted := make([]PriceAdjustment, len(adjustments))
+	for i, adjustment := range adjustments {
+		adjustment.Amount = er.convert(adjustment.Amount, rounding)
+		converted[i] = adjustment
+	}
+
+	return converted
+}
+
+// convertPriceList returns a copy of the given PriceByCarrierList with every
+// price converted to the To currency.
+func (er *ExchangeRate) convertPriceList(priceList PriceByCarrierList, rounding RoundingMode) PriceByCarrierList {
+	if er == nil {
+		return priceList
+	}
+
+	converted := make(PriceByCarrierList, len(priceList))
+	for i, priceByCarrier := range priceList {
+		priceByCarrier.Amount = er.convert(priceByCarrier.Amount, rounding)
+		priceByCarrier.Breakdown = er.convertBreakdown(priceByCarrier.Breakdown, rounding)
+		converted[i] = priceByCarrier
+	}
+
+	return converted
+}
+
+// convertParcelPrices returns a copy of the given parcel prices where the
+// converted total is shared proportionally to the original prices, so that
+// they still add up to it.
+func (er *ExchangeRate) convertParcelPrices(parcelPrices []ParcelPrice, total Money) []ParcelPrice {
+	if er == nil {
+		return parcelPrices
+	}
+
+	weights := make([]float64, len(parcelPrices))
+	for i, parcelPrice := range parcelPrices {
+		weights[i] = float64(parcelPrice.Amount.Amount)
+	}
+
+	converted := make([]ParcelPrice, len(parcelPrices))
+	for i, amount := range allocateProportionally(total.Amount, weights) {
+		converted[i] = parcelPrices[i]
+		converted[i].Amount = NewMoney(amount, er.To)
+	}
+
+	return converted
+}
+
+// exchangeRate returns the ExchangeRate used to convert prices from the currency
+// of the pricing rules to the requested one; nil is returned when no conversion
+// is needed.
+func (s *Service) exchangeRate(rules *PricingRules, currency string) (*ExchangeRate, error) {
+	if currency == "" || currency == rules.Currency {
+		return nil, nil
+	}
+
+	if !IsValidCurrency(currency) {
+		return nil, invalidArgs(fmt.Errorf("%w %q", errInvalidCurrency, currency))
+	}
+
+	if s.exchangeRateProvider == nil {
+		return nil, invalidArgs(errExchangeRatesNotAvailable)
+	}
+
+	exchangeRate, err := s.exchangeRateProvider.GetExchangeRate(rules.Currency, currency)
+	if err != nil {
+		// no exchange rate is available for the currency
+		return nil, invalidArgs(err)
+	}
+
+	return exchangeRate, nil
+}
+
+// carrierServiceInCurrency returns a copy of the given CarrierService having its
+// markup, and the components of the markup, converted to the currency of the
+// pricing rules; an error is returned when no exchange rate is available.
+func (s *Service) carrierServiceInCurrency(rules *PricingRules, carrierService CarrierService) (CarrierService, error) {
+	if carrierService.Markup.Currency == rules.Currency {
+		return carrierService, nil
+	}
+
+	if s.exchangeRateProvider == nil {
+		return carrierService, errExchangeRatesNotAvailable
+	}
+
+	exchangeRate, err := s.exchangeRateProvider.GetExchangeRate(carrierService.Markup.Currency, rules.Currency)
+	if err != nil {
+		return carrierService, err
+	}
+
+	basePrice, _ := carrierService.markupComponents()
+
+	// the service markup is what remains of the converted markup, so that the
+	// components still add up to it
+	carrierService.Markup = exchangeRate.convert(carrierService.Markup, rules.Rounding)
+	carrierService.BasePrice = exchangeRate.convert(basePrice, rules.Rounding)
+	carrierService.ServiceMarkup = NewMoney(carrierService.Markup.Amount-carrierService.BasePrice.Amount, rules.Currency)
+
+	return carrierService, nil
+}
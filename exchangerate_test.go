@@ -0,0 +1,304 @@
+package carrierpricing
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExchangeRateConvert(t *testing.T) {
+	tests := []struct {
+		ExchangeRate   *ExchangeRate
+		Amount         Money
+		ExpectedResult Money
+	}{
+		// case #1 no conversion
+		{nil, gbp(348), gbp(348)},
+		// case #2 same minor unit
+		{&ExchangeRate{From: "GBP", To: "EUR", Rate: 1.1712}, gbp(348), NewMoney(408, "EUR")},
+		// case #3 currency without minor unit: 3.48 GBP are 687.7872 JPY
+		{&ExchangeRate{From: "GBP", To: "JPY", Rate: 197.64}, gbp(348), NewMoney(688, "JPY")},
+		// case #4 from a currency without minor unit: 688 JPY are 3.4811... GBP
+		{&ExchangeRate{From: "JPY", To: "GBP", Rate: 0.00506}, NewMoney(688, "JPY"), gbp(348)},
+	}
+
+	for i, tc := range tests {
+		result := tc.ExchangeRate.convert(tc.Amount, RoundingModeHalfEven)
+		if result != tc.ExpectedResult {
+			t.Fatalf("case #%d: expected %v, received: %v", i+1, tc.ExpectedResult, result)
+		}
+	}
+}
+
+func TestExchangeRateConvertBreakdown(t *testing.T) {
+	// tests that converted items still add up to the converted total
+	exchangeRate := &ExchangeRate{From: "GBP", To: "EUR", Rate: 1.5}
+
+	breakdown := &PriceBreakdown{
+		BasePrice:          gbp(315),
+		VehicleMultiplier:  1.3,
+		VehicleMarkup:      gbp(95),
+		CarrierBasePrice:   gbp(15),
+		ServiceMarkup:      gbp(5),
+		Surcharges:         []PriceAdjustment{PriceAdjustment{Description: "minimum charge", Amount: gbp(1)}},
+		Discounts:          []PriceAdjustment{},
+		RoundingAdjustment: gbp(0),
+		Total:              gbp(431),
+	}
+
+	expectedBreakdown := &PriceBreakdown{
+		BasePrice:          NewMoney(472, "EUR"),
+		VehicleMultiplier:  1.3,
+		VehicleMarkup:      NewMoney(142, "EUR"),
+		CarrierBasePrice:   NewMoney(22, "EUR"),
+		ServiceMarkup:      NewMoney(8, "EUR"),
+		Surcharges:         []PriceAdjustment{PriceAdjustment{Description: "minimum charge", Amount: NewMoney(2, "EUR")}},
+		Discounts:          []PriceAdjustment{},
+		RoundingAdjustment: NewMoney(0, "EUR"),
+		Total:              NewMoney(646, "EUR"),
+	}
+
+	result := exchangeRate.convertBreakdown(breakdown, RoundingModeHalfEven)
+	if !reflect.DeepEqual(expectedBreakdown, result) {
+		t.Fatalf("expected breakdown '%+v', received: '%+v'", expectedBreakdown, result)
+	}
+
+	if breakdown.Surcharges[0].Amount != gbp(1) {
+		t.Fatal("expected the original breakdown not to be modified")
+	}
+}
+
+func TestGetQuotesByVehicleInCurrency(t *testing.T) {
+	logger := log.New(ioutil.Discard, "", 0)
+	asOf := time.Date(2026, time.October, 15, 16, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		Options        []ServiceOption
+		Currency       string
+		ExpectedResult *GetQuotesByVehicleResponse
+		ExpectedError  error
+	}{
+		// case #1 prices converted to the requested currency
+		{
+			Options:  []ServiceOption{WithExchangeRateProvider(&mockExchangeRateProvider{asOf: asOf})},
+			Currency: "EUR",
+			ExpectedResult: &GetQuotesByVehicleResponse{
+				PickupPostcode:   "SW1A 1AA",
+				DeliveryPostcode: "EC2A 3LT",
+				Vehicle:          VehicleTypeSmallVan,
+				Price:            NewMoney(616, "EUR"),
+				TaxedAmounts: TaxedAmounts{
+					Net:   NewMoney(616, "EUR"),
+					Tax:   NewMoney(123, "EUR"),
+					Gross: NewMoney(739, "EUR"),
+				},
+				TaxJurisdiction: JurisdictionGreatBritain,
+				TaxRate:         0.2,
+				ExchangeRate:    &ExchangeRate{From: "GBP", To: "EUR", Rate: 1.5, AsOf: asOf},
+			},
+			ExpectedError: nil,
+		},
+		// case #2 requesting the currency of the pricing rules needs no conversion
+		{
+			Options:  nil,
+			Currency: "GBP",
+			ExpectedResult: &GetQuotesByVehicleResponse{
+				PickupPostcode:   "SW1A 1AA",
+				DeliveryPostcode: "EC2A 3LT",
+				Vehicle:          VehicleTypeSmallVan,
+				Price:            gbp(411),
+				TaxedAmounts:     taxedGBP(411, 82),
+				TaxJurisdiction:  JurisdictionGreatBritain,
+				TaxRate:          0.2,
+			},
+			ExpectedError: nil,
+		},
+		// case #3 invalid currency
+		{
+			Options:        []ServiceOption{WithExchangeRateProvider(&mockExchangeRateProvider{asOf: asOf})},
+			Currency:       "XYZ",
+			ExpectedResult: nil,
+			ExpectedError:  errors.New("invalid currency provided \"XYZ\""),
+		},
+		// case #4 no exchange rate provider
+		{
+			Options:        nil,
+			Currency:       "EUR",
+			ExpectedResult: nil,
+			ExpectedError:  errExchangeRatesNotAvailable,
+		},
+		// case #5 no rate available for the currency
+		{
+			Options:        []ServiceOption{WithExchangeRateProvider(&mockExchangeRateProvider{asOf: asOf})},
+			Currency:       "USD",
+			ExpectedResult: nil,
+			ExpectedError:  errors.New("no exchange rate available for USD"),
+		},
+	}
+
+	for i, tc := range tests {
+		service, err := NewService(logger, &mockCarrierServiceFinder{}, &mockDistanceCalculator{}, nil, tc.Options...)
+		if err != nil {
+			t.Fatalf("NewService returned error %v", err)
+		}
+
+		result, err := service.GetQuotesByVehicle(context.Background(), GetQuotesByVehicleArgs{
+			PickupPostcode:   "SW1A1AA",
+			DeliveryPostcode: "EC2A3LT",
+			Vehicle:          VehicleTypeSmallVan,
+			Currency:         tc.Currency,
+		})
+		if (tc.ExpectedError != nil && err == nil) ||
+			(tc.ExpectedError == nil && err != nil) ||
+			(tc.ExpectedError != nil && err != nil && tc.ExpectedError.Error() != err.Error()) {
+			t.Fatalf("case #%d: expected error '%v', received: '%v'", i+1, tc.ExpectedError, err)
+		}
+		if !reflect.DeepEqual(tc.ExpectedResult, result) {
+			t.Fatalf("case #%d: expected result '%+v', received: '%+v'", i+1, tc.ExpectedResult, result)
+		}
+	}
+}
+
+func TestGetShipmentQuoteInCurrency(t *testing.T) {
+	// tests that converted parcel prices still add up to the converted price
+	logger := log.New(ioutil.Discard, "", 0)
+	service, err := NewService(logger, &mockCarrierServiceFinder{}, &mockDistanceCalculator{}, nil,
+		WithExchangeRateProvider(&mockExchangeRateProvider{}))
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
+
+	result, err := service.GetShipmentQuote(context.Background(), GetShipmentQuoteArgs{
+		PickupPostcode:   "SW1A1AA",
+		DeliveryPostcode: "EC2A3LT",
+		Vehicle:          VehicleTypeSmallVan,
+		Parcels:          []Parcel{Parcel{WeightKg: 1}, Parcel{WeightKg: 2}, Parcel{WeightKg: 3}},
+		Currency:         "EUR",
+	})
+	if err != nil {
+		t.Fatalf("GetShipmentQuote returned error %v", err)
+	}
+
+	var sum int64
+	for _, parcelPrice := range result.Parcels {
+		if parcelPrice.Amount.Currency != "EUR" {
+			t.Fatalf("expected parcel price in EUR, received: %v", parcelPrice.Amount)
+		}
+		sum += parcelPrice.Amount.Amount
+	}
+	if result.Price.Currency != "EUR" || sum != result.Price.Amount {
+		t.Fatalf("expected parcel prices to add up to %v, received: %d", result.Price, sum)
+	}
+
+	for _, priceByCarrier := range result.PriceList {
+		if priceByCarrier.Amount.Currency != "EUR" {
+			t.Fatalf("expected carrier price in EUR, received: %v", priceByCarrier.Amount)
+		}
+	}
+}
+
+func TestGetQuotesByCarrierWithMarkupInOtherCurrency(t *testing.T) {
+	// tests that carrier services with a markup in a currency other than the
+	// one of the pricing rules are converted, and skipped only without rates
+	logger := log.New(ioutil.Discard, "", 0)
+	carrierServices := &mockCarrierServiceList{
+		CarrierService{Name: "MockService1", Markup: gbp(20), DeliveryTime: workingDays(1)},
+		CarrierService{
+			Name:          "MockEuroService",
+			Markup:        NewMoney(55, "EUR"),
+			BasePrice:     NewMoney(25, "EUR"),
+			ServiceMarkup: NewMoney(30, "EUR"),
+			DeliveryTime:  workingDays(2),
+		},
+	}
+
+	tests := []struct {
+		Options           []ServiceOption
+		ExpectedCarriers  []string
+		ExpectedPrices    []Money
+		ExpectedBreakdown *PriceBreakdown
+	}{
+		// case #1 markup converted via the exchange rate provider: 0.55 EUR are 0.44 GBP
+		{
+			Options:          []ServiceOption{WithExchangeRateProvider(&mockExchangeRateProvider{})},
+			ExpectedCarriers: []string{"MockService1", "MockEuroService"},
+			ExpectedPrices:   []Money{gbp(431), gbp(455)},
+			ExpectedBreakdown: &PriceBreakdown{
+				BasePrice:          gbp(316),
+				VehicleMultiplier:  1.3,
+				VehicleMarkup:      gbp(95),
+				CarrierBasePrice:   gbp(20),
+				ServiceMarkup:      gbp(24),
+				Surcharges:         []PriceAdjustment{},
+				Discounts:          []PriceAdjustment{},
+				RoundingAdjustment: gbp(0),
+				Total:              gbp(455),
+			},
+		},
+		// case #2 no exchange rate provider, the service cannot be quoted
+		{
+			Options:          nil,
+			ExpectedCarriers: []string{"MockService1"},
+			ExpectedPrices:   []Money{gbp(431)},
+		},
+	}
+
+	for i, tc := range tests {
+		service, err := NewService(logger, carrierServices, &mockDistanceCalculator{}, nil, tc.Options...)
+		if err != nil {
+			t.Fatalf("NewService returned error %v", err)
+		}
+
+		result, err := service.GetQuotesByCarrier(context.Background(), GetQuotesByCarrierArgs{
+			PickupPostcode:   "SW1A1AA",
+			DeliveryPostcode: "EC2A3LT",
+			Vehicle:          VehicleTypeSmallVan,
+			IncludeBreakdown: true,
+		})
+		if err != nil {
+			t.Fatalf("case #%d: GetQuotesByCarrier returned error %v", i+1, err)
+		}
+
+		carriers := []string{}
+		prices := []Money{}
+		for _, priceByCarrier := range result.PriceList {
+			carriers = append(carriers, priceByCarrier.CarrierName)
+			prices = append(prices, priceByCarrier.Amount)
+		}
+		if !reflect.DeepEqual(tc.ExpectedCarriers, carriers) || !reflect.DeepEqual(tc.ExpectedPrices, prices) {
+			t.Fatalf("case #%d: expected carriers %v with prices %v, received: %v with prices %v", i+1, tc.ExpectedCarriers, tc.ExpectedPrices, carriers, prices)
+		}
+
+		if tc.ExpectedBreakdown != nil && !reflect.DeepEqual(tc.ExpectedBreakdown, result.PriceList[1].Breakdown) {
+			t.Fatalf("case #%d: expected breakdown '%+v', received: '%+v'", i+1, tc.ExpectedBreakdown, result.PriceList[1].Breakdown)
+		}
+	}
+}
+
+// UTILS
+
+// mockExchangeRateProvider converts GBP to EUR, at a rate of 1.5, and EUR to
+// GBP, at a rate of 0.8.
+type mockExchangeRateProvider struct {
+	asOf time.Time
+}
+
+func (merp *mockExchangeRateProvider) GetExchangeRate(from, to string) (*ExchangeRate, error) {
+	switch {
+	case from == CurrencyGBP && to == "EUR":
+		return &ExchangeRate{From: from, To: to, Rate: 1.5, AsOf: merp.asOf}, nil
+	case from == "EUR" && to == CurrencyGBP:
+		return &ExchangeRate{From: from, To: to, Rate: 0.8, AsOf: merp.asOf}, nil
+	}
+	return nil, errors.New("no exchange rate available for " + to)
+}
+
+type mockCarrierServiceList []CarrierService
+
+func (mcsl *mockCarrierServiceList) FindCarrierServicesForVehicle(vehicleType string) []CarrierService {
+	return append([]CarrierService{}, (*mcsl)...)
+}
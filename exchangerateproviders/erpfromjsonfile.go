@@ -0,0 +1,110 @@
+package exchangerateproviders
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"time"
+
+	"github.com/giefferre/carrierpricing"
+)
+
+// ErrUnknownCurrency is returned when no exchange rate is available for the
+// requested currency.
+var ErrUnknownCurrency = errors.New("no exchange rate available for the given currency")
+
+// ERPFromJSONFile implements the carrierpricing.ExchangeRateProvider interface;
+// the source of data is a single JSON encoded file from local storage containing
+// the rates of several currencies against a base one, as of a given time.
+// Rates between two non-base currencies are derived from their base rates.
+type ERPFromJSONFile struct {
+	base  string
+	asOf  time.Time
+	rates map[string]float64
+}
+
+// NewERPFromJSONFile returns a fresh ERPFromJSONFile object having the rates
+// loaded in memory. The file must contain an object in the following format:
+//
+//	{"base": "GBP", "as_of": "2026-10-15T16:00:00Z", "rates": {"EUR": 1.1712}}
+//
+// where each rate is the amount of the currency worth one unit of the base one.
+// An error is returned if the file is not found or it does not contain valid rates.
+func NewERPFromJSONFile(jsonFilePath string) (*ERPFromJSONFile, error) {
+	jsonFileContent, err := ioutil.ReadFile(jsonFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	content := exchangeRates{}
+	err = json.Unmarshal(jsonFileContent, &content)
+	if err != nil {
+		return nil, err
+	}
+
+	if !carrierpricing.IsValidCurrency(content.Base) {
+		return nil, fmt.Errorf("invalid base currency %q", content.Base)
+	}
+
+	if content.AsOf.IsZero() {
+		return nil, errors.New("as_of must be provided")
+	}
+
+	for currency, rate := range content.Rates {
+		if !carrierpricing.IsValidCurrency(currency) {
+			return nil, fmt.Errorf("invalid currency %q", currency)
+		}
+		if rate <= 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+			return nil, fmt.Errorf("rate for %s must be a positive number", currency)
+		}
+	}
+
+	return &ERPFromJSONFile{
+		base:  content.Base,
+		asOf:  content.AsOf,
+		rates: content.Rates,
+	}, nil
+}
+
+// GetExchangeRate returns the rate to convert amounts from one currency to the other.
+func (erp *ERPFromJSONFile) GetExchangeRate(from, to string) (*carrierpricing.ExchangeRate, error) {
+	fromRate, err := erp.rateAgainstBase(from)
+	if err != nil {
+		return nil, err
+	}
+
+	toRate, err := erp.rateAgainstBase(to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &carrierpricing.ExchangeRate{
+		From: from,
+		To:   to,
+		Rate: toRate / fromRate,
+		AsOf: erp.asOf,
+	}, nil
+}
+
+// rateAgainstBase returns the amount of the given currency worth one unit of
+// the base currency.
+func (erp *ERPFromJSONFile) rateAgainstBase(currency string) (float64, error) {
+	if currency == erp.base {
+		return 1, nil
+	}
+
+	rate, exists := erp.rates[currency]
+	if !exists {
+		return 0, fmt.Errorf("%s: %w", currency, ErrUnknownCurrency)
+	}
+
+	return rate, nil
+}
+
+type exchangeRates struct {
+	Base  string             `json:"base"`
+	AsOf  time.Time          `json:"as_of"`
+	Rates map[string]float64 `json:"rates"`
+}
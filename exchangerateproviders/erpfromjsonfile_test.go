@@ -0,0 +1,158 @@
+package exchangerateproviders
+
+import (
+	"errors"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestERPFromJSONFile(t *testing.T) {
+	tests := []struct {
+		From          string
+		To            string
+		ExpectedRate  float64
+		ExpectedError error
+	}{
+		// case #1 from the base currency
+		{
+			From:         "GBP",
+			To:           "EUR",
+			ExpectedRate: 1.2,
+		},
+		// case #2 to the base currency
+		{
+			From:         "EUR",
+			To:           "GBP",
+			ExpectedRate: 1 / 1.2,
+		},
+		// case #3 between two non-base currencies, derived from their base rates
+		{
+			From:         "EUR",
+			To:           "USD",
+			ExpectedRate: 1.5 / 1.2,
+		},
+		// case #4 same currency
+		{
+			From:         "USD",
+			To:           "USD",
+			ExpectedRate: 1,
+		},
+		// case #5 unknown target currency
+		{
+			From:          "GBP",
+			To:            "JPY",
+			ExpectedError: ErrUnknownCurrency,
+		},
+		// case #6 unknown source currency
+		{
+			From:          "CHF",
+			To:            "EUR",
+			ExpectedError: ErrUnknownCurrency,
+		},
+	}
+
+	directory, err := ioutil.TempDir("", "erpfromjsonfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	jsonFilePath := filepath.Join(directory, "exchange_rates.json")
+	err = ioutil.WriteFile(jsonFilePath, []byte(`{"base": "GBP", "as_of": "2026-10-15T16:00:00Z", "rates": {"EUR": 1.2, "USD": 1.5}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	erp, err := NewERPFromJSONFile(jsonFilePath)
+	if err != nil {
+		t.Fatalf("NewERPFromJSONFile returned error %v", err)
+	}
+
+	expectedAsOf := time.Date(2026, time.October, 15, 16, 0, 0, 0, time.UTC)
+
+	for i, tc := range tests {
+		exchangeRate, err := erp.GetExchangeRate(tc.From, tc.To)
+
+		if tc.ExpectedError != nil {
+			if !errors.Is(err, tc.ExpectedError) {
+				t.Fatalf("case #%d: expected error '%v', received: '%v'", i+1, tc.ExpectedError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("case #%d: unexpected error %v", i+1, err)
+		}
+
+		if exchangeRate.From != tc.From || exchangeRate.To != tc.To || !exchangeRate.AsOf.Equal(expectedAsOf) {
+			t.Fatalf("case #%d: expected rate from %s to %s as of %v, received: %+v", i+1, tc.From, tc.To, expectedAsOf, exchangeRate)
+		}
+		if math.Abs(exchangeRate.Rate-tc.ExpectedRate) > 1e-9 {
+			t.Fatalf("case #%d: expected rate %v, received: %v", i+1, tc.ExpectedRate, exchangeRate.Rate)
+		}
+	}
+}
+
+func TestNewERPFromJSONFile(t *testing.T) {
+	tests := []struct {
+		Content       string
+		ExpectedError string
+	}{
+		// case #1 valid rates
+		{
+			Content: `{"base": "GBP", "as_of": "2026-10-15T16:00:00Z", "rates": {"EUR": 1.1712, "JPY": 197.64}}`,
+		},
+		// case #2 invalid base currency
+		{
+			Content:       `{"base": "XYZ", "as_of": "2026-10-15T16:00:00Z", "rates": {"EUR": 1.1712}}`,
+			ExpectedError: `invalid base currency "XYZ"`,
+		},
+		// case #3 missing as_of
+		{
+			Content:       `{"base": "GBP", "rates": {"EUR": 1.1712}}`,
+			ExpectedError: "as_of must be provided",
+		},
+		// case #4 invalid currency
+		{
+			Content:       `{"base": "GBP", "as_of": "2026-10-15T16:00:00Z", "rates": {"EURO": 1.1712}}`,
+			ExpectedError: `invalid currency "EURO"`,
+		},
+		// case #5 zero rate
+		{
+			Content:       `{"base": "GBP", "as_of": "2026-10-15T16:00:00Z", "rates": {"EUR": 0}}`,
+			ExpectedError: "rate for EUR must be a positive number",
+		},
+		// case #6 negative rate
+		{
+			Content:       `{"base": "GBP", "as_of": "2026-10-15T16:00:00Z", "rates": {"USD": -1.27}}`,
+			ExpectedError: "rate for USD must be a positive number",
+		},
+	}
+
+	directory, err := ioutil.TempDir("", "erpfromjsonfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	for i, tc := range tests {
+		jsonFilePath := filepath.Join(directory, "exchange_rates.json")
+		err = ioutil.WriteFile(jsonFilePath, []byte(tc.Content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = NewERPFromJSONFile(jsonFilePath)
+		if (err == nil && tc.ExpectedError != "") || (err != nil && err.Error() != tc.ExpectedError) {
+			t.Fatalf("case #%d: expected error '%s', received: '%v'", i+1, tc.ExpectedError, err)
+		}
+	}
+
+	_, err = NewERPFromJSONFile(filepath.Join(directory, "missing.json"))
+	if err == nil {
+		t.Fatal("expected an error loading a missing file")
+	}
+}
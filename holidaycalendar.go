@@ -0,0 +1,13 @@
+package carrierpricing
+
+import (
+	"time"
+
+	"github.com/giefferre/carrierpricing/postcode"
+)
+
+// HolidayCalendar is a software service used to know whether the given day,
+// in UK local time, is a bank holiday where the given postcode is located.
+type HolidayCalendar interface {
+	IsBankHoliday(day time.Time, location postcode.Postcode) bool
+}
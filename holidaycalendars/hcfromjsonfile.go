@@ -0,0 +1,92 @@
+package holidaycalendars
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/giefferre/carrierpricing/postcode"
+)
+
+const (
+	divisionEnglandAndWales = "england-and-wales"
+	divisionScotland        = "scotland"
+	divisionNorthernIreland = "northern-ireland"
+)
+
+// scottishAreas lists the postcode areas located in Scotland.
+var scottishAreas = map[string]bool{
+	"AB": true, "DD": true, "DG": true, "EH": true, "FK": true, "G": true,
+	"HS": true, "IV": true, "KA": true, "KW": true, "KY": true, "ML": true,
+	"PA": true, "PH": true, "TD": true, "ZE": true,
+}
+
+// HCFromJSONFile implements the carrierpricing.HolidayCalendar interface;
+// the source of data is a JSON encoded file from local storage in the same
+// format published at https://www.gov.uk/bank-holidays.json, listing the bank
+// holidays of England and Wales, Scotland and Northern Ireland.
+// Postcodes outside of the three divisions (e.g. the Channel Islands) follow
+// the bank holidays of England and Wales.
+type HCFromJSONFile struct {
+	divisions map[string]map[string]bool
+}
+
+// NewHCFromJSONFile returns a fresh HCFromJSONFile object having the bank holidays
+// loaded in memory. An error is returned if the file is not found or it does
+// not contain valid dates.
+func NewHCFromJSONFile(jsonFilePath string) (*HCFromJSONFile, error) {
+	jsonFileContent, err := ioutil.ReadFile(jsonFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	content := map[string]division{}
+	err = json.Unmarshal(jsonFileContent, &content)
+	if err != nil {
+		return nil, err
+	}
+
+	divisions := map[string]map[string]bool{}
+	for name, division := range content {
+		days := map[string]bool{}
+		for _, event := range division.Events {
+			if _, err := time.Parse("2006-01-02", event.Date); err != nil {
+				return nil, fmt.Errorf("invalid date for %s in %s: %v", event.Title, name, err)
+			}
+			days[event.Date] = true
+		}
+		divisions[name] = days
+	}
+
+	return &HCFromJSONFile{
+		divisions: divisions,
+	}, nil
+}
+
+// IsBankHoliday returns true if the given day is a bank holiday in the division
+// the given postcode is located in.
+func (hc *HCFromJSONFile) IsBankHoliday(day time.Time, location postcode.Postcode) bool {
+	return hc.divisions[divisionOf(location)][day.Format("2006-01-02")]
+}
+
+// divisionOf returns the division of the bank holidays calendar the given
+// postcode is located in.
+func divisionOf(location postcode.Postcode) string {
+	if location.Area == "BT" {
+		return divisionNorthernIreland
+	}
+	if scottishAreas[location.Area] {
+		return divisionScotland
+	}
+	return divisionEnglandAndWales
+}
+
+type division struct {
+	Events []event `json:"events"`
+}
+
+type event struct {
+	Title string `json:"title"`
+	Date  string `json:"date"`
+}
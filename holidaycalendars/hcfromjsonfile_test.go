@@ -0,0 +1,165 @@
+package holidaycalendars
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/giefferre/carrierpricing/postcode"
+)
+
+const bankHolidaysFixture = `{
+    "england-and-wales": {
+        "division": "england-and-wales",
+        "events": [
+            {"title": "New Year’s Day", "date": "2026-01-01"},
+            {"title": "Easter Monday", "date": "2026-04-06"},
+            {"title": "Summer bank holiday", "date": "2026-08-31"}
+        ]
+    },
+    "scotland": {
+        "division": "scotland",
+        "events": [
+            {"title": "New Year’s Day", "date": "2026-01-01"},
+            {"title": "2nd January", "date": "2026-01-02"},
+            {"title": "Summer bank holiday", "date": "2026-08-03"},
+            {"title": "St Andrew’s Day (substitute day)", "date": "2026-11-30"}
+        ]
+    },
+    "northern-ireland": {
+        "division": "northern-ireland",
+        "events": [
+            {"title": "New Year’s Day", "date": "2026-01-01"},
+            {"title": "St Patrick’s Day", "date": "2026-03-17"},
+            {"title": "Easter Monday", "date": "2026-04-06"},
+            {"title": "Battle of the Boyne (Orangemen’s Day) (substitute day)", "date": "2026-07-13"},
+            {"title": "Summer bank holiday", "date": "2026-08-31"}
+        ]
+    }
+}`
+
+func TestHCFromJSONFile(t *testing.T) {
+	tests := []struct {
+		Day                   string
+		Location              string
+		ExpectedIsBankHoliday bool
+	}{
+		// case #1 bank holiday everywhere
+		{Day: "2026-01-01", Location: "SW1A 1AA", ExpectedIsBankHoliday: true},
+		// case #2 bank holiday everywhere, in Scotland
+		{Day: "2026-01-01", Location: "EH1 1YZ", ExpectedIsBankHoliday: true},
+		// case #3 bank holiday everywhere, in Northern Ireland
+		{Day: "2026-01-01", Location: "BT1 5GS", ExpectedIsBankHoliday: true},
+		// case #4 Scottish bank holiday, in Scotland
+		{Day: "2026-01-02", Location: "EH1 1YZ", ExpectedIsBankHoliday: true},
+		// case #5 Scottish bank holiday, in a single letter Scottish area
+		{Day: "2026-11-30", Location: "G1 1XQ", ExpectedIsBankHoliday: true},
+		// case #6 Scottish bank holiday, in England
+		{Day: "2026-01-02", Location: "SW1A 1AA", ExpectedIsBankHoliday: false},
+		// case #7 Scottish bank holiday, in Northern Ireland
+		{Day: "2026-01-02", Location: "BT1 5GS", ExpectedIsBankHoliday: false},
+		// case #8 Northern Irish bank holiday, in Northern Ireland
+		{Day: "2026-03-17", Location: "BT1 5GS", ExpectedIsBankHoliday: true},
+		// case #9 Northern Irish bank holiday, in Scotland
+		{Day: "2026-07-13", Location: "AB10 1AA", ExpectedIsBankHoliday: false},
+		// case #10 English bank holiday, in Scotland
+		{Day: "2026-04-06", Location: "EH1 1YZ", ExpectedIsBankHoliday: false},
+		// cases #11 to #14 summer bank holiday, on different days in Scotland and in Wales
+		{Day: "2026-08-03", Location: "EH1 1YZ", ExpectedIsBankHoliday: true},
+		{Day: "2026-08-03", Location: "CF10 1EP", ExpectedIsBankHoliday: false},
+		{Day: "2026-08-31", Location: "CF10 1EP", ExpectedIsBankHoliday: true},
+		{Day: "2026-08-31", Location: "EH1 1YZ", ExpectedIsBankHoliday: false},
+		// case #15 Channel Islands following England and Wales
+		{Day: "2026-04-06", Location: "JE2 3AB", ExpectedIsBankHoliday: true},
+		// case #16 working day
+		{Day: "2026-10-15", Location: "SW1A 1AA", ExpectedIsBankHoliday: false},
+	}
+
+	directory, err := ioutil.TempDir("", "hcfromjsonfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	jsonFilePath := filepath.Join(directory, "bank_holidays.json")
+	err = ioutil.WriteFile(jsonFilePath, []byte(bankHolidaysFixture), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hc, err := NewHCFromJSONFile(jsonFilePath)
+	if err != nil {
+		t.Fatalf("NewHCFromJSONFile returned error %v", err)
+	}
+
+	for i, tc := range tests {
+		day, err := time.Parse("2006-01-02", tc.Day)
+		if err != nil {
+			t.Fatal(err)
+		}
+		location, err := postcode.Parse(tc.Location)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		isBankHoliday := hc.IsBankHoliday(day, *location)
+		if isBankHoliday != tc.ExpectedIsBankHoliday {
+			t.Fatalf("case #%d: expected bank holiday on %s in %s to be %t, received: %t", i+1, tc.Day, tc.Location, tc.ExpectedIsBankHoliday, isBankHoliday)
+		}
+	}
+}
+
+func TestNewHCFromJSONFile(t *testing.T) {
+	tests := []struct {
+		Content       string
+		ExpectedError bool
+	}{
+		// case #1 valid calendar
+		{
+			Content: bankHolidaysFixture,
+		},
+		// case #2 division without events
+		{
+			Content: `{"scotland": {"division": "scotland", "events": []}}`,
+		},
+		// case #3 invalid date
+		{
+			Content:       `{"scotland": {"division": "scotland", "events": [{"title": "2nd January", "date": "02/01/2026"}]}}`,
+			ExpectedError: true,
+		},
+		// case #4 not a calendar
+		{
+			Content:       `[{"title": "2nd January", "date": "2026-01-02"}]`,
+			ExpectedError: true,
+		},
+	}
+
+	directory, err := ioutil.TempDir("", "hcfromjsonfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	for i, tc := range tests {
+		jsonFilePath := filepath.Join(directory, "bank_holidays.json")
+		err = ioutil.WriteFile(jsonFilePath, []byte(tc.Content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = NewHCFromJSONFile(jsonFilePath)
+		if tc.ExpectedError && err == nil {
+			t.Fatalf("case #%d: expected an error", i+1)
+		}
+		if !tc.ExpectedError && err != nil {
+			t.Fatalf("case #%d: unexpected error %v", i+1, err)
+		}
+	}
+
+	_, err = NewHCFromJSONFile(filepath.Join(directory, "missing.json"))
+	if err == nil {
+		t.Fatal("expected an error loading a missing file")
+	}
+}
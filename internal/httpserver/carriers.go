@@ -0,0 +1,279 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/giefferre/carrierpricing"
+)
+
+// carriersHandler serves GET /carriers, returning all the carriers, and
+// POST /carriers, creating the carrier in the body.
+func (s *HTTPServer) carriersHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		responseObject, err := s.service.ListCarriers()
+		if err != nil {
+			s.writeCarrierError(w, err)
+			return
+		}
+
+		writeResponse(w, responseObject)
+
+	case http.MethodPost:
+		// decode the request into a CarrierDefinition object
+		requestObject := &carrierpricing.CarrierDefinition{}
+
+		err := decodeRequestBodyStrictly(r.Body, requestObject)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		responseObject, err := s.service.CreateCarrier(*requestObject)
+		if err != nil {
+			s.writeCarrierError(w, err)
+			return
+		}
+
+		w.Header().Set("Location", carrierPath(responseObject.Name))
+		writeCarrier(w, http.StatusCreated, responseObject)
+
+	default:
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// carrierHandler serves GET, PUT and DELETE /carriers/{name}, returning,
+// replacing and deleting the carrier, POST /carriers/{name}/services, adding
+// the service in the body to the carrier, and PUT and DELETE
+// /carriers/{name}/services/{index}, replacing and deleting the service at the
+// given position. Changes require the If-Match header to hold the current
+// ETag of the carrier, returned by all the requests: 428 is returned when
+// missing, 412 when the carrier has been changed in the meantime.
+func (s *HTTPServer) carrierHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.TrimPrefix(r.URL.Path, "/carriers/"), "/")
+	name := path[0]
+
+	switch {
+	case len(path) == 1:
+		s.serveCarrier(w, r, name)
+	case len(path) == 2 && path[1] == "services":
+		s.serveCarrierServices(w, r, name)
+	case len(path) == 3 && path[1] == "services":
+		serviceIndex, err := strconv.Atoi(path[2])
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		s.serveCarrierService(w, r, name, serviceIndex)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *HTTPServer) serveCarrier(w http.ResponseWriter, r *http.Request, name string) {
+	switch r.Method {
+	case http.MethodGet:
+		responseObject, err := s.service.GetCarrier(name)
+		if err != nil {
+			s.writeCarrierError(w, err)
+			return
+		}
+
+		writeCarrier(w, http.StatusOK, responseObject)
+
+	case http.MethodPut:
+		etag, ok := requireIfMatch(w, r)
+		if !ok {
+			return
+		}
+
+		// decode the request into a CarrierDefinition object
+		requestObject := &carrierpricing.CarrierDefinition{}
+
+		err := decodeRequestBodyStrictly(r.Body, requestObject)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		responseObject, err := s.service.UpdateCarrier(carrierpricing.UpdateCarrierArgs{
+			CarrierName: name,
+			ETag:        etag,
+			Carrier:     *requestObject,
+		})
+		if err != nil {
+			s.writeCarrierError(w, err)
+			return
+		}
+
+		writeCarrier(w, http.StatusOK, responseObject)
+
+	case http.MethodDelete:
+		etag, ok := requireIfMatch(w, r)
+		if !ok {
+			return
+		}
+
+		err := s.service.DeleteCarrier(carrierpricing.DeleteCarrierArgs{
+			CarrierName: name,
+			ETag:        etag,
+		})
+		if err != nil {
+			s.writeCarrierError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", strings.Join([]string{http.MethodGet, http.MethodPut, http.MethodDelete}, ", "))
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *HTTPServer) serveCarrierServices(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	etag, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	// decode the request into a ServiceDefinition object
+	requestObject := &carrierpricing.ServiceDefinition{}
+
+	err := decodeRequestBodyStrictly(r.Body, requestObject)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	responseObject, err := s.service.AddCarrierService(carrierpricing.CarrierServiceArgs{
+		CarrierName: name,
+		ETag:        etag,
+		Service:     *requestObject,
+	})
+	if err != nil {
+		s.writeCarrierError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s/services/%d", carrierPath(responseObject.Name), len(responseObject.Services)-1))
+	writeCarrier(w, http.StatusCreated, responseObject)
+}
+
+func (s *HTTPServer) serveCarrierService(w http.ResponseWriter, r *http.Request, name string, serviceIndex int) {
+	if r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		w.Header().Set("Allow", http.MethodPut+", "+http.MethodDelete)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	etag, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	args := carrierpricing.CarrierServiceArgs{
+		CarrierName:  name,
+		ETag:         etag,
+		ServiceIndex: serviceIndex,
+	}
+
+	var (
+		responseObject *carrierpricing.CarrierDefinition
+		err            error
+	)
+
+	if r.Method == http.MethodPut {
+		err = decodeRequestBodyStrictly(r.Body, &args.Service)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		responseObject, err = s.service.UpdateCarrierService(args)
+	} else {
+		responseObject, err = s.service.DeleteCarrierService(args)
+	}
+
+	if err != nil {
+		s.writeCarrierError(w, err)
+		return
+	}
+
+	writeCarrier(w, http.StatusOK, responseObject)
+}
+
+// writeCarrierError writes the given error returned by the carrier methods of
+// the service, with its HTTP status code; unexpected errors are only logged.
+func (s *HTTPServer) writeCarrierError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, carrierpricing.ErrCarrierNotFound), errors.Is(err, carrierpricing.ErrCarrierServiceNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, carrierpricing.ErrCarrierAlreadyExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, carrierpricing.ErrCarrierModified):
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+	case errors.Is(err, carrierpricing.ErrInvalidCarrier):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		s.logger.Println(err)
+		http.Error(w, errMessageInternalServerError, http.StatusInternalServerError)
+	}
+}
+
+// requireIfMatch returns the ETag of the If-Match header of the request; when
+// missing, 428 is written and false is returned.
+func requireIfMatch(w http.ResponseWriter, r *http.Request) (string, bool) {
+	etag := r.Header.Get("If-Match")
+	if etag == "" {
+		http.Error(w, "the If-Match header must hold the ETag of the carrier", http.StatusPreconditionRequired)
+		return "", false
+	}
+	return etag, true
+}
+
+// writeCarrier writes the given carrier with the given status code, together
+// with its ETag.
+func writeCarrier(w http.ResponseWriter, statusCode int, carrier *carrierpricing.CarrierDefinition) {
+	responseDataAsBytes, err := json.Marshal(carrier)
+	if err != nil {
+		http.Error(w, errMessageInternalServerError, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", carrier.ETag())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(responseDataAsBytes)
+}
+
+// carrierPath returns the path of the carrier with the given name.
+func carrierPath(name string) string {
+	return "/carriers/" + url.PathEscape(name)
+}
+
+// decodeRequestBodyStrictly decodes the HTTP request body into the given
+// requestObject as decodeRequestBodyAsRequestObject does, but rejecting unknown
+// fields, so that misspelled ones are not silently ignored.
+func decodeRequestBodyStrictly(requestBody io.ReadCloser, requestObject interface{}) error {
+	defer requestBody.Close()
+
+	decoder := json.NewDecoder(requestBody)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(requestObject)
+}
@@ -1,12 +1,15 @@
 package httpserver
 
 import (
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/giefferre/carrierpricing"
 )
@@ -17,26 +20,66 @@ const (
 
 // HTTPServer implements an HTTP REST API server
 type HTTPServer struct {
-	logger  *log.Logger
-	service carrierpricing.ServiceInterface
+	logger           *log.Logger
+	service          carrierpricing.ServiceInterface
+	carriersAPIToken string
+	bookingsAPIToken string
+}
+
+// HTTPServerOption configures an optional feature of the HTTPServer.
+type HTTPServerOption func(s *HTTPServer)
+
+// WithCarriersAPIToken enables the /carriers endpoints, used to manage carriers
+// and their services, to the requests authenticated with the given bearer
+// token; without it, such endpoints are not served.
+func WithCarriersAPIToken(token string) HTTPServerOption {
+	return func(s *HTTPServer) {
+		s.carriersAPIToken = token
+	}
+}
+
+// WithBookingsAPIToken enables POST /bookings/{id}/status, used by operations
+// to move bookings through their lifecycle, to the requests authenticated with
+// the given bearer token; without it, such endpoint is not served.
+func WithBookingsAPIToken(token string) HTTPServerOption {
+	return func(s *HTTPServer) {
+		s.bookingsAPIToken = token
+	}
 }
 
 // NewHTTPServer returns a new HTTPServer object with the given parameters:
 // - logger, which is a standard Go *log.Logger object, used to log errors and other info
 // - service, which actually implements the carrierpricing Service
-func NewHTTPServer(logger *log.Logger, service carrierpricing.ServiceInterface) *HTTPServer {
-	return &HTTPServer{
+// - options, enabling optional features
+func NewHTTPServer(logger *log.Logger, service carrierpricing.ServiceInterface, options ...HTTPServerOption) *HTTPServer {
+	server := &HTTPServer{
 		logger:  logger,
 		service: service,
 	}
+
+	for _, option := range options {
+		option(server)
+	}
+
+	return server
 }
 
 // Start setups the HTTP router and starts the HTTP server on port 80
 func (s *HTTPServer) Start() {
 	http.HandleFunc("/quotes/byvehicle", s.getQuotesByVehicleHandler)
 	http.HandleFunc("/quotes/bycarrier", s.getQuotesByCarrierHandler)
+	http.HandleFunc("/quotes/best", s.getBestQuotesHandler)
+	http.HandleFunc("/quotes/shipment", s.getShipmentQuoteHandler)
+	http.HandleFunc("/quotes/route", s.getRouteQuoteHandler)
 	http.HandleFunc("/quotes/basic", s.getBasicQuotesHandler)
 	http.HandleFunc("/quotes", s.getBasicQuotesHandler)
+	http.HandleFunc("/quotes/", s.getStoredQuoteHandler)
+	http.HandleFunc("/bookings", s.createBookingHandler)
+	http.HandleFunc("/bookings/", s.bookingHandler)
+	if s.carriersAPIToken != "" {
+		http.HandleFunc("/carriers", authenticated("carriers", s.carriersAPIToken, s.carriersHandler))
+		http.HandleFunc("/carriers/", authenticated("carriers", s.carriersAPIToken, s.carrierHandler))
+	}
 
 	s.logger.Println("Starting HTTP server...")
 	err := http.ListenAndServe(":80", nil)
@@ -56,10 +99,10 @@ func (s *HTTPServer) getBasicQuotesHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	responseObject, err := s.service.GetBasicQuote(*requestObject)
+	responseObject, err := s.service.GetBasicQuote(r.Context(), *requestObject)
 	if err != nil {
 		s.logger.Println(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), quoteErrorStatus(err))
 		return
 	}
 
@@ -77,10 +120,10 @@ func (s *HTTPServer) getQuotesByVehicleHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	responseObject, err := s.service.GetQuotesByVehicle(*requestObject)
+	responseObject, err := s.service.GetQuotesByVehicle(r.Context(), *requestObject)
 	if err != nil {
 		s.logger.Println(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), quoteErrorStatus(err))
 		return
 	}
 
@@ -98,16 +141,285 @@ func (s *HTTPServer) getQuotesByCarrierHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	responseObject, err := s.service.GetQuotesByCarrier(*requestObject)
+	responseObject, err := s.service.GetQuotesByCarrier(r.Context(), *requestObject)
+	if err != nil {
+		s.logger.Println(err)
+		http.Error(w, err.Error(), quoteErrorStatus(err))
+		return
+	}
+
+	writeResponse(w, responseObject)
+}
+
+func (s *HTTPServer) getBestQuotesHandler(w http.ResponseWriter, r *http.Request) {
+	// decode the request into a GetBestQuotesArgs object
+	requestObject := &carrierpricing.GetBestQuotesArgs{}
+
+	err := decodeRequestBodyAsRequestObject(r.Body, &requestObject)
+	if err != nil {
+		s.logger.Println(err)
+		http.Error(w, errMessageInternalServerError, http.StatusInternalServerError)
+		return
+	}
+
+	responseObject, err := s.service.GetBestQuotes(r.Context(), *requestObject)
+	if err != nil {
+		s.logger.Println(err)
+		http.Error(w, err.Error(), quoteErrorStatus(err))
+		return
+	}
+
+	writeResponse(w, responseObject)
+}
+
+func (s *HTTPServer) getShipmentQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	// decode the request into a GetShipmentQuoteArgs object
+	requestObject := &carrierpricing.GetShipmentQuoteArgs{}
+
+	err := decodeRequestBodyAsRequestObject(r.Body, &requestObject)
+	if err != nil {
+		s.logger.Println(err)
+		http.Error(w, errMessageInternalServerError, http.StatusInternalServerError)
+		return
+	}
+
+	responseObject, err := s.service.GetShipmentQuote(r.Context(), *requestObject)
+	if err != nil {
+		s.logger.Println(err)
+		http.Error(w, err.Error(), quoteErrorStatus(err))
+		return
+	}
+
+	writeResponse(w, responseObject)
+}
+
+func (s *HTTPServer) getRouteQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	// decode the request into a GetRouteQuoteArgs object
+	requestObject := &carrierpricing.GetRouteQuoteArgs{}
+
+	err := decodeRequestBodyAsRequestObject(r.Body, &requestObject)
+	if err != nil {
+		s.logger.Println(err)
+		http.Error(w, errMessageInternalServerError, http.StatusInternalServerError)
+		return
+	}
+
+	responseObject, err := s.service.GetRouteQuote(r.Context(), *requestObject)
 	if err != nil {
 		s.logger.Println(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), quoteErrorStatus(err))
 		return
 	}
 
 	writeResponse(w, responseObject)
 }
 
+// getStoredQuoteHandler returns the quote whose ID follows /quotes/ in the path,
+// as it has been returned when computed; 404 is returned for unknown quotes and
+// 410 for expired ones.
+func (s *HTTPServer) getStoredQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/quotes/")
+
+	responseObject, err := s.service.GetQuote(id)
+	switch {
+	case errors.Is(err, carrierpricing.ErrQuoteNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, carrierpricing.ErrQuoteExpired):
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	case err != nil:
+		s.logger.Println(err)
+		http.Error(w, errMessageInternalServerError, http.StatusInternalServerError)
+		return
+	}
+
+	writeResponse(w, responseObject)
+}
+
+// createBookingHandler books a stored quote by carrier with the chosen carrier,
+// returning 201 and the new booking.
+func (s *HTTPServer) createBookingHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode the request into a CreateBookingArgs object
+	requestObject := &carrierpricing.CreateBookingArgs{}
+
+	err := decodeRequestBodyAsRequestObject(r.Body, &requestObject)
+	if err != nil {
+		s.logger.Println(err)
+		http.Error(w, errMessageInternalServerError, http.StatusInternalServerError)
+		return
+	}
+
+	responseObject, err := s.service.CreateBooking(r.Context(), *requestObject)
+	if err != nil {
+		s.logger.Println(err)
+		http.Error(w, err.Error(), bookingErrorStatus(err))
+		return
+	}
+
+	responseDataAsBytes, err := json.Marshal(responseObject)
+	if err != nil {
+		s.logger.Println(err)
+		http.Error(w, errMessageInternalServerError, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", "/bookings/"+responseObject.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(responseDataAsBytes)
+}
+
+// bookingHandler serves GET /bookings/{id}, returning the booking,
+// POST /bookings/{id}/status, when enabled via WithBookingsAPIToken, and
+// GET /bookings/{id}/tracking, returning the status reported by the carrier.
+func (s *HTTPServer) bookingHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/bookings/")
+
+	if id := strings.TrimSuffix(path, "/tracking"); id != path {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		responseObject, err := s.service.TrackBooking(id)
+		if err != nil {
+			s.logger.Println(err)
+			http.Error(w, err.Error(), bookingErrorStatus(err))
+			return
+		}
+
+		writeResponse(w, responseObject)
+		return
+	}
+
+	if id := strings.TrimSuffix(path, "/status"); id != path {
+		if s.bookingsAPIToken == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		authenticated("bookings", s.bookingsAPIToken, func(w http.ResponseWriter, r *http.Request) {
+			s.updateBookingStatusHandler(w, r, id)
+		})(w, r)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	responseObject, err := s.service.GetBooking(path)
+	if err != nil {
+		s.logger.Println(err)
+		http.Error(w, err.Error(), bookingErrorStatus(err))
+		return
+	}
+
+	writeResponse(w, responseObject)
+}
+
+// updateBookingStatusHandler serves POST /bookings/{id}/status for the booking
+// with the given ID, moving it to the status in the body.
+func (s *HTTPServer) updateBookingStatusHandler(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode the request into a UpdateBookingStatusArgs object
+	requestObject := &carrierpricing.UpdateBookingStatusArgs{}
+
+	err := decodeRequestBodyAsRequestObject(r.Body, &requestObject)
+	if err != nil {
+		s.logger.Println(err)
+		http.Error(w, errMessageInternalServerError, http.StatusInternalServerError)
+		return
+	}
+	requestObject.BookingID = id
+
+	responseObject, err := s.service.UpdateBookingStatus(*requestObject)
+	if err != nil {
+		s.logger.Println(err)
+		http.Error(w, err.Error(), bookingErrorStatus(err))
+		return
+	}
+
+	writeResponse(w, responseObject)
+}
+
+// authenticated returns a handler serving the requests having the given token
+// as bearer token via the given handler; 401 is returned for the others, e.g.
+// the ones sending the token without the "Bearer" scheme, asking to
+// authenticate for the given realm.
+func authenticated(realm, apiToken string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+		token := strings.TrimPrefix(authorization, "Bearer ")
+		if token == authorization || subtle.ConstantTimeCompare([]byte(token), []byte(apiToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q", realm))
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+// quoteErrorStatus returns the HTTP status code for an error returned by the
+// quote methods of the service: 503 when carrier services cannot be found, 400
+// when the request cannot be quoted, 500 for any other error, e.g. failing to
+// store the quote.
+func quoteErrorStatus(err error) int {
+	var invalidArgsError *carrierpricing.InvalidArgsError
+
+	switch {
+	case errors.Is(err, carrierpricing.ErrCarrierServicesUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.As(err, &invalidArgsError):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// bookingErrorStatus returns the HTTP status code for an error returned by the
+// booking methods of the service.
+func bookingErrorStatus(err error) int {
+	var priceChangedError *carrierpricing.PriceChangedError
+
+	switch {
+	case errors.Is(err, carrierpricing.ErrQuoteNotFound), errors.Is(err, carrierpricing.ErrBookingNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, carrierpricing.ErrQuoteExpired):
+		return http.StatusGone
+	case errors.Is(err, carrierpricing.ErrCarrierNotAvailable),
+		errors.Is(err, carrierpricing.ErrInvalidBookingTransition),
+		errors.Is(err, carrierpricing.ErrQuoteAlreadyBooked),
+		errors.Is(err, carrierpricing.ErrBookingNotDispatched),
+		errors.As(err, &priceChangedError):
+		return http.StatusConflict
+	case errors.Is(err, carrierpricing.ErrDispatchFailed):
+		return http.StatusBadGateway
+	}
+	return quoteErrorStatus(err)
+}
+
 // decodeRequestBodyAsRequestObject is a utility method which abstracts the way an
 // HTTP request body is decoded into the given requestObject passed as argument
 func decodeRequestBodyAsRequestObject(requestBody io.ReadCloser, requestObject interface{}) error {
@@ -0,0 +1,132 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/giefferre/carrierpricing"
+	"github.com/giefferre/carrierpricing/carrierservicefinders"
+	"github.com/giefferre/carrierpricing/postcode"
+)
+
+func TestAuthenticated(t *testing.T) {
+	handler := authenticated("bookings", "secret", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		Authorization      string
+		ExpectedStatusCode int
+	}{
+		// case #1 bearer token
+		{Authorization: "Bearer secret", ExpectedStatusCode: http.StatusNoContent},
+		// case #2 token without the scheme
+		{Authorization: "secret", ExpectedStatusCode: http.StatusUnauthorized},
+		// case #3 token with another scheme
+		{Authorization: "Basic secret", ExpectedStatusCode: http.StatusUnauthorized},
+		// case #4 wrong bearer token
+		{Authorization: "Bearer wrong", ExpectedStatusCode: http.StatusUnauthorized},
+		// case #5 no token
+		{Authorization: "", ExpectedStatusCode: http.StatusUnauthorized},
+	}
+
+	for i, tc := range tests {
+		request := httptest.NewRequest(http.MethodPost, "/bookings/booking/status", nil)
+		if tc.Authorization != "" {
+			request.Header.Set("Authorization", tc.Authorization)
+		}
+		recorder := httptest.NewRecorder()
+
+		handler(recorder, request)
+
+		if recorder.Code != tc.ExpectedStatusCode {
+			t.Fatalf("case #%d: expected status code %d, received: %d", i+1, tc.ExpectedStatusCode, recorder.Code)
+		}
+		if tc.ExpectedStatusCode == http.StatusUnauthorized && recorder.Header().Get("WWW-Authenticate") != `Bearer realm="bookings"` {
+			t.Fatalf("case #%d: unexpected WWW-Authenticate header %q", i+1, recorder.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
+
+func TestQuoteErrorStatus(t *testing.T) {
+	logger := log.New(ioutil.Discard, "", 0)
+	service, err := carrierpricing.NewService(
+		logger,
+		carrierservicefinders.NewCSFFromStaticData(),
+		&mockDistanceCalculator{},
+		nil,
+		carrierpricing.WithQuoteStore(&mockFailingQuoteStore{}, 0),
+	)
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
+	server := NewHTTPServer(logger, service)
+
+	tests := []struct {
+		Body               string
+		Cancelled          bool
+		ExpectedStatusCode int
+	}{
+		// case #1 the quote cannot be stored
+		{
+			Body:               `{"pickup_postcode": "SW1A1AA", "delivery_postcode": "EC2A3LT", "vehicle": "small_van"}`,
+			ExpectedStatusCode: http.StatusInternalServerError,
+		},
+		// case #2 invalid postcode
+		{
+			Body:               `{"pickup_postcode": "SW1A1AA", "delivery_postcode": "OOPS", "vehicle": "small_van"}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		// case #3 invalid vehicle
+		{
+			Body:               `{"pickup_postcode": "SW1A1AA", "delivery_postcode": "EC2A3LT", "vehicle": "spaceship"}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		// case #4 request cancelled while finding carrier services
+		{
+			Body:               `{"pickup_postcode": "SW1A1AA", "delivery_postcode": "EC2A3LT", "vehicle": "small_van"}`,
+			Cancelled:          true,
+			ExpectedStatusCode: http.StatusServiceUnavailable,
+		},
+	}
+
+	for i, tc := range tests {
+		ctx, cancel := context.WithCancel(context.Background())
+		if tc.Cancelled {
+			cancel()
+		}
+
+		request := httptest.NewRequest(http.MethodPost, "/quotes/bycarrier", strings.NewReader(tc.Body)).WithContext(ctx)
+		recorder := httptest.NewRecorder()
+
+		server.getQuotesByCarrierHandler(recorder, request)
+		cancel()
+
+		if recorder.Code != tc.ExpectedStatusCode {
+			t.Fatalf("case #%d: expected status code %d, received: %d (%s)", i+1, tc.ExpectedStatusCode, recorder.Code, recorder.Body.String())
+		}
+	}
+}
+
+type mockDistanceCalculator struct{}
+
+func (mdc *mockDistanceCalculator) CalculateDistance(pickup, delivery postcode.Postcode) (float64, error) {
+	return 10, nil
+}
+
+// mockFailingQuoteStore fails to save and to get any quote.
+type mockFailingQuoteStore struct{}
+
+func (mfqs *mockFailingQuoteStore) SaveQuote(quote *carrierpricing.StoredQuote) error {
+	return errors.New("disk full")
+}
+
+func (mfqs *mockFailingQuoteStore) GetQuote(id string) (*carrierpricing.StoredQuote, error) {
+	return nil, errors.New("disk full")
+}
@@ -0,0 +1,176 @@
+package carrierpricing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+// CurrencyGBP is the ISO 4217 code of the British pound, the default currency of prices.
+const CurrencyGBP = "GBP"
+
+var (
+	errInvalidCurrency  = errors.New("invalid currency provided")
+	errCurrencyMismatch = errors.New("amounts in different currencies cannot be added")
+)
+
+// currencyMinorUnits indicates, for each supported ISO 4217 currency, the number
+// of decimal digits of its minor unit.
+var currencyMinorUnits = map[string]int{
+	"AUD": 2,
+	"CAD": 2,
+	"CHF": 2,
+	"CNY": 2,
+	"CZK": 2,
+	"DKK": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"HKD": 2,
+	"HUF": 2,
+	"INR": 2,
+	"JPY": 0,
+	"KRW": 0,
+	"NOK": 2,
+	"NZD": 2,
+	"PLN": 2,
+	"SEK": 2,
+	"SGD": 2,
+	"USD": 2,
+	"ZAR": 2,
+}
+
+// IsValidCurrency reports whether the given ISO 4217 currency code is supported.
+func IsValidCurrency(currency string) bool {
+	_, exists := currencyMinorUnits[currency]
+	return exists
+}
+
+// Money is an amount of money, expressed in the minor unit of its ISO 4217
+// currency (e.g. pence for GBP), so that no precision is lost.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// NewMoney returns a Money object for the given amount, in minor units, and currency.
+func NewMoney(amount int64, currency string) Money {
+	return Money{
+		Amount:   amount,
+		Currency: currency,
+	}
+}
+
+// Add returns the sum of the two amounts; an error is returned when they are
+// expressed in different currencies.
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", errCurrencyMismatch, m.Currency, other.Currency)
+	}
+	return NewMoney(m.Amount+other.Amount, m.Currency), nil
+}
+
+// Multiply returns the amount multiplied by the given factor, rounded to the
+// minor unit according to the given RoundingMode. The factor is taken as the
+// shortest decimal number representing it (e.g. 1.1 rather than its binary
+// approximation), so that the multiplication does not suffer from floating
+// point errors.
+func (m Money) Multiply(factor float64, rounding RoundingMode) Money {
+	return NewMoney(multiplyMinorUnits(m.Amount, factor, rounding), m.Currency)
+}
+
+// String returns the amount in major units followed by the currency, e.g. "3.16 GBP".
+func (m Money) String() string {
+	minorUnits, exists := currencyMinorUnits[m.Currency]
+	if !exists || minorUnits == 0 {
+		return fmt.Sprintf("%d %s", m.Amount, m.Currency)
+	}
+
+	amount := new(big.Rat).SetFrac64(m.Amount, pow10(minorUnits))
+	return fmt.Sprintf("%s %s", amount.FloatString(minorUnits), m.Currency)
+}
+
+// UnmarshalJSON decodes a Money object, returning an error if its currency is not supported.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	// money is an alias type without methods, avoiding recursion
+	type money Money
+
+	decoded := money{}
+	err := json.Unmarshal(data, &decoded)
+	if err != nil {
+		return err
+	}
+
+	if !IsValidCurrency(decoded.Currency) {
+		return fmt.Errorf("%w %q", errInvalidCurrency, decoded.Currency)
+	}
+
+	*m = Money(decoded)
+	return nil
+}
+
+// multiplyMinorUnits multiplies the amount by the factor using exact decimal
+// arithmetic, then rounds the result according to the given RoundingMode.
+func multiplyMinorUnits(amount int64, factor float64, rounding RoundingMode) int64 {
+	exactFactor, ok := decimalRat(factor)
+	if !ok {
+		// this can only happen for NaN and infinite factors, which are rejected
+		// when validating the pricing rules
+		return int64(rounding.Round(float64(amount) * factor))
+	}
+
+	product := new(big.Rat).Mul(new(big.Rat).SetInt64(amount), exactFactor)
+
+	return rounding.roundRat(product)
+}
+
+// decimalRat returns the shortest decimal number representing the given value
+// (e.g. 1.1 rather than its binary approximation) as a rational number; ok is
+// false for NaN and infinite values.
+func decimalRat(value float64) (*big.Rat, bool) {
+	return new(big.Rat).SetString(strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// roundRat rounds the given rational number to an integer according to the RoundingMode.
+func (rm RoundingMode) roundRat(value *big.Rat) int64 {
+	quotient, remainder := new(big.Int).QuoRem(value.Num(), value.Denom(), new(big.Int))
+	if remainder.Sign() == 0 {
+		return quotient.Int64()
+	}
+
+	// the remainder has the same sign of the value; away is the direction
+	// to move to when rounding away from zero.
+	away := int64(value.Sign())
+
+	// compare twice the remainder with the denominator to find out whether
+	// the fractional part is lower, equal or greater than a half.
+	doubleRemainder := new(big.Int).Abs(new(big.Int).Mul(remainder, big.NewInt(2)))
+	half := doubleRemainder.Cmp(value.Denom())
+
+	result := quotient.Int64()
+	switch rm {
+	case RoundingModeDown:
+		return result
+	case RoundingModeUp:
+		return result + away
+	case RoundingModeHalfUp:
+		if half >= 0 {
+			return result + away
+		}
+		return result
+	default:
+		if half > 0 || (half == 0 && result%2 != 0) {
+			return result + away
+		}
+		return result
+	}
+}
+
+func pow10(exponent int) int64 {
+	result := int64(1)
+	for i := 0; i < exponent; i++ {
+		result *= 10
+	}
+	return result
+}
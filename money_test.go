@@ -0,0 +1,85 @@
+package carrierpricing
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMoneyMultiply(t *testing.T) {
+	tests := []struct {
+		Amount         int64
+		Factor         float64
+		Rounding       RoundingMode
+		ExpectedResult int64
+	}{
+		// 1000 * 1.1 is 1100.0000000000002 using floating point arithmetic
+		{1000, 1.1, RoundingModeUp, 1100},
+		{316, 1.3, RoundingModeHalfEven, 411},
+		{5, 0.5, RoundingModeHalfEven, 2},
+		{5, 0.5, RoundingModeHalfUp, 3},
+		{7, 0.5, RoundingModeHalfEven, 4},
+		{-5, 0.5, RoundingModeHalfUp, -3},
+		{-5, 0.5, RoundingModeDown, -2},
+		{-5, 0.5, RoundingModeUp, -3},
+		{101, 1.01, RoundingModeDown, 102},
+		{101, 1.01, RoundingModeUp, 103},
+	}
+
+	for _, tc := range tests {
+		result := NewMoney(tc.Amount, CurrencyGBP).Multiply(tc.Factor, tc.Rounding)
+		if result != NewMoney(tc.ExpectedResult, CurrencyGBP) {
+			t.Fatalf(
+				"expected %d * %v with %s rounding to be %d, received: %v\n",
+				tc.Amount,
+				tc.Factor,
+				tc.Rounding,
+				tc.ExpectedResult,
+				result,
+			)
+		}
+	}
+}
+
+func TestMoneyAdd(t *testing.T) {
+	result, err := gbp(316).Add(gbp(95))
+	if err != nil || result != gbp(411) {
+		t.Fatalf("expected 4.11 GBP, received: %v, %v", result, err)
+	}
+
+	_, err = gbp(316).Add(NewMoney(95, "EUR"))
+	if err == nil || err.Error() != "amounts in different currencies cannot be added: GBP and EUR" {
+		t.Fatalf("expected currency mismatch error, received: %v", err)
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		Money          Money
+		ExpectedResult string
+	}{
+		{gbp(316), "3.16 GBP"},
+		{gbp(5), "0.05 GBP"},
+		{gbp(-120), "-1.20 GBP"},
+		{NewMoney(1500, "JPY"), "1500 JPY"},
+	}
+
+	for _, tc := range tests {
+		if result := tc.Money.String(); result != tc.ExpectedResult {
+			t.Fatalf("expected '%s', received: '%s'", tc.ExpectedResult, result)
+		}
+	}
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	var m Money
+
+	err := json.Unmarshal([]byte(`{"amount": 316, "currency": "GBP"}`), &m)
+	if err != nil || m != gbp(316) {
+		t.Fatalf("expected 3.16 GBP, received: %v, %v", m, err)
+	}
+
+	err = json.Unmarshal([]byte(`{"amount": 316, "currency": "XYZ"}`), &m)
+	if err == nil || err.Error() != "invalid currency provided \"XYZ\"" {
+		t.Fatalf("expected invalid currency error, received: %v", err)
+	}
+}
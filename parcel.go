@@ -0,0 +1,94 @@
+package carrierpricing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var errInvalidParcel = errors.New("invalid parcel: weight and dimensions must be non negative numbers")
+
+// Parcel contains the weight, in kilograms, and the dimensions, in centimetres,
+// of the item to be delivered.
+type Parcel struct {
+	WeightKg float64 `json:"weight_kg"`
+	LengthCm float64 `json:"length_cm"`
+	WidthCm  float64 `json:"width_cm"`
+	HeightCm float64 `json:"height_cm"`
+}
+
+// VolumeLitres returns the volume of the parcel, in litres.
+func (p Parcel) VolumeLitres() float64 {
+	return p.LengthCm * p.WidthCm * p.HeightCm / 1000
+}
+
+// ChargeableWeightKg returns the greater between the actual weight of the parcel
+// and its volumetric weight, calculated dividing its volume in cubic centimetres
+// by the given divisor. When the divisor is zero, the actual weight is returned.
+func (p Parcel) ChargeableWeightKg(volumetricDivisor float64) float64 {
+	if volumetricDivisor <= 0 {
+		return p.WeightKg
+	}
+
+	volumetricWeight := p.LengthCm * p.WidthCm * p.HeightCm / volumetricDivisor
+
+	return math.Max(p.WeightKg, volumetricWeight)
+}
+
+func (p Parcel) validate() error {
+	for _, value := range []float64{p.WeightKg, p.LengthCm, p.WidthCm, p.HeightCm} {
+		if value < 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+			return errInvalidParcel
+		}
+	}
+	return nil
+}
+
+// VehicleCapacity indicates the maximum weight, in kilograms, and the maximum
+// volume, in litres, a vehicle can carry.
+type VehicleCapacity struct {
+	MaxWeightKg     float64 `json:"max_weight_kg"`
+	MaxVolumeLitres float64 `json:"max_volume_litres"`
+}
+
+// canCarry returns a *VehicleCapacityError if the parcel exceeds the capacity
+// of the given vehicle.
+func (vc VehicleCapacity) canCarry(vehicleType string, parcel Parcel) error {
+	weight := parcel.WeightKg
+	volume := parcel.VolumeLitres()
+
+	if weight > vc.MaxWeightKg || volume > vc.MaxVolumeLitres {
+		return &VehicleCapacityError{
+			Vehicle:         vehicleType,
+			WeightKg:        weight,
+			MaxWeightKg:     vc.MaxWeightKg,
+			VolumeLitres:    volume,
+			MaxVolumeLitres: vc.MaxVolumeLitres,
+		}
+	}
+
+	return nil
+}
+
+// VehicleCapacityError is returned when the chosen vehicle cannot carry the parcel.
+type VehicleCapacityError struct {
+	Vehicle         string
+	WeightKg        float64
+	MaxWeightKg     float64
+	VolumeLitres    float64
+	MaxVolumeLitres float64
+}
+
+func (e *VehicleCapacityError) Error() string {
+	if e.MaxWeightKg > 0 && e.WeightKg > e.MaxWeightKg {
+		return fmt.Sprintf(
+			"%s cannot carry the parcel: weight of %gkg exceeds the maximum of %gkg",
+			e.Vehicle, e.WeightKg, e.MaxWeightKg,
+		)
+	}
+
+	return fmt.Sprintf(
+		"%s cannot carry the parcel: volume of %gl exceeds the maximum of %gl",
+		e.Vehicle, e.VolumeLitres, e.MaxVolumeLitres,
+	)
+}
@@ -0,0 +1,181 @@
+/*
+Package postcode parses and validates UK postcodes.
+
+A postcode is made of an outward code (e.g. "SW1A") and an inward code
+(e.g. "1AA"). Parse normalises the given string, ignoring case and spacing,
+and returns its structured parts:
+
+- Area:		the leading letters of the outward code, e.g. "SW"
+- District:	the whole outward code, e.g. "SW1A"
+- Sector:	the district followed by the inward digit, e.g. "SW1A 1"
+- Unit:		the trailing letters of the inward code, e.g. "AA"
+
+Special cases such as the Girobank postcode (GIR 0AA) and British Forces
+Post Office numbers (BFPO 1234) are supported as well.
+*/
+package postcode
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	// ErrEmpty is returned when an empty postcode is provided.
+	ErrEmpty = errors.New("postcode is empty")
+
+	// ErrInvalidLength is returned when the postcode is too short or too long
+	// to be a valid UK postcode.
+	ErrInvalidLength = errors.New("postcode has an invalid length")
+
+	// ErrInvalidOutwardCode is returned when the outward code (e.g. "SW1A")
+	// does not match any of the UK formats.
+	ErrInvalidOutwardCode = errors.New("postcode has an invalid outward code")
+
+	// ErrInvalidInwardCode is returned when the inward code (e.g. "1AA")
+	// does not match the UK format.
+	ErrInvalidInwardCode = errors.New("postcode has an invalid inward code")
+)
+
+// ValidationError is returned by Parse when the given string is not a valid
+// UK postcode. Err is one of the Err* values declared in this package.
+type ValidationError struct {
+	Postcode string
+	Err      error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid postcode %q: %v", e.Postcode, e.Err)
+}
+
+// Unwrap returns the underlying validation error.
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+const (
+	girobankPostcode = "GIR0AA"
+	bfpoPrefix       = "BFPO"
+)
+
+var (
+	// outward codes follow one of the A9, A9A, A99, AA9, AA9A, AA99 formats,
+	// with some letters never being used in specific positions.
+	outwardCodeRegexp = regexp.MustCompile(
+		`^([A-PR-UWYZ])([0-9])([0-9]|[A-HJKPSTUW])?$|^([A-PR-UWYZ][A-HK-Y])([0-9])([0-9]|[ABEHMNPRVWXY])?$`,
+	)
+
+	// inward codes always follow the 9AA format.
+	inwardCodeRegexp = regexp.MustCompile(`^[0-9][ABD-HJLNP-UW-Z]{2}$`)
+
+	bfpoNumberRegexp = regexp.MustCompile(`^[0-9]{1,4}$`)
+)
+
+// Postcode is a parsed, normalised UK postcode.
+type Postcode struct {
+	Outward  string `json:"outward"`
+	Inward   string `json:"inward"`
+	Area     string `json:"area"`
+	District string `json:"district"`
+	Sector   string `json:"sector"`
+	Unit     string `json:"unit"`
+}
+
+// Parse normalises and validates the given string, returning its structured
+// parts. Case and spacing are ignored, so "sw1a1aa" and "SW1A 1AA" are
+// equivalent. A *ValidationError is returned if the string is not a valid UK
+// postcode.
+func Parse(s string) (*Postcode, error) {
+	normalised := normalise(s)
+	if normalised == "" {
+		return nil, &ValidationError{Postcode: s, Err: ErrEmpty}
+	}
+
+	if normalised == girobankPostcode {
+		return &Postcode{
+			Outward:  "GIR",
+			Inward:   "0AA",
+			Area:     "GIR",
+			District: "GIR",
+			Sector:   "GIR 0",
+			Unit:     "AA",
+		}, nil
+	}
+
+	if strings.HasPrefix(normalised, bfpoPrefix) {
+		number := strings.TrimPrefix(normalised, bfpoPrefix)
+		if !bfpoNumberRegexp.MatchString(number) {
+			return nil, &ValidationError{Postcode: s, Err: ErrInvalidInwardCode}
+		}
+
+		return &Postcode{
+			Outward:  bfpoPrefix,
+			Inward:   number,
+			Area:     bfpoPrefix,
+			District: bfpoPrefix,
+			Sector:   bfpoPrefix + " " + number,
+			Unit:     number,
+		}, nil
+	}
+
+	// the shortest postcode is A9 9AA, the longest one is AA9A 9AA
+	if len(normalised) < 5 || len(normalised) > 7 {
+		return nil, &ValidationError{Postcode: s, Err: ErrInvalidLength}
+	}
+
+	outward := normalised[:len(normalised)-3]
+	inward := normalised[len(normalised)-3:]
+
+	outwardParts := outwardCodeRegexp.FindStringSubmatch(outward)
+	if outwardParts == nil {
+		return nil, &ValidationError{Postcode: s, Err: ErrInvalidOutwardCode}
+	}
+
+	if !inwardCodeRegexp.MatchString(inward) {
+		return nil, &ValidationError{Postcode: s, Err: ErrInvalidInwardCode}
+	}
+
+	// the regexp has two alternatives: the area is captured either by the
+	// first group (single letter areas) or by the fourth one (two letters).
+	area := outwardParts[1]
+	if area == "" {
+		area = outwardParts[4]
+	}
+
+	return &Postcode{
+		Outward:  outward,
+		Inward:   inward,
+		Area:     area,
+		District: outward,
+		Sector:   outward + " " + inward[:1],
+		Unit:     inward[1:],
+	}, nil
+}
+
+// IsBFPO reports whether the postcode is a British Forces Post Office number.
+func (p Postcode) IsBFPO() bool {
+	return p.Area == bfpoPrefix
+}
+
+// String returns the postcode in its canonical form, e.g. "SW1A 1AA".
+func (p Postcode) String() string {
+	return p.Outward + " " + p.Inward
+}
+
+// Compact returns the postcode without any space, e.g. "SW1A1AA".
+func (p Postcode) Compact() string {
+	return p.Outward + p.Inward
+}
+
+// normalise upper-cases the given string and removes any whitespace from it.
+func normalise(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, s)
+}
@@ -0,0 +1,139 @@
+package postcode
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		Input          string
+		ExpectedResult *Postcode
+		ExpectedError  error
+	}{
+		// case #1 empty string
+		{
+			Input:         "  ",
+			ExpectedError: ErrEmpty,
+		},
+		// case #2 too short
+		{
+			Input:         "SW1",
+			ExpectedError: ErrInvalidLength,
+		},
+		// case #3 too long
+		{
+			Input:         "SW1A 1AAA",
+			ExpectedError: ErrInvalidLength,
+		},
+		// case #4 invalid outward code
+		{
+			Input:         "QW1 1AA",
+			ExpectedError: ErrInvalidOutwardCode,
+		},
+		// case #5 invalid inward code, C is never used in inward codes
+		{
+			Input:         "SW1A 1CA",
+			ExpectedError: ErrInvalidInwardCode,
+		},
+		// case #6 invalid BFPO number
+		{
+			Input:         "BFPO 12345",
+			ExpectedError: ErrInvalidInwardCode,
+		},
+		// case #7 AA9A 9AA format, lowercase without space
+		{
+			Input: "sw1a1aa",
+			ExpectedResult: &Postcode{
+				Outward:  "SW1A",
+				Inward:   "1AA",
+				Area:     "SW",
+				District: "SW1A",
+				Sector:   "SW1A 1",
+				Unit:     "AA",
+			},
+		},
+		// case #8 A9 9AA format
+		{
+			Input: "M1 1AE",
+			ExpectedResult: &Postcode{
+				Outward:  "M1",
+				Inward:   "1AE",
+				Area:     "M",
+				District: "M1",
+				Sector:   "M1 1",
+				Unit:     "AE",
+			},
+		},
+		// case #9 AA99 9AA format with extra spaces
+		{
+			Input: " DN55  1PT ",
+			ExpectedResult: &Postcode{
+				Outward:  "DN55",
+				Inward:   "1PT",
+				Area:     "DN",
+				District: "DN55",
+				Sector:   "DN55 1",
+				Unit:     "PT",
+			},
+		},
+		// case #10 Girobank
+		{
+			Input: "gir 0aa",
+			ExpectedResult: &Postcode{
+				Outward:  "GIR",
+				Inward:   "0AA",
+				Area:     "GIR",
+				District: "GIR",
+				Sector:   "GIR 0",
+				Unit:     "AA",
+			},
+		},
+		// case #11 BFPO
+		{
+			Input: "BFPO 801",
+			ExpectedResult: &Postcode{
+				Outward:  "BFPO",
+				Inward:   "801",
+				Area:     "BFPO",
+				District: "BFPO",
+				Sector:   "BFPO 801",
+				Unit:     "801",
+			},
+		},
+	}
+
+	for i, tc := range tests {
+		result, err := Parse(tc.Input)
+		if !errors.Is(err, tc.ExpectedError) {
+			t.Fatalf("case #%d: expected error '%v', received: '%v'", i+1, tc.ExpectedError, err)
+		}
+		if err != nil {
+			validationError := &ValidationError{}
+			if !errors.As(err, &validationError) || validationError.Postcode != tc.Input {
+				t.Fatalf("case #%d: expected a *ValidationError for '%s', received: '%v'", i+1, tc.Input, err)
+			}
+		}
+		if !reflect.DeepEqual(tc.ExpectedResult, result) {
+			t.Fatalf("case #%d: expected result '%v', received: '%v'", i+1, tc.ExpectedResult, result)
+		}
+	}
+}
+
+func TestPostcodeString(t *testing.T) {
+	p, err := Parse("ec2a3lt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.String() != "EC2A 3LT" {
+		t.Fatalf("expected 'EC2A 3LT', received '%s'", p.String())
+	}
+	if p.Compact() != "EC2A3LT" {
+		t.Fatalf("expected 'EC2A3LT', received '%s'", p.Compact())
+	}
+	if p.IsBFPO() {
+		t.Fatal("expected EC2A 3LT not to be a BFPO postcode")
+	}
+}
@@ -0,0 +1,240 @@
+package carrierpricing
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// SortByPrice sorts price lists from the cheapest to the most expensive carrier.
+	SortByPrice = "price"
+
+	// SortByDeliveryTime sorts price lists from the fastest to the slowest carrier.
+	SortByDeliveryTime = "delivery_time"
+
+	// SortByScore sorts price lists by a score weighting price and speed, best first.
+	SortByScore = "score"
+
+	// SortByCarrierName sorts price lists by carrier name, alphabetically.
+	SortByCarrierName = "carrier_name"
+)
+
+// defaultPriceWeight gives price and speed the same weight in the score.
+const defaultPriceWeight = 0.5
+
+var (
+	errInvalidSortBy      = errors.New("invalid sort_by provided")
+	errInvalidPriceWeight = errors.New("price_weight must be between 0 and 1")
+)
+
+// PriceListOptions sorts and filters the price list of the GetQuotesByCarrier
+// method; all of them are optional.
+//
+// SortBy is one of SortByPrice (the default), SortByDeliveryTime, SortByScore
+// and SortByCarrierName; ties are broken by price, then by delivery time, then
+// by carrier name. The score of each carrier weights its price by PriceWeight,
+// between 0 and 1 (0.5 when not set), and its delivery time by the rest, both
+// relative to the other carriers of the list.
+//
+// MaxPrice, in minor units of the currency of the response, is compared with
+// the displayed price; MaxDeliveryTime is compared with the delivery time of
+// each carrier service. Carriers, when not empty, lists the only carriers to be
+// quoted, while ExcludedCarriers lists the carriers not to be quoted; carrier
+// names are case insensitive.
+type PriceListOptions struct {
+	SortBy           string            `json:"sort_by,omitempty"`
+	PriceWeight      *float64          `json:"price_weight,omitempty"`
+	MaxPrice         int64             `json:"max_price,omitempty"`
+	MaxDeliveryTime  *DeliveryDuration `json:"max_delivery_time,omitempty"`
+	Carriers         []string          `json:"carriers,omitempty"`
+	ExcludedCarriers []string          `json:"excluded_carriers,omitempty"`
+}
+
+// validate returns an error if the PriceListOptions cannot be applied.
+func (plo PriceListOptions) validate() error {
+	switch plo.SortBy {
+	case "", SortByPrice, SortByDeliveryTime, SortByScore, SortByCarrierName:
+	default:
+		return fmt.Errorf("%w %q", errInvalidSortBy, plo.SortBy)
+	}
+
+	if plo.PriceWeight != nil && !(*plo.PriceWeight >= 0 && *plo.PriceWeight <= 1) {
+		return errInvalidPriceWeight
+	}
+
+	if plo.MaxPrice < 0 {
+		return errors.New("max_price must not be negative")
+	}
+
+	if plo.MaxDeliveryTime != nil {
+		if err := plo.MaxDeliveryTime.Validate(); err != nil {
+			return fmt.Errorf("max_delivery_time: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// apply returns the given price list filtered and sorted according to the
+// PriceListOptions.
+func (plo PriceListOptions) apply(priceList PriceByCarrierList) PriceByCarrierList {
+	filtered := PriceByCarrierList{}
+	for _, priceByCarrier := range priceList {
+		if plo.accepts(priceByCarrier) {
+			filtered = append(filtered, priceByCarrier)
+		}
+	}
+
+	switch plo.SortBy {
+	case SortByDeliveryTime:
+		delivery := deliveryKeys(filtered)
+		sortPriceList(filtered, func(i, j int) int {
+			return compareFloats(delivery[i], delivery[j])
+		})
+	case SortByScore:
+		scores := plo.scores(filtered)
+		sortPriceList(filtered, func(i, j int) int {
+			return compareFloats(scores[i], scores[j])
+		})
+	case SortByCarrierName:
+		sortPriceList(filtered, func(i, j int) int {
+			return strings.Compare(strings.ToLower(filtered[i].CarrierName), strings.ToLower(filtered[j].CarrierName))
+		})
+	default:
+		sort.Stable(filtered)
+	}
+
+	return filtered
+}
+
+// accepts returns true if the given PriceByCarrier passes all the filters.
+func (plo PriceListOptions) accepts(priceByCarrier PriceByCarrier) bool {
+	if plo.MaxPrice > 0 && priceByCarrier.Amount.Amount > plo.MaxPrice {
+		return false
+	}
+
+	if plo.MaxDeliveryTime != nil && priceByCarrier.DeliveryTime.Nominal() > plo.MaxDeliveryTime.Nominal() {
+		return false
+	}
+
+	if len(plo.Carriers) > 0 && !containsFold(plo.Carriers, priceByCarrier.CarrierName) {
+		return false
+	}
+
+	return !containsFold(plo.ExcludedCarriers, priceByCarrier.CarrierName)
+}
+
+// scores returns the score of each element of the given price list, lower
+// being better: price and delivery time are scaled between 0, for the best
+// carrier of the list, and 1, for the worst one, and then weighted.
+func (plo PriceListOptions) scores(priceList PriceByCarrierList) []float64 {
+	priceWeight := defaultPriceWeight
+	if plo.PriceWeight != nil {
+		priceWeight = *plo.PriceWeight
+	}
+
+	prices := make([]float64, len(priceList))
+	for i, priceByCarrier := range priceList {
+		prices[i] = float64(priceByCarrier.Amount.Amount)
+	}
+	prices = normalise(prices)
+	delivery := normalise(deliveryKeys(priceList))
+
+	scores := make([]float64, len(priceList))
+	for i := range priceList {
+		scores[i] = priceWeight*prices[i] + (1-priceWeight)*delivery[i]
+	}
+	return scores
+}
+
+// sortPriceList sorts the given price list by the given comparison, breaking
+// ties as PriceByCarrierList does. Keys are looked up by the original position
+// of each element, so the comparison can rely on slices built beforehand.
+func sortPriceList(priceList PriceByCarrierList, compare func(i, j int) int) {
+	order := make([]int, len(priceList))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		if result := compare(order[i], order[j]); result != 0 {
+			return result < 0
+		}
+		return priceList.Less(order[i], order[j])
+	})
+
+	sorted := make(PriceByCarrierList, len(priceList))
+	for i, index := range order {
+		sorted[i] = priceList[index]
+	}
+	copy(priceList, sorted)
+}
+
+// deliveryKeys returns a value for each element of the given price list which
+// orders them from the first to be delivered: the end of the estimated delivery
+// window, when known for all of them, otherwise the delivery time.
+func deliveryKeys(priceList PriceByCarrierList) []float64 {
+	keys := make([]float64, len(priceList))
+
+	for _, priceByCarrier := range priceList {
+		if priceByCarrier.EstimatedDelivery == nil {
+			for i, priceByCarrier := range priceList {
+				keys[i] = float64(priceByCarrier.DeliveryTime.Nominal())
+			}
+			return keys
+		}
+	}
+
+	for i, priceByCarrier := range priceList {
+		keys[i] = float64(priceByCarrier.EstimatedDelivery.Latest.UnixNano())
+	}
+	return keys
+}
+
+// normalise scales the given values between 0, for the lowest, and 1, for the
+// highest; all values are 0 when they are equal.
+func normalise(values []float64) []float64 {
+	if len(values) == 0 {
+		return values
+	}
+
+	min, max := values[0], values[0]
+	for _, value := range values {
+		if value < min {
+			min = value
+		}
+		if value > max {
+			max = value
+		}
+	}
+
+	normalised := make([]float64, len(values))
+	if max == min {
+		return normalised
+	}
+	for i, value := range values {
+		normalised[i] = (value - min) / (max - min)
+	}
+	return normalised
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package carrierpricing
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"testing"
+)
+
+func TestPriceListOptionsApply(t *testing.T) {
+	priceList := PriceByCarrierList{
+		PriceByCarrier{CarrierName: "Hercules", Amount: gbp(400), DeliveryTime: workingDays(5)},
+		PriceByCarrier{CarrierName: "CollectTimes", Amount: gbp(600), DeliveryTime: workingDays(1)},
+		PriceByCarrier{CarrierName: "RoyalPackages", Amount: gbp(500), DeliveryTime: workingDays(2)},
+		PriceByCarrier{CarrierName: "OOPS", Amount: gbp(400), DeliveryTime: workingDays(3)},
+		PriceByCarrier{CarrierName: "Ampersand", Amount: gbp(600), DeliveryTime: workingDays(1)},
+	}
+
+	priceWeight := func(weight float64) *float64 { return &weight }
+	maxDeliveryTime := workingDays(2)
+
+	tests := []struct {
+		Options          PriceListOptions
+		ExpectedCarriers []string
+	}{
+		// case #1 by price, ties broken by delivery time and carrier name
+		{
+			Options:          PriceListOptions{},
+			ExpectedCarriers: []string{"OOPS", "Hercules", "RoyalPackages", "Ampersand", "CollectTimes"},
+		},
+		// case #2 by delivery time, ties broken by price and carrier name
+		{
+			Options:          PriceListOptions{SortBy: SortByDeliveryTime},
+			ExpectedCarriers: []string{"Ampersand", "CollectTimes", "RoyalPackages", "OOPS", "Hercules"},
+		},
+		// case #3 by carrier name, case insensitive
+		{
+			Options:          PriceListOptions{SortBy: SortByCarrierName},
+			ExpectedCarriers: []string{"Ampersand", "CollectTimes", "Hercules", "OOPS", "RoyalPackages"},
+		},
+		// case #4 by score, price and speed weighted the same
+		{
+			Options:          PriceListOptions{SortBy: SortByScore},
+			ExpectedCarriers: []string{"OOPS", "RoyalPackages", "Hercules", "Ampersand", "CollectTimes"},
+		},
+		// case #5 by score, only price matters
+		{
+			Options:          PriceListOptions{SortBy: SortByScore, PriceWeight: priceWeight(1)},
+			ExpectedCarriers: []string{"OOPS", "Hercules", "RoyalPackages", "Ampersand", "CollectTimes"},
+		},
+		// case #6 max price
+		{
+			Options:          PriceListOptions{MaxPrice: 500},
+			ExpectedCarriers: []string{"OOPS", "Hercules", "RoyalPackages"},
+		},
+		// case #7 max delivery time
+		{
+			Options:          PriceListOptions{MaxDeliveryTime: &maxDeliveryTime},
+			ExpectedCarriers: []string{"RoyalPackages", "Ampersand", "CollectTimes"},
+		},
+		// case #8 allowed carriers
+		{
+			Options:          PriceListOptions{Carriers: []string{"hercules", "CollectTimes"}},
+			ExpectedCarriers: []string{"Hercules", "CollectTimes"},
+		},
+		// case #9 excluded carriers
+		{
+			Options:          PriceListOptions{ExcludedCarriers: []string{"OOPS", "Ampersand"}},
+			ExpectedCarriers: []string{"Hercules", "RoyalPackages", "CollectTimes"},
+		},
+		// case #10 no carrier passes the filters
+		{
+			Options:          PriceListOptions{MaxPrice: 100},
+			ExpectedCarriers: []string{},
+		},
+	}
+
+	for i, tc := range tests {
+		result := tc.Options.apply(priceList)
+
+		carriers := []string{}
+		for _, priceByCarrier := range result {
+			carriers = append(carriers, priceByCarrier.CarrierName)
+		}
+
+		if !reflect.DeepEqual(tc.ExpectedCarriers, carriers) {
+			t.Fatalf("case #%d: expected carriers '%v', received: '%v'", i+1, tc.ExpectedCarriers, carriers)
+		}
+	}
+
+	if priceList[0].CarrierName != "Hercules" {
+		t.Fatal("expected apply not to modify the given price list")
+	}
+}
+
+func TestGetQuotesByCarrierPriceListOptions(t *testing.T) {
+	negativeWeight := -0.5
+	invalidDeliveryTime := NewDeliveryDuration(1, "weeks")
+
+	tests := []struct {
+		Options          PriceListOptions
+		ExpectedCarriers []string
+		ExpectedError    error
+	}{
+		// case #1 filters apply to the displayed prices
+		{
+			Options:          PriceListOptions{MaxPrice: 421},
+			ExpectedCarriers: []string{"MockService2"},
+		},
+		// case #2 sorting
+		{
+			Options:          PriceListOptions{SortBy: SortByDeliveryTime},
+			ExpectedCarriers: []string{"MockService1", "MockService2"},
+		},
+		// case #3 unknown sort order
+		{
+			Options:       PriceListOptions{SortBy: "rating"},
+			ExpectedError: errors.New("invalid sort_by provided \"rating\""),
+		},
+		// case #4 invalid price weight
+		{
+			Options:       PriceListOptions{SortBy: SortByScore, PriceWeight: &negativeWeight},
+			ExpectedError: errors.New("price_weight must be between 0 and 1"),
+		},
+		// case #5 invalid max delivery time
+		{
+			Options:       PriceListOptions{MaxDeliveryTime: &invalidDeliveryTime},
+			ExpectedError: errors.New("max_delivery_time: invalid delivery time unit \"weeks\""),
+		},
+	}
+
+	for i, tc := range tests {
+		logger := log.New(ioutil.Discard, "", 0)
+		service, err := NewService(logger, &mockCarrierServiceFinder{}, &mockDistanceCalculator{}, nil)
+		if err != nil {
+			t.Fatalf("NewService returned error %v", err)
+		}
+
+		result, err := service.GetQuotesByCarrier(context.Background(), GetQuotesByCarrierArgs{
+			PickupPostcode:   "SW1A1AA",
+			DeliveryPostcode: "EC2A3LT",
+			Vehicle:          VehicleTypeSmallVan,
+			PriceListOptions: tc.Options,
+		})
+
+		if tc.ExpectedError != nil {
+			if err == nil || err.Error() != tc.ExpectedError.Error() {
+				t.Fatalf("case #%d: expected error '%v', received: '%v'", i+1, tc.ExpectedError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("case #%d: GetQuotesByCarrier returned error %v", i+1, err)
+		}
+
+		carriers := []string{}
+		for _, priceByCarrier := range result.PriceList {
+			carriers = append(carriers, priceByCarrier.CarrierName)
+		}
+
+		if !reflect.DeepEqual(tc.ExpectedCarriers, carriers) {
+			t.Fatalf("case #%d: expected carriers '%v', received: '%v'", i+1, tc.ExpectedCarriers, carriers)
+		}
+	}
+}
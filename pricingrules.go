@@ -0,0 +1,281 @@
+package carrierpricing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+)
+
+// RoundingMode defines how fractional prices are rounded to an integer amount.
+type RoundingMode string
+
+const (
+	// RoundingModeHalfEven rounds to the nearest integer, ties to the even one.
+	RoundingModeHalfEven RoundingMode = "half_even"
+
+	// RoundingModeHalfUp rounds to the nearest integer, ties away from zero.
+	RoundingModeHalfUp RoundingMode = "half_up"
+
+	// RoundingModeDown always rounds towards zero.
+	RoundingModeDown RoundingMode = "down"
+
+	// RoundingModeUp always rounds away from zero.
+	RoundingModeUp RoundingMode = "up"
+)
+
+var errInvalidRoundingMode = errors.New("invalid rounding mode")
+
+// Round rounds the given value according to the RoundingMode.
+func (rm RoundingMode) Round(value float64) float64 {
+	switch rm {
+	case RoundingModeHalfUp:
+		return math.Round(value)
+	case RoundingModeDown:
+		return math.Trunc(value)
+	case RoundingModeUp:
+		if value < 0 {
+			return math.Floor(value)
+		}
+		return math.Ceil(value)
+	default:
+		return math.RoundToEven(value)
+	}
+}
+
+func (rm RoundingMode) isValid() bool {
+	switch rm {
+	case RoundingModeHalfEven, RoundingModeHalfUp, RoundingModeDown, RoundingModeUp:
+		return true
+	}
+	return false
+}
+
+// PricingRules contains all the parameters used by the Service to calculate prices.
+type PricingRules struct {
+	// Currency is the ISO 4217 code of the currency prices are calculated in;
+	// MinimumCharge and all the prices per unit are expressed in its minor unit.
+	Currency string `json:"currency"`
+
+	// VehicleMultipliers indicates the markup to be applied to the base price for
+	// each vehicle type; all the ValidVehicleTypes must be listed.
+	VehicleMultipliers map[string]float64 `json:"vehicle_multipliers"`
+
+	// VehicleCapacities indicates the maximum weight and volume each vehicle type
+	// can carry; all the ValidVehicleTypes must be listed.
+	VehicleCapacities map[string]VehicleCapacity `json:"vehicle_capacities"`
+
+	// PricePerKilometre is the price applied for each kilometre between the
+	// pickup and the delivery postcodes, before any markup.
+	PricePerKilometre float64 `json:"price_per_km"`
+
+	// PricePerKilogram is the price applied for each kilogram of the chargeable
+	// weight of the parcel, before any markup.
+	PricePerKilogram float64 `json:"price_per_kg"`
+
+	// VolumetricDivisor is used to calculate the volumetric weight of a parcel,
+	// dividing its volume in cubic centimetres; when zero, only the actual weight
+	// of the parcel is charged.
+	VolumetricDivisor float64 `json:"volumetric_divisor"`
+
+	// MinimumCharge is the lowest base price of a delivery, regardless of its distance.
+	MinimumCharge int64 `json:"minimum_charge"`
+
+	// Rounding is the RoundingMode applied whenever a price has a fractional part.
+	Rounding RoundingMode `json:"rounding"`
+
+	// TaxRates indicates the tax rate (e.g. 0.2 for 20%) applied to the prices of
+	// the deliveries in each jurisdiction; jurisdictions not listed here are not taxed.
+	TaxRates map[string]float64 `json:"tax_rates"`
+
+	// Surcharges lists the surcharges applied according to the pickup time, when
+	// known; all the rules matching the pickup time apply.
+	Surcharges []SurchargeRule `json:"surcharges"`
+}
+
+// DefaultPricingRules returns the PricingRules used when no rules are provided.
+func DefaultPricingRules() *PricingRules {
+	return &PricingRules{
+		Currency: CurrencyGBP,
+		VehicleMultipliers: map[string]float64{
+			VehicleTypeBicycle:   1.1,
+			VehicleTypeMotorbike: 1.15,
+			VehicleTypeParcelCar: 1.2,
+			VehicleTypeSmallVan:  1.3,
+			VehicleTypeLargeVan:  1.4,
+		},
+		VehicleCapacities: map[string]VehicleCapacity{
+			VehicleTypeBicycle:   {MaxWeightKg: 8, MaxVolumeLitres: 40},
+			VehicleTypeMotorbike: {MaxWeightKg: 15, MaxVolumeLitres: 80},
+			VehicleTypeParcelCar: {MaxWeightKg: 100, MaxVolumeLitres: 1000},
+			VehicleTypeSmallVan:  {MaxWeightKg: 500, MaxVolumeLitres: 4000},
+			VehicleTypeLargeVan:  {MaxWeightKg: 1200, MaxVolumeLitres: 12000},
+		},
+		PricePerKilometre: 100,
+		PricePerKilogram:  10,
+		VolumetricDivisor: 5000,
+		MinimumCharge:     0,
+		Rounding:          RoundingModeHalfEven,
+		TaxRates: map[string]float64{
+			JurisdictionGreatBritain:    0.2,
+			JurisdictionNorthernIreland: 0.2,
+			JurisdictionIsleOfMan:       0.2,
+			JurisdictionJersey:          0,
+			JurisdictionGuernsey:        0,
+		},
+		Surcharges: []SurchargeRule{
+			{Description: "evening collection", Period: SurchargePeriodTimeOfDay, From: "19:00", To: "07:00", Multiplier: 1.25},
+			{Description: "weekend collection", Period: SurchargePeriodWeekend, Multiplier: 1.5},
+			{Description: "bank holiday collection", Period: SurchargePeriodBankHoliday, FlatFee: 1500},
+		},
+	}
+}
+
+// LoadPricingRulesFromJSONFile reads PricingRules from a JSON encoded file from
+// local storage. An error is returned if the file is not found, it cannot be
+// decoded or the rules it contains are not valid.
+func LoadPricingRulesFromJSONFile(jsonFilePath string) (*PricingRules, error) {
+	jsonFileContent, err := ioutil.ReadFile(jsonFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := &PricingRules{}
+	err = json.Unmarshal(jsonFileContent, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	err = rules.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// Validate returns an error if the PricingRules cannot be used to calculate prices.
+func (pr *PricingRules) Validate() error {
+	if !IsValidCurrency(pr.Currency) {
+		return fmt.Errorf("pricing rules: %w %q", errInvalidCurrency, pr.Currency)
+	}
+
+	for vehicleType, multiplier := range pr.VehicleMultipliers {
+		if !isVehicleTypeKnown(vehicleType) {
+			return fmt.Errorf("pricing rules: %w %q", errInvalidVehicle, vehicleType)
+		}
+		if multiplier <= 0 || math.IsInf(multiplier, 0) || math.IsNaN(multiplier) {
+			return fmt.Errorf("pricing rules: multiplier for %s must be a positive number", vehicleType)
+		}
+	}
+
+	for _, vehicleType := range ValidVehicleTypes {
+		if _, exists := pr.VehicleMultipliers[vehicleType]; !exists {
+			return fmt.Errorf("pricing rules: missing multiplier for %s", vehicleType)
+		}
+	}
+
+	for vehicleType, capacity := range pr.VehicleCapacities {
+		if !isVehicleTypeKnown(vehicleType) {
+			return fmt.Errorf("pricing rules: %w %q", errInvalidVehicle, vehicleType)
+		}
+		if !isPositive(capacity.MaxWeightKg) || !isPositive(capacity.MaxVolumeLitres) {
+			return fmt.Errorf("pricing rules: capacity for %s must be made of positive numbers", vehicleType)
+		}
+	}
+
+	for _, vehicleType := range ValidVehicleTypes {
+		if _, exists := pr.VehicleCapacities[vehicleType]; !exists {
+			return fmt.Errorf("pricing rules: missing capacity for %s", vehicleType)
+		}
+	}
+
+	if !isNonNegative(pr.PricePerKilometre) {
+		return errors.New("pricing rules: price_per_km must be a non negative number")
+	}
+
+	if !isNonNegative(pr.PricePerKilogram) {
+		return errors.New("pricing rules: price_per_kg must be a non negative number")
+	}
+
+	if !isNonNegative(pr.VolumetricDivisor) {
+		return errors.New("pricing rules: volumetric_divisor must be a non negative number")
+	}
+
+	if pr.MinimumCharge < 0 {
+		return errors.New("pricing rules: minimum_charge must not be negative")
+	}
+
+	if !pr.Rounding.isValid() {
+		return fmt.Errorf("pricing rules: %w %q", errInvalidRoundingMode, pr.Rounding)
+	}
+
+	for jurisdiction, rate := range pr.TaxRates {
+		if !isJurisdictionKnown(jurisdiction) {
+			return fmt.Errorf("pricing rules: %w %q", errInvalidJurisdiction, jurisdiction)
+		}
+		if !isNonNegative(rate) {
+			return fmt.Errorf("pricing rules: tax rate for %s must be a non negative number", jurisdiction)
+		}
+	}
+
+	for i, surcharge := range pr.Surcharges {
+		if err := surcharge.validate(); err != nil {
+			return fmt.Errorf("pricing rules: surcharge #%d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// vehicleMultiplier returns the multiplier for the given vehicle type; valid
+// rules have a multiplier for all the ValidVehicleTypes.
+func (pr *PricingRules) vehicleMultiplier(vehicleType string) float64 {
+	return pr.VehicleMultipliers[vehicleType]
+}
+
+// basePrice adds up the given price components, rounding the result and
+// applying the minimum charge.
+func (pr *PricingRules) basePrice(components []float64) basePrice {
+	result := basePrice{}
+	for _, component := range components {
+		result.exact += component
+	}
+
+	result.amount = int64(pr.Rounding.Round(result.exact))
+	if result.amount < pr.MinimumCharge {
+		result.minimumChargeSurcharge = pr.MinimumCharge - result.amount
+		result.amount = pr.MinimumCharge
+	}
+
+	return result
+}
+
+// money returns a Money object for the given amount, in the currency of the rules.
+func (pr *PricingRules) money(amount int64) Money {
+	return NewMoney(amount, pr.Currency)
+}
+
+// vehicleCapacity returns the capacity for the given vehicle type; valid rules
+// have a capacity for all the ValidVehicleTypes.
+func (pr *PricingRules) vehicleCapacity(vehicleType string) VehicleCapacity {
+	return pr.VehicleCapacities[vehicleType]
+}
+
+func isNonNegative(value float64) bool {
+	return value >= 0 && !math.IsInf(value, 0) && !math.IsNaN(value)
+}
+
+func isPositive(value float64) bool {
+	return value > 0 && !math.IsInf(value, 0) && !math.IsNaN(value)
+}
+
+func isVehicleTypeKnown(vehicleType string) bool {
+	for _, validVehicleType := range ValidVehicleTypes {
+		if validVehicleType == vehicleType {
+			return true
+		}
+	}
+	return false
+}
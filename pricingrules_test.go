@@ -0,0 +1,199 @@
+package carrierpricing
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRoundingModeRound(t *testing.T) {
+	tests := []struct {
+		RoundingMode   RoundingMode
+		Value          float64
+		ExpectedResult float64
+	}{
+		{RoundingModeHalfEven, 2.5, 2},
+		{RoundingModeHalfEven, 3.5, 4},
+		{RoundingModeHalfUp, 2.5, 3},
+		{RoundingModeHalfUp, 2.4, 2},
+		{RoundingModeDown, 2.9, 2},
+		{RoundingModeUp, 2.1, 3},
+		{RoundingModeUp, -2.1, -3},
+	}
+
+	for _, tc := range tests {
+		result := tc.RoundingMode.Round(tc.Value)
+		if result != tc.ExpectedResult {
+			t.Fatalf(
+				"expected %s rounding of %v to be %v, received: %v\n",
+				tc.RoundingMode,
+				tc.Value,
+				tc.ExpectedResult,
+				result,
+			)
+		}
+	}
+}
+
+func TestPricingRulesValidate(t *testing.T) {
+	tests := []struct {
+		Mutate        func(rules *PricingRules)
+		ExpectedError string
+	}{
+		// case #1 default rules are valid
+		{
+			Mutate:        func(rules *PricingRules) {},
+			ExpectedError: "",
+		},
+		// case #2 unknown vehicle
+		{
+			Mutate:        func(rules *PricingRules) { rules.VehicleMultipliers["scooter"] = 1.1 },
+			ExpectedError: "pricing rules: invalid vehicle provided \"scooter\"",
+		},
+		// case #3 negative multiplier
+		{
+			Mutate:        func(rules *PricingRules) { rules.VehicleMultipliers[VehicleTypeBicycle] = -1 },
+			ExpectedError: "pricing rules: multiplier for bicycle must be a positive number",
+		},
+		// case #4 missing multiplier
+		{
+			Mutate:        func(rules *PricingRules) { delete(rules.VehicleMultipliers, VehicleTypeLargeVan) },
+			ExpectedError: "pricing rules: missing multiplier for large_van",
+		},
+		// case #5 missing capacity
+		{
+			Mutate:        func(rules *PricingRules) { delete(rules.VehicleCapacities, VehicleTypeMotorbike) },
+			ExpectedError: "pricing rules: missing capacity for motorbike",
+		},
+		// case #6 capacity without a maximum volume
+		{
+			Mutate: func(rules *PricingRules) {
+				rules.VehicleCapacities[VehicleTypeSmallVan] = VehicleCapacity{MaxWeightKg: 500}
+			},
+			ExpectedError: "pricing rules: capacity for small_van must be made of positive numbers",
+		},
+		// case #7 negative price per km
+		{
+			Mutate:        func(rules *PricingRules) { rules.PricePerKilometre = -1 },
+			ExpectedError: "pricing rules: price_per_km must be a non negative number",
+		},
+		// case #8 negative minimum charge
+		{
+			Mutate:        func(rules *PricingRules) { rules.MinimumCharge = -1 },
+			ExpectedError: "pricing rules: minimum_charge must not be negative",
+		},
+		// case #9 unknown rounding mode
+		{
+			Mutate:        func(rules *PricingRules) { rules.Rounding = "" },
+			ExpectedError: "pricing rules: invalid rounding mode \"\"",
+		},
+		// case #10 unknown currency
+		{
+			Mutate:        func(rules *PricingRules) { rules.Currency = "XYZ" },
+			ExpectedError: "pricing rules: invalid currency provided \"XYZ\"",
+		},
+		// case #11 unknown tax jurisdiction
+		{
+			Mutate:        func(rules *PricingRules) { rules.TaxRates["FR"] = 0.2 },
+			ExpectedError: "pricing rules: invalid tax jurisdiction provided \"FR\"",
+		},
+		// case #12 negative tax rate
+		{
+			Mutate:        func(rules *PricingRules) { rules.TaxRates[JurisdictionJersey] = -0.05 },
+			ExpectedError: "pricing rules: tax rate for JE must be a non negative number",
+		},
+		// case #13 unknown surcharge period
+		{
+			Mutate:        func(rules *PricingRules) { rules.Surcharges[1].Period = "night" },
+			ExpectedError: "pricing rules: surcharge #2: invalid surcharge period \"night\"",
+		},
+		// case #14 invalid surcharge time
+		{
+			Mutate:        func(rules *PricingRules) { rules.Surcharges[0].To = "7pm" },
+			ExpectedError: "pricing rules: surcharge #1: to: invalid time \"7pm\", expected HH:MM",
+		},
+		// case #15 surcharge multiplier discounting the price
+		{
+			Mutate:        func(rules *PricingRules) { rules.Surcharges[1].Multiplier = 0.9 },
+			ExpectedError: "pricing rules: surcharge #2: multiplier must be a number not lower than 1",
+		},
+	}
+
+	for i, tc := range tests {
+		rules := DefaultPricingRules()
+		tc.Mutate(rules)
+
+		err := rules.Validate()
+		if (err == nil && tc.ExpectedError != "") || (err != nil && err.Error() != tc.ExpectedError) {
+			t.Fatalf("case #%d: expected error '%s', received: '%v'", i+1, tc.ExpectedError, err)
+		}
+	}
+}
+
+func TestLoadPricingRulesFromJSONFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pricingrules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	validFilePath := filepath.Join(dir, "valid.json")
+	ioutil.WriteFile(validFilePath, []byte(`{
+		"currency": "EUR",
+		"vehicle_multipliers": {"bicycle": 1.05, "motorbike": 1.1, "parcel_car": 1.2, "small_van": 1.5, "large_van": 2},
+		"vehicle_capacities": {
+			"bicycle": {"max_weight_kg": 10, "max_volume_litres": 50},
+			"motorbike": {"max_weight_kg": 20, "max_volume_litres": 90},
+			"parcel_car": {"max_weight_kg": 100, "max_volume_litres": 1000},
+			"small_van": {"max_weight_kg": 500, "max_volume_litres": 4000},
+			"large_van": {"max_weight_kg": 1000, "max_volume_litres": 10000}
+		},
+		"price_per_km": 80,
+		"minimum_charge": 500,
+		"rounding": "half_up"
+	}`), 0644)
+
+	invalidFilePath := filepath.Join(dir, "invalid.json")
+	ioutil.WriteFile(invalidFilePath, []byte(`{"currency": "GBP", "rounding": "half_up", "vehicle_multipliers": {"smal_van": 1.3}}`), 0644)
+
+	rules, err := LoadPricingRulesFromJSONFile(validFilePath)
+	if err != nil {
+		t.Fatalf("LoadPricingRulesFromJSONFile returned error %v", err)
+	}
+
+	expectedRules := &PricingRules{
+		Currency: "EUR",
+		VehicleMultipliers: map[string]float64{
+			VehicleTypeBicycle:   1.05,
+			VehicleTypeMotorbike: 1.1,
+			VehicleTypeParcelCar: 1.2,
+			VehicleTypeSmallVan:  1.5,
+			VehicleTypeLargeVan:  2,
+		},
+		VehicleCapacities: map[string]VehicleCapacity{
+			VehicleTypeBicycle:   {MaxWeightKg: 10, MaxVolumeLitres: 50},
+			VehicleTypeMotorbike: {MaxWeightKg: 20, MaxVolumeLitres: 90},
+			VehicleTypeParcelCar: {MaxWeightKg: 100, MaxVolumeLitres: 1000},
+			VehicleTypeSmallVan:  {MaxWeightKg: 500, MaxVolumeLitres: 4000},
+			VehicleTypeLargeVan:  {MaxWeightKg: 1000, MaxVolumeLitres: 10000},
+		},
+		PricePerKilometre: 80,
+		MinimumCharge:     500,
+		Rounding:          RoundingModeHalfUp,
+	}
+	if !reflect.DeepEqual(expectedRules, rules) {
+		t.Fatalf("expected rules '%v', received: '%v'", expectedRules, rules)
+	}
+
+	_, err = LoadPricingRulesFromJSONFile(invalidFilePath)
+	if err == nil {
+		t.Fatal("expected LoadPricingRulesFromJSONFile to reject invalid rules")
+	}
+
+	_, err = LoadPricingRulesFromJSONFile(filepath.Join(dir, "missing.json"))
+	if err == nil {
+		t.Fatal("expected LoadPricingRulesFromJSONFile to return an error for a missing file")
+	}
+}
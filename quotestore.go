@@ -0,0 +1,158 @@
+package carrierpricing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// DefaultQuoteValidity is how long a stored quote can be retrieved for, unless
+// a different validity is given to WithQuoteStore.
+const DefaultQuoteValidity = 15 * time.Minute
+
+const (
+	quoteTypeBasic     = "basic"
+	quoteTypeByVehicle = "byvehicle"
+	quoteTypeByCarrier = "bycarrier"
+	quoteTypeBest      = "best"
+	quoteTypeShipment  = "shipment"
+	quoteTypeRoute     = "route"
+)
+
+var (
+	// ErrQuoteNotFound is returned when no quote has been stored with the given ID.
+	ErrQuoteNotFound = errors.New("quote not found")
+
+	// ErrQuoteExpired is returned when the quote with the given ID is no longer valid.
+	ErrQuoteExpired = errors.New("quote expired")
+
+	errQuoteStoreNotAvailable = errors.New("quotes are not stored")
+)
+
+// QuoteStore is a software service used to save the quotes computed by the
+// Service, so that they can be retrieved later on by their ID.
+// GetQuote must return ErrQuoteNotFound when no quote has the given ID.
+type QuoteStore interface {
+	SaveQuote(quote *StoredQuote) error
+	GetQuote(id string) (*StoredQuote, error)
+}
+
+// StoredQuote is a quote saved in a QuoteStore: the type of the quote, the time
+// it has been computed at and the one it expires at, the arguments used and the
+// response returned, both JSON encoded.
+type StoredQuote struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	ExpiresAt time.Time       `json:"expires_at"`
+	Args      json.RawMessage `json:"args"`
+	Response  json.RawMessage `json:"response"`
+}
+
+// QuoteReference identifies a quote saved in the QuoteStore of the Service and
+// tells until when it can be retrieved; it is empty when quotes are not stored.
+type QuoteReference struct {
+	QuoteID    string     `json:"quote_id,omitempty"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+}
+
+func (qr *QuoteReference) setQuoteReference(id string, validUntil time.Time) {
+	qr.QuoteID = id
+	qr.ValidUntil = &validUntil
+}
+
+// quoteResponse is implemented by all the responses embedding a QuoteReference.
+type quoteResponse interface {
+	setQuoteReference(id string, validUntil time.Time)
+}
+
+// WithQuoteStore sets the QuoteStore every quote is saved to, together with
+// the validity of the quotes; when validity is not positive, DefaultQuoteValidity
+// is used. Without it, quotes are computed and forgotten.
+func WithQuoteStore(quoteStore QuoteStore, validity time.Duration) ServiceOption {
+	return func(s *Service) {
+		if validity <= 0 {
+			validity = DefaultQuoteValidity
+		}
+		s.quoteStore = quoteStore
+		s.quoteValidity = validity
+	}
+}
+
+// GetQuote returns the quote saved with the given ID. ErrQuoteNotFound is
+// returned when there is no such quote, ErrQuoteExpired when it is no longer valid.
+func (s *Service) GetQuote(id string) (*StoredQuote, error) {
+	s.logger.Printf("executing GetQuote with args: %v\n", id)
+
+	if s.quoteStore == nil {
+		return nil, errQuoteStoreNotAvailable
+	}
+
+	quote, err := s.quoteStore.GetQuote(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.currentTime().Before(quote.ExpiresAt) {
+		return nil, ErrQuoteExpired
+	}
+
+	return quote, nil
+}
+
+// storeQuote saves the given response of a quote of the given type, computed
+// with the given arguments, setting its QuoteReference; nothing is done when
+// the Service has no QuoteStore.
+func (s *Service) storeQuote(quoteType string, args interface{}, response quoteResponse) error {
+	if s.quoteStore == nil {
+		return nil
+	}
+
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+
+	createdAt := s.currentTime()
+	expiresAt := createdAt.Add(s.quoteValidity)
+	response.setQuoteReference(id, expiresAt)
+
+	encodedArgs, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	encodedResponse, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+
+	return s.quoteStore.SaveQuote(&StoredQuote{
+		ID:        id,
+		Type:      quoteType,
+		CreatedAt: createdAt,
+		ExpiresAt: expiresAt,
+		Args:      encodedArgs,
+		Response:  encodedResponse,
+	})
+}
+
+// newID returns a random, hex encoded, ID for quotes and bookings.
+func newID() (string, error) {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// currentTime returns the current time, as given by the now function when set.
+func (s *Service) currentTime() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
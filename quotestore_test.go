@@ -0,0 +1,144 @@
+package carrierpricing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetQuote(t *testing.T) {
+	logger := log.New(ioutil.Discard, "", 0)
+	quoteStore := &mockQuoteStore{quotes: map[string]StoredQuote{}}
+	service, err := NewService(logger, &mockCarrierServiceFinder{}, &mockDistanceCalculator{}, nil, WithQuoteStore(quoteStore, 5*time.Minute))
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
+
+	now := time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	args := GetQuotesByVehicleArgs{
+		PickupPostcode:   "SW1A1AA",
+		DeliveryPostcode: "EC2A3LT",
+		Vehicle:          VehicleTypeSmallVan,
+	}
+
+	response, err := service.GetQuotesByVehicle(context.Background(), args)
+	if err != nil {
+		t.Fatalf("GetQuotesByVehicle returned error %v", err)
+	}
+
+	expectedValidUntil := now.Add(5 * time.Minute)
+	if response.QuoteID == "" || response.ValidUntil == nil || !response.ValidUntil.Equal(expectedValidUntil) {
+		t.Fatalf("expected a quote ID valid until %v, received: %v", expectedValidUntil, response.QuoteReference)
+	}
+
+	// the quote is retrieved as it has been returned, with the arguments used
+	now = now.Add(4 * time.Minute)
+	quote, err := service.GetQuote(response.QuoteID)
+	if err != nil {
+		t.Fatalf("GetQuote returned error %v", err)
+	}
+
+	if quote.ID != response.QuoteID || quote.Type != quoteTypeByVehicle || !quote.ExpiresAt.Equal(expectedValidUntil) {
+		t.Fatalf("unexpected quote %+v", quote)
+	}
+
+	storedResponse := &GetQuotesByVehicleResponse{}
+	if err := json.Unmarshal(quote.Response, storedResponse); err != nil {
+		t.Fatalf("cannot decode stored response: %v", err)
+	}
+	if storedResponse.QuoteID != response.QuoteID || storedResponse.Price != response.Price {
+		t.Fatalf("expected stored response '%+v', received: '%+v'", response, storedResponse)
+	}
+
+	storedArgs := GetQuotesByVehicleArgs{}
+	if err := json.Unmarshal(quote.Args, &storedArgs); err != nil {
+		t.Fatalf("cannot decode stored arguments: %v", err)
+	}
+	if !reflect.DeepEqual(args, storedArgs) {
+		t.Fatalf("expected stored arguments '%v', received: '%v'", args, storedArgs)
+	}
+
+	// the quote expires at the end of its validity
+	now = now.Add(time.Minute)
+	_, err = service.GetQuote(response.QuoteID)
+	if !errors.Is(err, ErrQuoteExpired) {
+		t.Fatalf("expected error '%v', received: '%v'", ErrQuoteExpired, err)
+	}
+
+	_, err = service.GetQuote("unknown")
+	if !errors.Is(err, ErrQuoteNotFound) {
+		t.Fatalf("expected error '%v', received: '%v'", ErrQuoteNotFound, err)
+	}
+}
+
+func TestGetQuoteWithoutQuoteStore(t *testing.T) {
+	// tests that quotes are not referenced when they are not stored
+	logger := log.New(ioutil.Discard, "", 0)
+	service, err := NewService(logger, &mockCarrierServiceFinder{}, &mockDistanceCalculator{}, nil)
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
+
+	response, err := service.GetBasicQuote(context.Background(), GetBasicQuoteArgs{
+		PickupPostcode:   "SW1A1AA",
+		DeliveryPostcode: "EC2A3LT",
+	})
+	if err != nil {
+		t.Fatalf("GetBasicQuote returned error %v", err)
+	}
+
+	if response.QuoteID != "" || response.ValidUntil != nil {
+		t.Fatalf("expected no quote reference, received: %v", response.QuoteReference)
+	}
+
+	_, err = service.GetQuote("unknown")
+	if err == nil || err.Error() != "quotes are not stored" {
+		t.Fatalf("expected error 'quotes are not stored', received: '%v'", err)
+	}
+}
+
+func TestStoreQuoteError(t *testing.T) {
+	// tests that quotes which cannot be stored are not returned
+	logger := log.New(ioutil.Discard, "", 0)
+	quoteStore := &mockQuoteStore{err: errors.New("disk full")}
+	service, err := NewService(logger, &mockCarrierServiceFinder{}, &mockDistanceCalculator{}, nil, WithQuoteStore(quoteStore, 0))
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
+
+	_, err = service.GetBasicQuote(context.Background(), GetBasicQuoteArgs{
+		PickupPostcode:   "SW1A1AA",
+		DeliveryPostcode: "EC2A3LT",
+	})
+	if err == nil || err.Error() != "disk full" {
+		t.Fatalf("expected error 'disk full', received: '%v'", err)
+	}
+}
+
+type mockQuoteStore struct {
+	quotes map[string]StoredQuote
+	err    error
+}
+
+func (mqs *mockQuoteStore) SaveQuote(quote *StoredQuote) error {
+	if mqs.err != nil {
+		return mqs.err
+	}
+	mqs.quotes[quote.ID] = *quote
+	return nil
+}
+
+func (mqs *mockQuoteStore) GetQuote(id string) (*StoredQuote, error) {
+	quote, exists := mqs.quotes[id]
+	if !exists {
+		return nil, ErrQuoteNotFound
+	}
+	return &quote, nil
+}
@@ -0,0 +1,39 @@
+package quotestores
+
+import (
+	"sync"
+	"time"
+)
+
+// evictionInterval is how often the stores delete the expired quotes, while
+// saving new ones.
+const evictionInterval = time.Minute
+
+// evictionSchedule tells a store when to delete the expired quotes, so that
+// they do not pile up forever without scanning all of them at every save.
+type evictionSchedule struct {
+	mutex       sync.Mutex
+	lastEvicted time.Time
+
+	// now, when set, replaces time.Now to test time dependent behaviours.
+	now func() time.Time
+}
+
+// due returns the current time and true when the expired quotes have not been
+// deleted for at least evictionInterval; the eviction is then considered done.
+func (es *evictionSchedule) due() (time.Time, bool) {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+
+	now := time.Now()
+	if es.now != nil {
+		now = es.now()
+	}
+
+	if now.Sub(es.lastEvicted) < evictionInterval {
+		return now, false
+	}
+
+	es.lastEvicted = now
+	return now, true
+}
@@ -0,0 +1,53 @@
+package quotestores
+
+import (
+	"sync"
+
+	"github.com/giefferre/carrierpricing"
+)
+
+// QSInMemory implements the carrierpricing.QuoteStore interface keeping the
+// quotes in memory; quotes are lost when the application stops. Expired quotes
+// are deleted while saving new ones, at most once per minute.
+type QSInMemory struct {
+	mutex    sync.RWMutex
+	quotes   map[string]carrierpricing.StoredQuote
+	eviction evictionSchedule
+}
+
+// NewQSInMemory returns a new, empty, QSInMemory object.
+func NewQSInMemory() *QSInMemory {
+	return &QSInMemory{
+		quotes: map[string]carrierpricing.StoredQuote{},
+	}
+}
+
+// SaveQuote stores a copy of the given quote.
+func (qs *QSInMemory) SaveQuote(quote *carrierpricing.StoredQuote) error {
+	qs.mutex.Lock()
+	defer qs.mutex.Unlock()
+
+	qs.quotes[quote.ID] = *quote
+
+	if now, due := qs.eviction.due(); due {
+		for id, storedQuote := range qs.quotes {
+			if !now.Before(storedQuote.ExpiresAt) {
+				delete(qs.quotes, id)
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetQuote returns a copy of the quote with the given ID.
+func (qs *QSInMemory) GetQuote(id string) (*carrierpricing.StoredQuote, error) {
+	qs.mutex.RLock()
+	defer qs.mutex.RUnlock()
+
+	quote, exists := qs.quotes[id]
+	if !exists {
+		return nil, carrierpricing.ErrQuoteNotFound
+	}
+	return &quote, nil
+}
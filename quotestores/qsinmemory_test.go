@@ -0,0 +1,105 @@
+package quotestores
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/giefferre/carrierpricing"
+)
+
+func TestQSInMemory(t *testing.T) {
+	qs := NewQSInMemory()
+
+	quote := &carrierpricing.StoredQuote{
+		ID:        "0a1b2c3d",
+		Type:      "basic",
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(15 * time.Minute),
+		Args:      json.RawMessage(`{"pickupPostcode":"SW1A1AA","deliveryPostcode":"EC2A3LT"}`),
+		Response:  json.RawMessage(`{"price":316}`),
+	}
+
+	err := qs.SaveQuote(quote)
+	if err != nil {
+		t.Fatalf("SaveQuote returned error %v", err)
+	}
+
+	// changes to the saved quote do not affect the stored copy
+	quote.Type = "byvehicle"
+
+	storedQuote, err := qs.GetQuote("0a1b2c3d")
+	if err != nil {
+		t.Fatalf("GetQuote returned error %v", err)
+	}
+	if storedQuote.Type != "basic" || !reflect.DeepEqual(storedQuote.Response, json.RawMessage(`{"price":316}`)) {
+		t.Fatalf("expected the quote as saved, received: %+v", storedQuote)
+	}
+
+	_, err = qs.GetQuote("ffffffff")
+	if err != carrierpricing.ErrQuoteNotFound {
+		t.Fatalf("expected error '%v', received: '%v'", carrierpricing.ErrQuoteNotFound, err)
+	}
+}
+
+func TestQSInMemoryEviction(t *testing.T) {
+	now := time.Date(2026, time.October, 15, 10, 0, 0, 0, time.UTC)
+
+	qs := NewQSInMemory()
+	qs.eviction.now = func() time.Time { return now }
+
+	tests := []struct {
+		Description      string
+		Elapsed          time.Duration
+		ID               string
+		ExpectedQuoteIDs []string
+	}{
+		{
+			Description:      "first quote saved",
+			ID:               "01",
+			ExpectedQuoteIDs: []string{"01"},
+		},
+		{
+			Description:      "first quote expired, not evicted until the next eviction is due",
+			Elapsed:          40 * time.Second,
+			ID:               "02",
+			ExpectedQuoteIDs: []string{"01", "02"},
+		},
+		{
+			Description:      "first quote evicted",
+			Elapsed:          25 * time.Second,
+			ID:               "03",
+			ExpectedQuoteIDs: []string{"02", "03"},
+		},
+		{
+			Description:      "all the previous quotes evicted",
+			Elapsed:          2 * evictionInterval,
+			ID:               "04",
+			ExpectedQuoteIDs: []string{"04"},
+		},
+	}
+
+	for _, tc := range tests {
+		now = now.Add(tc.Elapsed)
+
+		err := qs.SaveQuote(&carrierpricing.StoredQuote{ID: tc.ID, CreatedAt: now, ExpiresAt: now.Add(30 * time.Second)})
+		if err != nil {
+			t.Fatalf("%s: SaveQuote returned error %v", tc.Description, err)
+		}
+
+		for _, id := range []string{"01", "02", "03", "04"} {
+			_, err := qs.GetQuote(id)
+			expected := false
+			for _, expectedID := range tc.ExpectedQuoteIDs {
+				expected = expected || expectedID == id
+			}
+			if expected && err != nil {
+				t.Fatalf("%s: expected quote %s to be stored, received error '%v'", tc.Description, id, err)
+			}
+			if !expected && err != carrierpricing.ErrQuoteNotFound {
+				t.Fatalf("%s: expected quote %s to be evicted, received error '%v'", tc.Description, id, err)
+			}
+		}
+	}
+}
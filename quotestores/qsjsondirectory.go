@@ -0,0 +1,140 @@
+package quotestores
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/giefferre/carrierpricing"
+)
+
+// QSJSONDirectory implements the carrierpricing.QuoteStore interface saving each
+// quote as a JSON encoded file, named after its ID, in a directory from local
+// storage; quotes survive restarts of the application. The files of expired
+// quotes are deleted while saving new ones, at most once per minute.
+type QSJSONDirectory struct {
+	directory string
+	eviction  evictionSchedule
+}
+
+// NewQSJSONDirectory returns a new QSJSONDirectory object saving quotes in the
+// given directory, which is created when missing. An error is returned if the
+// directory cannot be created.
+func NewQSJSONDirectory(directory string) (*QSJSONDirectory, error) {
+	err := os.MkdirAll(directory, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &QSJSONDirectory{
+		directory: directory,
+	}, nil
+}
+
+// SaveQuote writes the given quote to its file; the file is written under a
+// temporary name first, so that a quote is never read while partially written.
+func (qs *QSJSONDirectory) SaveQuote(quote *carrierpricing.StoredQuote) error {
+	if !isValidQuoteID(quote.ID) {
+		return fmt.Errorf("invalid quote ID %q", quote.ID)
+	}
+
+	content, err := json.Marshal(quote)
+	if err != nil {
+		return err
+	}
+
+	temporaryFile, err := ioutil.TempFile(qs.directory, "."+quote.ID+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporaryFile.Name())
+
+	_, err = temporaryFile.Write(content)
+	if closeErr := temporaryFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(temporaryFile.Name(), qs.path(quote.ID))
+	if err != nil {
+		return err
+	}
+
+	if now, due := qs.eviction.due(); due {
+		qs.evictExpiredQuotes(now)
+	}
+
+	return nil
+}
+
+// GetQuote reads the quote with the given ID from its file.
+func (qs *QSJSONDirectory) GetQuote(id string) (*carrierpricing.StoredQuote, error) {
+	if !isValidQuoteID(id) {
+		return nil, carrierpricing.ErrQuoteNotFound
+	}
+
+	content, err := ioutil.ReadFile(qs.path(id))
+	if os.IsNotExist(err) {
+		return nil, carrierpricing.ErrQuoteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	quote := &carrierpricing.StoredQuote{}
+	err = json.Unmarshal(content, quote)
+	if err != nil {
+		return nil, fmt.Errorf("quote %s: %v", id, err)
+	}
+
+	return quote, nil
+}
+
+// evictExpiredQuotes deletes the files of the quotes expired at the given time;
+// files which are not quotes, or cannot be read, are left untouched.
+func (qs *QSJSONDirectory) evictExpiredQuotes(now time.Time) {
+	files, err := ioutil.ReadDir(qs.directory)
+	if err != nil {
+		return
+	}
+
+	for _, file := range files {
+		id := strings.TrimSuffix(file.Name(), ".json")
+		if file.IsDir() || id == file.Name() || !isValidQuoteID(id) {
+			continue
+		}
+
+		quote, err := qs.GetQuote(id)
+		if err != nil {
+			continue
+		}
+
+		if !now.Before(quote.ExpiresAt) {
+			os.Remove(qs.path(id))
+		}
+	}
+}
+
+func (qs *QSJSONDirectory) path(id string) string {
+	return filepath.Join(qs.directory, id+".json")
+}
+
+// isValidQuoteID returns true if the given ID can be safely used as a file name:
+// IDs generated by the carrierpricing Service are made of hexadecimal digits only.
+func isValidQuoteID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= '0' && r <= '9') && !(r >= 'a' && r <= 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,232 @@
+package quotestores
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/giefferre/carrierpricing"
+)
+
+func TestQSJSONDirectory(t *testing.T) {
+	directory, err := ioutil.TempDir("", "qsjsondirectory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	// the directory is created when missing
+	qs, err := NewQSJSONDirectory(filepath.Join(directory, "quotes"))
+	if err != nil {
+		t.Fatalf("NewQSJSONDirectory returned error %v", err)
+	}
+
+	quote := &carrierpricing.StoredQuote{
+		ID:        "0a1b2c3d",
+		Type:      "basic",
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		ExpiresAt: time.Now().UTC().Truncate(time.Second).Add(15 * time.Minute),
+		Args:      json.RawMessage(`{"pickupPostcode":"SW1A1AA","deliveryPostcode":"EC2A3LT"}`),
+		Response:  json.RawMessage(`{"price":316}`),
+	}
+
+	err = qs.SaveQuote(quote)
+	if err != nil {
+		t.Fatalf("SaveQuote returned error %v", err)
+	}
+
+	storedQuote, err := qs.GetQuote("0a1b2c3d")
+	if err != nil {
+		t.Fatalf("GetQuote returned error %v", err)
+	}
+	if !reflect.DeepEqual(quote, storedQuote) {
+		t.Fatalf("expected quote '%+v', received: '%+v'", quote, storedQuote)
+	}
+
+	// saving again replaces the file, without leaving temporary files behind
+	quote.Response = json.RawMessage(`{"price":411}`)
+	err = qs.SaveQuote(quote)
+	if err != nil {
+		t.Fatalf("SaveQuote returned error %v", err)
+	}
+
+	storedQuote, err = qs.GetQuote("0a1b2c3d")
+	if err != nil {
+		t.Fatalf("GetQuote returned error %v", err)
+	}
+	if !reflect.DeepEqual(quote, storedQuote) {
+		t.Fatalf("expected quote '%+v', received: '%+v'", quote, storedQuote)
+	}
+
+	files, err := ioutil.ReadDir(filepath.Join(directory, "quotes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != "0a1b2c3d.json" {
+		t.Fatalf("expected only the file of the quote, received: %v", fileNames(files))
+	}
+
+	_, err = qs.GetQuote("ffffffff")
+	if err != carrierpricing.ErrQuoteNotFound {
+		t.Fatalf("expected error '%v' for a missing quote, received: '%v'", carrierpricing.ErrQuoteNotFound, err)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(directory, "quotes", "abcdef.json"), []byte(`{"id": "abc`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = qs.GetQuote("abcdef")
+	if err == nil || errors.Is(err, carrierpricing.ErrQuoteNotFound) {
+		t.Fatalf("expected a decoding error for a corrupted quote, received: '%v'", err)
+	}
+}
+
+func TestQSJSONDirectoryQuoteID(t *testing.T) {
+	tests := []struct {
+		ID            string
+		ExpectedValid bool
+	}{
+		// case #1 generated ID
+		{ID: "9f86d081884c7d65", ExpectedValid: true},
+		// case #2 empty ID
+		{ID: "", ExpectedValid: false},
+		// case #3 upper case digits
+		{ID: "9F86D081", ExpectedValid: false},
+		// case #4 not hexadecimal
+		{ID: "quote", ExpectedValid: false},
+		// case #5 path traversal
+		{ID: "../9f86d081", ExpectedValid: false},
+		// case #6 path separator
+		{ID: "9f86/d081", ExpectedValid: false},
+		// case #7 file extension
+		{ID: "9f86d081.json", ExpectedValid: false},
+	}
+
+	directory, err := ioutil.TempDir("", "qsjsondirectory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	quotesDirectory := filepath.Join(directory, "quotes")
+	qs, err := NewQSJSONDirectory(quotesDirectory)
+	if err != nil {
+		t.Fatalf("NewQSJSONDirectory returned error %v", err)
+	}
+
+	for i, tc := range tests {
+		err = qs.SaveQuote(&carrierpricing.StoredQuote{ID: tc.ID, ExpiresAt: time.Now().Add(time.Minute)})
+		if tc.ExpectedValid && err != nil {
+			t.Fatalf("case #%d: SaveQuote returned error %v", i+1, err)
+		}
+		if !tc.ExpectedValid && err == nil {
+			t.Fatalf("case #%d: expected SaveQuote to reject the ID", i+1)
+		}
+
+		_, err = qs.GetQuote(tc.ID)
+		if tc.ExpectedValid && err != nil {
+			t.Fatalf("case #%d: GetQuote returned error %v", i+1, err)
+		}
+		if !tc.ExpectedValid && err != carrierpricing.ErrQuoteNotFound {
+			t.Fatalf("case #%d: expected error '%v', received: '%v'", i+1, carrierpricing.ErrQuoteNotFound, err)
+		}
+	}
+
+	// nothing has been written outside of the directory of the quotes
+	files, err := ioutil.ReadDir(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != "quotes" {
+		t.Fatalf("expected only the directory of the quotes, received: %v", fileNames(files))
+	}
+}
+
+func TestQSJSONDirectoryEviction(t *testing.T) {
+	now := time.Date(2026, time.October, 15, 10, 0, 0, 0, time.UTC)
+
+	directory, err := ioutil.TempDir("", "qsjsondirectory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	qs, err := NewQSJSONDirectory(directory)
+	if err != nil {
+		t.Fatalf("NewQSJSONDirectory returned error %v", err)
+	}
+	qs.eviction.now = func() time.Time { return now }
+
+	// files which are not quotes are never deleted
+	err = ioutil.WriteFile(filepath.Join(directory, "README.md"), []byte("quotes"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(directory, "bad.json"), []byte("{"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Description   string
+		Elapsed       time.Duration
+		ID            string
+		ExpectedFiles []string
+	}{
+		{
+			Description:   "first quote saved",
+			ID:            "01",
+			ExpectedFiles: []string{"01.json", "README.md", "bad.json"},
+		},
+		{
+			Description:   "first quote expired, not evicted until the next eviction is due",
+			Elapsed:       40 * time.Second,
+			ID:            "02",
+			ExpectedFiles: []string{"01.json", "02.json", "README.md", "bad.json"},
+		},
+		{
+			Description:   "first quote evicted",
+			Elapsed:       25 * time.Second,
+			ID:            "03",
+			ExpectedFiles: []string{"02.json", "03.json", "README.md", "bad.json"},
+		},
+		{
+			Description:   "all the previous quotes evicted",
+			Elapsed:       2 * evictionInterval,
+			ID:            "04",
+			ExpectedFiles: []string{"04.json", "README.md", "bad.json"},
+		},
+	}
+
+	for _, tc := range tests {
+		now = now.Add(tc.Elapsed)
+
+		err := qs.SaveQuote(&carrierpricing.StoredQuote{ID: tc.ID, CreatedAt: now, ExpiresAt: now.Add(30 * time.Second)})
+		if err != nil {
+			t.Fatalf("%s: SaveQuote returned error %v", tc.Description, err)
+		}
+
+		files, err := ioutil.ReadDir(directory)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if names := fileNames(files); !reflect.DeepEqual(names, tc.ExpectedFiles) {
+			t.Fatalf("%s: expected files %v, received: %v", tc.Description, tc.ExpectedFiles, names)
+		}
+	}
+}
+
+func fileNames(files []os.FileInfo) []string {
+	names := []string{}
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,142 @@
+package carrierpricing
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// RecommendationCheapest recommends the cheapest carrier.
+	RecommendationCheapest = "cheapest"
+
+	// RecommendationFastest recommends the carrier delivering first.
+	RecommendationFastest = "fastest"
+
+	// RecommendationCheapestWithinSLA recommends the cheapest carrier delivering
+	// within the requested SLA, or the fastest one when none does.
+	RecommendationCheapestWithinSLA = "cheapest_within_sla"
+
+	// RecommendationBestScore recommends the carrier with the best score, weighting
+	// price and speed as the SortByScore sorting does.
+	RecommendationBestScore = "best_score"
+)
+
+var (
+	errInvalidRecommendation = errors.New("invalid recommendation provided")
+	errMissingSLA            = errors.New("sla must be provided to recommend the cheapest carrier within it")
+)
+
+// RecommendationOptions choose how the carrier recommended by the GetQuotesByCarrier
+// method is selected. Recommendation is one of RecommendationCheapest (the default),
+// RecommendationFastest, RecommendationCheapestWithinSLA and RecommendationBestScore;
+// SLA is the delivery time required by RecommendationCheapestWithinSLA.
+type RecommendationOptions struct {
+	Recommendation string            `json:"recommendation,omitempty"`
+	SLA            *DeliveryDuration `json:"sla,omitempty"`
+}
+
+// RecommendedCarrier is the carrier service recommended in the
+// GetQuotesByCarrierResponse, with the ServiceID needed to book it, its
+// displayed price, its delivery time and the reason why it has been
+// selected.
+type RecommendedCarrier struct {
+	CarrierName       string           `json:"service"`
+	ServiceID         string           `json:"service_id"`
+	Amount            Money            `json:"price"`
+	DeliveryTime      DeliveryDuration `json:"delivery_time"`
+	EstimatedDelivery *DeliveryWindow  `json:"estimated_delivery,omitempty"`
+	Reason            string           `json:"reason"`
+}
+
+// validate returns an error if the RecommendationOptions cannot be applied.
+func (ro RecommendationOptions) validate() error {
+	switch ro.Recommendation {
+	case "", RecommendationCheapest, RecommendationFastest, RecommendationBestScore:
+	case RecommendationCheapestWithinSLA:
+		if ro.SLA == nil {
+			return errMissingSLA
+		}
+	default:
+		return fmt.Errorf("%w %q", errInvalidRecommendation, ro.Recommendation)
+	}
+
+	if ro.SLA != nil {
+		if err := ro.SLA.Validate(); err != nil {
+			return fmt.Errorf("sla: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// recommend returns the carrier of the given price list to be recommended,
+// according to the RecommendationOptions; the price weight is the one used to
+// sort the list by score. Nil is returned when the price list is empty.
+func (ro RecommendationOptions) recommend(priceList PriceByCarrierList, listOptions PriceListOptions) *RecommendedCarrier {
+	if len(priceList) == 0 {
+		return nil
+	}
+
+	switch ro.Recommendation {
+	case RecommendationFastest:
+		return newRecommendedCarrier(priceList[fastestIndex(priceList)], "fastest carrier")
+	case RecommendationBestScore:
+		scores := listOptions.scores(priceList)
+		best := 0
+		for i := range priceList {
+			if scores[i] < scores[best] || (scores[i] == scores[best] && priceList.Less(i, best)) {
+				best = i
+			}
+		}
+		return newRecommendedCarrier(priceList[best], "best balance of price and delivery time")
+	case RecommendationCheapestWithinSLA:
+		cheapest := -1
+		for i, priceByCarrier := range priceList {
+			if priceByCarrier.DeliveryTime.Nominal() > ro.SLA.Nominal() {
+				continue
+			}
+			if cheapest == -1 || priceList.Less(i, cheapest) {
+				cheapest = i
+			}
+		}
+		if cheapest == -1 {
+			return newRecommendedCarrier(
+				priceList[fastestIndex(priceList)],
+				fmt.Sprintf("no carrier delivering within %s, fastest carrier", ro.SLA),
+			)
+		}
+		return newRecommendedCarrier(priceList[cheapest], fmt.Sprintf("cheapest carrier delivering within %s", ro.SLA))
+	default:
+		cheapest := 0
+		for i := range priceList {
+			if priceList.Less(i, cheapest) {
+				cheapest = i
+			}
+		}
+		return newRecommendedCarrier(priceList[cheapest], "cheapest carrier")
+	}
+}
+
+// fastestIndex returns the index of the first carrier of the given price list
+// to deliver; ties are broken as PriceByCarrierList does.
+func fastestIndex(priceList PriceByCarrierList) int {
+	delivery := deliveryKeys(priceList)
+	fastest := 0
+	for i := range priceList {
+		if delivery[i] < delivery[fastest] || (delivery[i] == delivery[fastest] && priceList.Less(i, fastest)) {
+			fastest = i
+		}
+	}
+	return fastest
+}
+
+func newRecommendedCarrier(priceByCarrier PriceByCarrier, reason string) *RecommendedCarrier {
+	return &RecommendedCarrier{
+		CarrierName:       priceByCarrier.CarrierName,
+		ServiceID:         priceByCarrier.ServiceID,
+		Amount:            priceByCarrier.Amount,
+		DeliveryTime:      priceByCarrier.DeliveryTime,
+		EstimatedDelivery: priceByCarrier.EstimatedDelivery,
+		Reason:            reason,
+	}
+}
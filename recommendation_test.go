@@ -0,0 +1,159 @@
+package carrierpricing
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"testing"
+)
+
+func TestRecommendationOptionsRecommend(t *testing.T) {
+	priceList := PriceByCarrierList{
+		PriceByCarrier{CarrierName: "Hercules", Amount: gbp(400), DeliveryTime: workingDays(5)},
+		PriceByCarrier{CarrierName: "RoyalPackages", Amount: gbp(500), DeliveryTime: workingDays(2)},
+		PriceByCarrier{CarrierName: "CollectTimes", Amount: gbp(600), DeliveryTime: workingDays(1)},
+		PriceByCarrier{CarrierName: "Ampersand", Amount: gbp(600), DeliveryTime: workingDays(1)},
+	}
+
+	twoDays := workingDays(2)
+	sameDay := NewDeliveryDuration(4, DeliveryTimeUnitHours)
+
+	tests := []struct {
+		Options         RecommendationOptions
+		ExpectedCarrier string
+		ExpectedReason  string
+	}{
+		// case #1 cheapest by default
+		{
+			Options:         RecommendationOptions{},
+			ExpectedCarrier: "Hercules",
+			ExpectedReason:  "cheapest carrier",
+		},
+		// case #2 fastest, ties broken by carrier name
+		{
+			Options:         RecommendationOptions{Recommendation: RecommendationFastest},
+			ExpectedCarrier: "Ampersand",
+			ExpectedReason:  "fastest carrier",
+		},
+		// case #3 cheapest within SLA
+		{
+			Options:         RecommendationOptions{Recommendation: RecommendationCheapestWithinSLA, SLA: &twoDays},
+			ExpectedCarrier: "RoyalPackages",
+			ExpectedReason:  "cheapest carrier delivering within 2 working_days",
+		},
+		// case #4 no carrier within SLA, the fastest is recommended
+		{
+			Options:         RecommendationOptions{Recommendation: RecommendationCheapestWithinSLA, SLA: &sameDay},
+			ExpectedCarrier: "Ampersand",
+			ExpectedReason:  "no carrier delivering within 4 hours, fastest carrier",
+		},
+		// case #5 best score
+		{
+			Options:         RecommendationOptions{Recommendation: RecommendationBestScore},
+			ExpectedCarrier: "RoyalPackages",
+			ExpectedReason:  "best balance of price and delivery time",
+		},
+	}
+
+	for i, tc := range tests {
+		result := tc.Options.recommend(priceList, PriceListOptions{})
+		if result == nil {
+			t.Fatalf("case #%d: expected a recommended carrier", i+1)
+		}
+		if result.CarrierName != tc.ExpectedCarrier || result.Reason != tc.ExpectedReason {
+			t.Fatalf(
+				"case #%d: expected %s (%s), received: %s (%s)",
+				i+1,
+				tc.ExpectedCarrier,
+				tc.ExpectedReason,
+				result.CarrierName,
+				result.Reason,
+			)
+		}
+	}
+
+	if result := (RecommendationOptions{}).recommend(PriceByCarrierList{}, PriceListOptions{}); result != nil {
+		t.Fatalf("expected no recommended carrier for an empty price list, received: %v", result)
+	}
+}
+
+func TestGetQuotesByCarrierRecommended(t *testing.T) {
+	oneDay := workingDays(1)
+
+	tests := []struct {
+		Arguments       GetQuotesByCarrierArgs
+		ExpectedPrice   Money
+		ExpectedCarrier string
+		ExpectedError   error
+	}{
+		// case #1 cheapest within SLA, displaying gross prices
+		{
+			Arguments: GetQuotesByCarrierArgs{
+				PriceDisplay:          PriceDisplayGross,
+				RecommendationOptions: RecommendationOptions{Recommendation: RecommendationCheapestWithinSLA, SLA: &oneDay},
+			},
+			ExpectedPrice:   gbp(517),
+			ExpectedCarrier: "MockService1",
+		},
+		// case #2 no carrier passes the filters
+		{
+			Arguments: GetQuotesByCarrierArgs{
+				PriceListOptions: PriceListOptions{MaxPrice: 100},
+			},
+			ExpectedPrice: Money{},
+		},
+		// case #3 missing SLA
+		{
+			Arguments: GetQuotesByCarrierArgs{
+				RecommendationOptions: RecommendationOptions{Recommendation: RecommendationCheapestWithinSLA},
+			},
+			ExpectedError: errors.New("sla must be provided to recommend the cheapest carrier within it"),
+		},
+		// case #4 unknown strategy
+		{
+			Arguments: GetQuotesByCarrierArgs{
+				RecommendationOptions: RecommendationOptions{Recommendation: "cheapest_ever"},
+			},
+			ExpectedError: errors.New("invalid recommendation provided \"cheapest_ever\""),
+		},
+	}
+
+	for i, tc := range tests {
+		logger := log.New(ioutil.Discard, "", 0)
+		service, err := NewService(logger, &mockCarrierServiceFinder{}, &mockDistanceCalculator{}, nil)
+		if err != nil {
+			t.Fatalf("NewService returned error %v", err)
+		}
+
+		tc.Arguments.PickupPostcode = "SW1A1AA"
+		tc.Arguments.DeliveryPostcode = "EC2A3LT"
+		tc.Arguments.Vehicle = VehicleTypeSmallVan
+
+		result, err := service.GetQuotesByCarrier(context.Background(), tc.Arguments)
+
+		if tc.ExpectedError != nil {
+			if err == nil || err.Error() != tc.ExpectedError.Error() {
+				t.Fatalf("case #%d: expected error '%v', received: '%v'", i+1, tc.ExpectedError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("case #%d: GetQuotesByCarrier returned error %v", i+1, err)
+		}
+
+		if result.Price != tc.ExpectedPrice {
+			t.Fatalf("case #%d: expected price %v, received: %v", i+1, tc.ExpectedPrice, result.Price)
+		}
+
+		if tc.ExpectedCarrier == "" {
+			if result.Recommended != nil {
+				t.Fatalf("case #%d: expected no recommended carrier, received: %v", i+1, result.Recommended)
+			}
+			continue
+		}
+		if result.Recommended == nil || result.Recommended.CarrierName != tc.ExpectedCarrier {
+			t.Fatalf("case #%d: expected %s to be recommended, received: %v", i+1, tc.ExpectedCarrier, result.Recommended)
+		}
+	}
+}
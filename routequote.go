@@ -0,0 +1,315 @@
+package carrierpricing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/giefferre/carrierpricing/postcode"
+)
+
+// maxRouteStops is the maximum number of delivery postcodes accepted for a route.
+const maxRouteStops = 25
+
+// maxStopsForExhaustiveSearch is the maximum number of delivery postcodes for
+// which every possible order is evaluated when optimising a route; longer
+// routes are optimised heuristically.
+const maxStopsForExhaustiveSearch = 8
+
+var errNoDeliveryPostcodes = errors.New("at least one delivery postcode must be provided")
+
+// GetRouteQuoteArgs contains arguments for the GetRouteQuote method.
+// When OptimiseOrder is true, delivery postcodes may be visited in a different
+// order than the given one, so that the total distance is minimised.
+// When IncludeBreakdown is true, the prices are itemised.
+// When Currency is set, prices are converted to it.
+// PriceDisplay chooses whether prices are displayed before (PriceDisplayNet, the
+// default) or including taxes (PriceDisplayGross).
+// When PickupTime is set, the surcharges of the period it falls in are applied.
+type GetRouteQuoteArgs struct {
+	PickupPostcode    string     `json:"pickup_postcode"`
+	DeliveryPostcodes []string   `json:"delivery_postcodes"`
+	Vehicle           string     `json:"vehicle"`
+	Parcel            *Parcel    `json:"parcel,omitempty"`
+	PickupTime        *time.Time `json:"pickup_time,omitempty"`
+	OptimiseOrder     bool       `json:"optimise_order"`
+	IncludeBreakdown  bool       `json:"include_breakdown"`
+	Currency          string     `json:"currency,omitempty"`
+	PriceDisplay      string     `json:"price_display,omitempty"`
+}
+
+// GetRouteQuoteResponse is the response object for the GetRouteQuote method.
+// DeliveryPostcodes are listed in the order they are visited.
+// ExchangeRate is set when prices have been converted to the requested currency.
+// The Breakdown, when requested, itemises the price before taxes.
+// QuoteReference is set when quotes are stored.
+type GetRouteQuoteResponse struct {
+	QuoteReference
+	PickupPostcode    string            `json:"pickup_postcode"`
+	DeliveryPostcodes []string          `json:"delivery_postcodes"`
+	Vehicle           string            `json:"vehicle"`
+	Legs              []RouteLeg        `json:"legs"`
+	DistanceKm        float64           `json:"distance_km"`
+	Price             Money             `json:"price"`
+	Surcharges        []PriceAdjustment `json:"surcharges,omitempty"`
+	TaxedAmounts
+	TaxJurisdiction string             `json:"tax_jurisdiction"`
+	TaxRate         float64            `json:"tax_rate"`
+	Breakdown       *PriceBreakdown    `json:"breakdown,omitempty"`
+	PriceList       PriceByCarrierList `json:"price_list"`
+	ExchangeRate    *ExchangeRate      `json:"exchange_rate,omitempty"`
+}
+
+// RouteLeg is the object returned in the GetRouteQuoteResponse indicating
+// a single trip between two consecutive stops of the route.
+type RouteLeg struct {
+	FromPostcode string  `json:"from_postcode"`
+	ToPostcode   string  `json:"to_postcode"`
+	DistanceKm   float64 `json:"distance_km"`
+}
+
+// GetRouteQuote calculates the price of a route starting from the pickup post code
+// and going through all the delivery post codes, using a specific vehicle and all
+// the available carriers. The base price is calculated over the total distance of
+// the route; vehicle and carrier markups are applied once for the whole route.
+func (s *Service) GetRouteQuote(ctx context.Context, args GetRouteQuoteArgs) (*GetRouteQuoteResponse, error) {
+	s.logger.Printf("executing GetRouteQuote with args: %v\n", args)
+
+	rules := s.PricingRules()
+
+	if !s.isVehicleValid(args.Vehicle) {
+		return nil, invalidArgs(errInvalidVehicle)
+	}
+
+	if len(args.DeliveryPostcodes) == 0 {
+		return nil, invalidArgs(errNoDeliveryPostcodes)
+	}
+
+	if len(args.DeliveryPostcodes) > maxRouteStops {
+		return nil, invalidArgs(fmt.Errorf("at most %d delivery postcodes can be provided", maxRouteStops))
+	}
+
+	err := s.checkVehicleCapacity(rules, args.Vehicle, args.Parcel)
+	if err != nil {
+		return nil, err
+	}
+
+	exchangeRate, err := s.exchangeRate(rules, args.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	// stops[0] is the pickup, the following ones are the deliveries
+	stops := make([]*postcode.Postcode, 0, len(args.DeliveryPostcodes)+1)
+	for _, rawPostcode := range append([]string{args.PickupPostcode}, args.DeliveryPostcodes...) {
+		stop, err := postcode.Parse(rawPostcode)
+		if err != nil {
+			return nil, invalidArgs(err)
+		}
+		stops = append(stops, stop)
+	}
+
+	taxation, err := newTaxation(rules, args.PriceDisplay, stops...)
+	if err != nil {
+		return nil, err
+	}
+
+	distances, err := s.calculateDistanceMatrix(stops)
+	if err != nil {
+		return nil, err
+	}
+
+	order := make([]int, len(args.DeliveryPostcodes))
+	for i := range order {
+		order[i] = i + 1
+	}
+	if args.OptimiseOrder {
+		order = optimiseRoute(distances, order)
+	}
+
+	legs := make([]RouteLeg, 0, len(order))
+	deliveryPostcodes := make([]string, 0, len(order))
+	var totalDistance float64
+
+	previousStop := 0
+	for _, stop := range order {
+		legs = append(legs, RouteLeg{
+			FromPostcode: stops[previousStop].String(),
+			ToPostcode:   stops[stop].String(),
+			DistanceKm:   roundDistance(distances[previousStop][stop]),
+		})
+		deliveryPostcodes = append(deliveryPostcodes, stops[stop].String())
+		totalDistance += distances[previousStop][stop]
+		previousStop = stop
+	}
+
+	components := []float64{totalDistance * rules.PricePerKilometre}
+	if args.Parcel != nil {
+		components = append(components, args.Parcel.ChargeableWeightKg(rules.VolumetricDivisor)*rules.PricePerKilogram)
+	}
+
+	basePrice := rules.basePrice(components)
+	priceByVehicle := s.applyVehicleMarkup(rules, basePrice.amount, args.Vehicle)
+	surcharges := s.calculateSurcharges(rules, args.PickupTime, stops[0], priceByVehicle)
+	price := priceByVehicle + sumOfAdjustments(surcharges)
+
+	deliveries := make([]postcode.Postcode, 0, len(stops)-1)
+	for _, stop := range stops[1:] {
+		deliveries = append(deliveries, *stop)
+	}
+
+	availableCarrierServices, err := s.findCarrierServices(ctx,
+		newCarrierQuery(args.Vehicle, stops[0], deliveries, args.Parcel, args.PickupTime),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(availableCarrierServices) == 0 {
+		return nil, invalidArgs(errNoAvailableCarrierServicesForVehicle)
+	}
+
+	var breakdown *PriceBreakdown
+	if args.IncludeBreakdown {
+		breakdown = newPriceBreakdown(rules, basePrice, args.Vehicle, surcharges, price)
+	}
+
+	priceList := s.getPriceListFromPriceAndCarrierServices(rules, price, breakdown, availableCarrierServices, stops[0], args.PickupTime)
+	amounts := taxation.apply(exchangeRate.convert(rules.money(price), rules.Rounding))
+
+	response := &GetRouteQuoteResponse{
+		PickupPostcode:    stops[0].String(),
+		DeliveryPostcodes: deliveryPostcodes,
+		Vehicle:           args.Vehicle,
+		Legs:              legs,
+		DistanceKm:        roundDistance(totalDistance),
+		Price:             taxation.display(amounts),
+		Surcharges:        exchangeRate.convertAdjustments(surcharges, rules.Rounding),
+		TaxedAmounts:      amounts,
+		TaxJurisdiction:   taxation.jurisdiction,
+		TaxRate:           taxation.rate,
+		Breakdown:         exchangeRate.convertBreakdown(breakdown, rules.Rounding),
+		PriceList:         taxation.applyToPriceList(exchangeRate.convertPriceList(priceList, rules.Rounding)),
+		ExchangeRate:      exchangeRate,
+	}
+
+	err = s.storeQuote(quoteTypeRoute, args, response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// calculateDistanceMatrix returns the distance between each pair of the given stops.
+func (s *Service) calculateDistanceMatrix(stops []*postcode.Postcode) ([][]float64, error) {
+	distances := make([][]float64, len(stops))
+	for i := range distances {
+		distances[i] = make([]float64, len(stops))
+	}
+
+	for i := range stops {
+		for j := i + 1; j < len(stops); j++ {
+			distance, err := s.distanceCalculator.CalculateDistance(*stops[i], *stops[j])
+			if err != nil {
+				// the stops cannot be located
+				return nil, invalidArgs(err)
+			}
+			distances[i][j] = distance
+			distances[j][i] = distance
+		}
+	}
+
+	return distances, nil
+}
+
+// optimiseRoute returns the order in which the given stops should be visited,
+// starting from stop 0, so that the total distance is minimised. Every possible
+// order is evaluated for short routes, while longer ones are built visiting the
+// nearest stop first and then improved by reversing segments of the route (2-opt).
+func optimiseRoute(distances [][]float64, stops []int) []int {
+	if len(stops) <= maxStopsForExhaustiveSearch {
+		best := make([]int, len(stops))
+		copy(best, stops)
+		bestDistance := routeDistance(distances, best)
+
+		permute(stops, 0, func(candidate []int) {
+			if candidateDistance := routeDistance(distances, candidate); candidateDistance < bestDistance {
+				bestDistance = candidateDistance
+				copy(best, candidate)
+			}
+		})
+
+		return best
+	}
+
+	// nearest neighbour
+	route := make([]int, 0, len(stops))
+	visited := make(map[int]bool, len(stops))
+	current := 0
+	for len(route) < len(stops) {
+		next := -1
+		for _, stop := range stops {
+			if visited[stop] {
+				continue
+			}
+			if next == -1 || distances[current][stop] < distances[current][next] {
+				next = stop
+			}
+		}
+		route = append(route, next)
+		visited[next] = true
+		current = next
+	}
+
+	// 2-opt
+	for improved := true; improved; {
+		improved = false
+		for i := 0; i < len(route)-1; i++ {
+			for j := i + 1; j < len(route); j++ {
+				candidate := make([]int, len(route))
+				copy(candidate, route)
+				for left, right := i, j; left < right; left, right = left+1, right-1 {
+					candidate[left], candidate[right] = candidate[right], candidate[left]
+				}
+				if routeDistance(distances, candidate) < routeDistance(distances, route) {
+					route = candidate
+					improved = true
+				}
+			}
+		}
+	}
+
+	return route
+}
+
+// permute calls visit for each permutation of stops[k:].
+func permute(stops []int, k int, visit func([]int)) {
+	if k == len(stops) {
+		visit(stops)
+		return
+	}
+
+	for i := k; i < len(stops); i++ {
+		stops[k], stops[i] = stops[i], stops[k]
+		permute(stops, k+1, visit)
+		stops[k], stops[i] = stops[i], stops[k]
+	}
+}
+
+// routeDistance returns the total distance of a route starting from stop 0.
+func routeDistance(distances [][]float64, route []int) float64 {
+	var total float64
+	previousStop := 0
+	for _, stop := range route {
+		total += distances[previousStop][stop]
+		previousStop = stop
+	}
+	return total
+}
+
+// roundDistance rounds a distance, in kilometres, to the nearest metre.
+func roundDistance(distance float64) float64 {
+	return math.Round(distance*1000) / 1000
+}
@@ -0,0 +1,168 @@
+package carrierpricing
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/giefferre/carrierpricing/postcode"
+)
+
+func TestGetRouteQuote(t *testing.T) {
+	tests := []struct {
+		Arguments      GetRouteQuoteArgs
+		ExpectedResult *GetRouteQuoteResponse
+		ExpectedError  error
+	}{
+		// case #1 invalid Vehicle argument
+		{
+			Arguments: GetRouteQuoteArgs{
+				PickupPostcode:    "SW1A1AA",
+				DeliveryPostcodes: []string{"EC2A3LT"},
+				Vehicle:           "scooter",
+			},
+			ExpectedResult: nil,
+			ExpectedError:  errors.New("invalid vehicle provided"),
+		},
+		// case #2 no delivery postcodes
+		{
+			Arguments: GetRouteQuoteArgs{
+				PickupPostcode: "SW1A1AA",
+				Vehicle:        "small_van",
+			},
+			ExpectedResult: nil,
+			ExpectedError:  errors.New("at least one delivery postcode must be provided"),
+		},
+		// case #3 invalid delivery postcode
+		{
+			Arguments: GetRouteQuoteArgs{
+				PickupPostcode:    "SW1A1AA",
+				DeliveryPostcodes: []string{"EC2A3LT", "_"},
+				Vehicle:           "small_van",
+			},
+			ExpectedResult: nil,
+			ExpectedError:  errors.New("invalid postcode \"_\": postcode has an invalid length"),
+		},
+		// case #4 stops visited in the given order
+		{
+			Arguments: GetRouteQuoteArgs{
+				PickupPostcode:    "SW1A1AA",
+				DeliveryPostcodes: []string{"EC2A3LT", "E16AN", "N19GU"},
+				Vehicle:           "small_van",
+			},
+			ExpectedResult: &GetRouteQuoteResponse{
+				PickupPostcode:    "SW1A 1AA",
+				DeliveryPostcodes: []string{"EC2A 3LT", "E1 6AN", "N1 9GU"},
+				Vehicle:           "small_van",
+				Legs: []RouteLeg{
+					RouteLeg{FromPostcode: "SW1A 1AA", ToPostcode: "EC2A 3LT", DistanceKm: 10},
+					RouteLeg{FromPostcode: "EC2A 3LT", ToPostcode: "E1 6AN", DistanceKm: 6},
+					RouteLeg{FromPostcode: "E1 6AN", ToPostcode: "N1 9GU", DistanceKm: 8},
+				},
+				DistanceKm:      24,
+				Price:           gbp(3120),
+				TaxedAmounts:    taxedGBP(3120, 624),
+				TaxJurisdiction: JurisdictionGreatBritain,
+				TaxRate:         0.2,
+				PriceList: PriceByCarrierList{
+					PriceByCarrier{CarrierName: "MockService2", ServiceID: "1", Amount: gbp(3130), TaxedAmounts: taxedGBP(3130, 626), DeliveryTime: workingDays(5)},
+					PriceByCarrier{CarrierName: "MockService1", ServiceID: "1", Amount: gbp(3140), TaxedAmounts: taxedGBP(3140, 628), DeliveryTime: workingDays(1)},
+				},
+			},
+			ExpectedError: nil,
+		},
+		// case #5 stops reordered to minimise the distance
+		{
+			Arguments: GetRouteQuoteArgs{
+				PickupPostcode:    "SW1A1AA",
+				DeliveryPostcodes: []string{"EC2A3LT", "E16AN", "N19GU"},
+				Vehicle:           "small_van",
+				OptimiseOrder:     true,
+			},
+			ExpectedResult: &GetRouteQuoteResponse{
+				PickupPostcode:    "SW1A 1AA",
+				DeliveryPostcodes: []string{"E1 6AN", "EC2A 3LT", "N1 9GU"},
+				Vehicle:           "small_van",
+				Legs: []RouteLeg{
+					RouteLeg{FromPostcode: "SW1A 1AA", ToPostcode: "E1 6AN", DistanceKm: 4},
+					RouteLeg{FromPostcode: "E1 6AN", ToPostcode: "EC2A 3LT", DistanceKm: 6},
+					RouteLeg{FromPostcode: "EC2A 3LT", ToPostcode: "N1 9GU", DistanceKm: 2},
+				},
+				DistanceKm:      12,
+				Price:           gbp(1560),
+				TaxedAmounts:    taxedGBP(1560, 312),
+				TaxJurisdiction: JurisdictionGreatBritain,
+				TaxRate:         0.2,
+				PriceList: PriceByCarrierList{
+					PriceByCarrier{CarrierName: "MockService2", ServiceID: "1", Amount: gbp(1570), TaxedAmounts: taxedGBP(1570, 314), DeliveryTime: workingDays(5)},
+					PriceByCarrier{CarrierName: "MockService1", ServiceID: "1", Amount: gbp(1580), TaxedAmounts: taxedGBP(1580, 316), DeliveryTime: workingDays(1)},
+				},
+			},
+			ExpectedError: nil,
+		},
+	}
+
+	logger := log.New(ioutil.Discard, "", 0)
+	service, err := NewService(logger, &mockCarrierServiceFinder{}, &mockLineDistanceCalculator{
+		"SW1A": 0,
+		"E1":   4,
+		"EC2A": 10,
+		"N1":   12,
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
+
+	for _, tc := range tests {
+		result, err := service.GetRouteQuote(context.Background(), tc.Arguments)
+		if (tc.ExpectedError != nil && err == nil) ||
+			(tc.ExpectedError == nil && err != nil) ||
+			(tc.ExpectedError != nil && err != nil && tc.ExpectedError.Error() != err.Error()) {
+			t.Fatalf(
+				"expected error '%v', received: '%v'\n",
+				tc.ExpectedError,
+				err,
+			)
+		}
+		if !reflect.DeepEqual(tc.ExpectedResult, result) {
+			t.Fatalf(
+				"expected result '%v', received: '%v'\n",
+				tc.ExpectedResult,
+				result,
+			)
+		}
+	}
+}
+
+func TestOptimiseRouteHeuristic(t *testing.T) {
+	// tests that routes too long for the exhaustive search are optimised too;
+	// stops lie on a line, so the best route visits them sorted by position
+	positions := []float64{0, 7, 3, 10, 1, 9, 2, 8, 4, 6, 5}
+
+	distances := make([][]float64, len(positions))
+	for i := range positions {
+		distances[i] = make([]float64, len(positions))
+		for j := range positions {
+			distances[i][j] = math.Abs(positions[i] - positions[j])
+		}
+	}
+
+	stops := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	route := optimiseRoute(distances, stops)
+
+	if routeDistance(distances, route) != 10 {
+		t.Fatalf("expected a route 10 long, received %v with distance %v", route, routeDistance(distances, route))
+	}
+}
+
+// mockLineDistanceCalculator places each postcode district on a line,
+// at the given position in kilometres.
+type mockLineDistanceCalculator map[string]float64
+
+func (mldc mockLineDistanceCalculator) CalculateDistance(pickup, delivery postcode.Postcode) (float64, error) {
+	return math.Abs(mldc[pickup.District] - mldc[delivery.District]), nil
+}
@@ -1,11 +1,14 @@
 package carrierpricing
 
 import (
+	"context"
 	"errors"
 	"log"
-	"math"
 	"sort"
-	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/giefferre/carrierpricing/postcode"
 )
 
 const (
@@ -34,220 +37,640 @@ var ValidVehicleTypes = []string{
 	VehicleTypeLargeVan,
 }
 
-// VehiclesMarkupTable indicates the markup to be applied to the base price for each vehicle type.
-var VehiclesMarkupTable = map[string]float64{
-	VehicleTypeBicycle:   1.1,
-	VehicleTypeMotorbike: 1.15,
-	VehicleTypeParcelCar: 1.2,
-	VehicleTypeSmallVan:  1.3,
-	VehicleTypeLargeVan:  1.4,
-}
-
 var (
 	errInvalidVehicle                       = errors.New("invalid vehicle provided")
 	errMarkupNotPresent                     = errors.New("markup not present")
 	errNoAvailableCarrierServicesForVehicle = errors.New("no available carrier services for the given vehicle")
 )
 
+// InvalidArgsError is returned by the methods of the Service when the given
+// args cannot be quoted, e.g. because of an invalid postcode or a parcel too
+// heavy for the vehicle, as opposed to the failures of the Service itself.
+type InvalidArgsError struct {
+	Err error
+}
+
+func (e *InvalidArgsError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InvalidArgsError) Unwrap() error {
+	return e.Err
+}
+
+// invalidArgs returns the given error as an *InvalidArgsError, nil if it is nil.
+func invalidArgs(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &InvalidArgsError{Err: err}
+}
+
 // GetBasicQuoteArgs contains arguments for the GetBasicQuote method.
+// When IncludeBreakdown is true, the response itemises how the price has been calculated.
+// When Currency is set, prices are converted to it.
+// PriceDisplay chooses whether prices are displayed before (PriceDisplayNet, the
+// default) or including taxes (PriceDisplayGross).
+// When PickupTime is set, the surcharges of the period it falls in are applied.
 type GetBasicQuoteArgs struct {
-	PickupPostcode   string `json:"pickup_postcode"`
-	DeliveryPostcode string `json:"delivery_postcode"`
+	PickupPostcode   string     `json:"pickup_postcode"`
+	DeliveryPostcode string     `json:"delivery_postcode"`
+	Parcel           *Parcel    `json:"parcel,omitempty"`
+	PickupTime       *time.Time `json:"pickup_time,omitempty"`
+	IncludeBreakdown bool       `json:"include_breakdown"`
+	Currency         string     `json:"currency,omitempty"`
+	PriceDisplay     string     `json:"price_display,omitempty"`
 }
 
 // GetBasicQuoteResponse is the response object for the GetBasicQuote method.
+// ExchangeRate is set when prices have been converted to the requested currency.
+// The Breakdown, when requested, itemises the price before taxes.
+// QuoteReference is set when quotes are stored.
 type GetBasicQuoteResponse struct {
-	PickupPostcode   string `json:"pickup_postcode"`
-	DeliveryPostcode string `json:"delivery_postcode"`
-	Price            int64  `json:"price"`
+	QuoteReference
+	PickupPostcode   string            `json:"pickup_postcode"`
+	DeliveryPostcode string            `json:"delivery_postcode"`
+	Price            Money             `json:"price"`
+	Surcharges       []PriceAdjustment `json:"surcharges,omitempty"`
+	TaxedAmounts
+	TaxJurisdiction string          `json:"tax_jurisdiction"`
+	TaxRate         float64         `json:"tax_rate"`
+	Breakdown       *PriceBreakdown `json:"breakdown,omitempty"`
+	ExchangeRate    *ExchangeRate   `json:"exchange_rate,omitempty"`
 }
 
 // GetQuotesByVehicleArgs contains arguments for the GetQuotesByVehicle method.
+// When IncludeBreakdown is true, the response itemises how the price has been calculated.
+// When Currency is set, prices are converted to it.
+// PriceDisplay chooses whether prices are displayed before (PriceDisplayNet, the
+// default) or including taxes (PriceDisplayGross).
+// When PickupTime is set, the surcharges of the period it falls in are applied.
 type GetQuotesByVehicleArgs struct {
-	PickupPostcode   string `json:"pickup_postcode"`
-	DeliveryPostcode string `json:"delivery_postcode"`
-	Vehicle          string `json:"vehicle"`
+	PickupPostcode   string     `json:"pickup_postcode"`
+	DeliveryPostcode string     `json:"delivery_postcode"`
+	Vehicle          string     `json:"vehicle"`
+	Parcel           *Parcel    `json:"parcel,omitempty"`
+	PickupTime       *time.Time `json:"pickup_time,omitempty"`
+	IncludeBreakdown bool       `json:"include_breakdown"`
+	Currency         string     `json:"currency,omitempty"`
+	PriceDisplay     string     `json:"price_display,omitempty"`
 }
 
 // GetQuotesByVehicleResponse is the response object for the GetQuotesByVehicle method.
+// ExchangeRate is set when prices have been converted to the requested currency.
+// The Breakdown, when requested, itemises the price before taxes.
+// QuoteReference is set when quotes are stored.
 type GetQuotesByVehicleResponse struct {
-	PickupPostcode   string `json:"pickup_postcode"`
-	DeliveryPostcode string `json:"delivery_postcode"`
-	Vehicle          string `json:"vehicle"`
-	Price            int64  `json:"price"`
+	QuoteReference
+	PickupPostcode   string            `json:"pickup_postcode"`
+	DeliveryPostcode string            `json:"delivery_postcode"`
+	Vehicle          string            `json:"vehicle"`
+	Price            Money             `json:"price"`
+	Surcharges       []PriceAdjustment `json:"surcharges,omitempty"`
+	TaxedAmounts
+	TaxJurisdiction string          `json:"tax_jurisdiction"`
+	TaxRate         float64         `json:"tax_rate"`
+	Breakdown       *PriceBreakdown `json:"breakdown,omitempty"`
+	ExchangeRate    *ExchangeRate   `json:"exchange_rate,omitempty"`
 }
 
 // GetQuotesByCarrierArgs contains arguments for the GetQuotesByCarrier method.
-type GetQuotesByCarrierArgs GetQuotesByVehicleArgs
+// When IncludeBreakdown is true, each price of the list is itemised.
+// When Currency is set, prices are converted to it.
+// PriceDisplay chooses whether prices are displayed before (PriceDisplayNet, the
+// default) or including taxes (PriceDisplayGross).
+// When PickupTime is set, the surcharges of the period it falls in are applied
+// and the delivery by each carrier is estimated.
+// PriceListOptions sort and filter the price list, while RecommendationOptions
+// choose the carrier to be recommended among the ones listed.
+type GetQuotesByCarrierArgs struct {
+	PickupPostcode   string     `json:"pickup_postcode"`
+	DeliveryPostcode string     `json:"delivery_postcode"`
+	Vehicle          string     `json:"vehicle"`
+	Parcel           *Parcel    `json:"parcel,omitempty"`
+	PickupTime       *time.Time `json:"pickup_time,omitempty"`
+	IncludeBreakdown bool       `json:"include_breakdown"`
+	Currency         string     `json:"currency,omitempty"`
+	PriceDisplay     string     `json:"price_display,omitempty"`
+	PriceListOptions
+	RecommendationOptions
+}
 
 // GetQuotesByCarrierResponse is the response object for the GetQuotesByCarrier method.
+// Price is the price of the Recommended carrier; both are not set when no carrier
+// is listed. ExchangeRate is set when prices have been converted to the requested
+// currency.
+// QuoteReference is set when quotes are stored.
 type GetQuotesByCarrierResponse struct {
-	PickupPostcode   string             `json:"pickup_postcode"`
-	DeliveryPostcode string             `json:"delivery_postcode"`
-	Vehicle          string             `json:"vehicle"`
-	Price            int64              `json:"price"`
-	PriceList        PriceByCarrierList `json:"price_list"`
+	QuoteReference
+	PickupPostcode   string              `json:"pickup_postcode"`
+	DeliveryPostcode string              `json:"delivery_postcode"`
+	Vehicle          string              `json:"vehicle"`
+	Price            Money               `json:"price"`
+	Recommended      *RecommendedCarrier `json:"recommended,omitempty"`
+	Surcharges       []PriceAdjustment   `json:"surcharges,omitempty"`
+	TaxJurisdiction  string              `json:"tax_jurisdiction"`
+	TaxRate          float64             `json:"tax_rate"`
+	PriceList        PriceByCarrierList  `json:"price_list"`
+	ExchangeRate     *ExchangeRate       `json:"exchange_rate,omitempty"`
 }
 
 // PriceByCarrier is the object returned in the GetQuotesByCarrierResponse
 // indicating the service price and delivery time for a specific carrier
-// matching the request.
+// matching the request. EstimatedDelivery is set when the pickup time is known.
+// The Breakdown, when requested, itemises the price before taxes. ServiceID
+// identifies the service among the ones of the same carrier, e.g. to book it.
 type PriceByCarrier struct {
-	CarrierName  string `json:"service"`
-	Amount       int64  `json:"price"`
-	DeliveryTime int64  `json:"delivery_time"`
+	CarrierName string `json:"service"`
+	ServiceID   string `json:"service_id"`
+	Amount      Money  `json:"price"`
+	TaxedAmounts
+	DeliveryTime      DeliveryDuration `json:"delivery_time"`
+	EstimatedDelivery *DeliveryWindow  `json:"estimated_delivery,omitempty"`
+	Breakdown         *PriceBreakdown  `json:"breakdown,omitempty"`
 }
 
 // PriceByCarrierList is a list of PriceByCarrier.
-// This struct has been created to apply sorting convenience methods.
+// This struct has been created to apply sorting convenience methods: the list
+// is sorted by price, then by delivery time, then by carrier name and service ID.
 type PriceByCarrierList []PriceByCarrier
 
-func (pbcl PriceByCarrierList) Len() int           { return len(pbcl) }
-func (pbcl PriceByCarrierList) Swap(i, j int)      { pbcl[i], pbcl[j] = pbcl[j], pbcl[i] }
-func (pbcl PriceByCarrierList) Less(i, j int) bool { return pbcl[i].Amount < pbcl[j].Amount }
+func (pbcl PriceByCarrierList) Len() int      { return len(pbcl) }
+func (pbcl PriceByCarrierList) Swap(i, j int) { pbcl[i], pbcl[j] = pbcl[j], pbcl[i] }
+func (pbcl PriceByCarrierList) Less(i, j int) bool {
+	if pbcl[i].Amount.Amount != pbcl[j].Amount.Amount {
+		return pbcl[i].Amount.Amount < pbcl[j].Amount.Amount
+	}
+	if pbcl[i].DeliveryTime.Nominal() != pbcl[j].DeliveryTime.Nominal() {
+		return pbcl[i].DeliveryTime.Nominal() < pbcl[j].DeliveryTime.Nominal()
+	}
+	if pbcl[i].CarrierName != pbcl[j].CarrierName {
+		return pbcl[i].CarrierName < pbcl[j].CarrierName
+	}
+	return pbcl[i].ServiceID < pbcl[j].ServiceID
+}
 
 // ServiceInterface defines the interface of the Service.
 // This is meant to be used from main/external packages, allowing to mock the service itself.
+// The quote methods and CreateBooking stop looking for carrier services when the
+// given context is done, e.g. when the client of the request has gone away.
 type ServiceInterface interface {
-	GetBasicQuote(args GetBasicQuoteArgs) (*GetBasicQuoteResponse, error)
-	GetQuotesByVehicle(args GetQuotesByVehicleArgs) (*GetQuotesByVehicleResponse, error)
-	GetQuotesByCarrier(args GetQuotesByCarrierArgs) (*GetQuotesByCarrierResponse, error)
+	GetBasicQuote(ctx context.Context, args GetBasicQuoteArgs) (*GetBasicQuoteResponse, error)
+	GetQuotesByVehicle(ctx context.Context, args GetQuotesByVehicleArgs) (*GetQuotesByVehicleResponse, error)
+	GetQuotesByCarrier(ctx context.Context, args GetQuotesByCarrierArgs) (*GetQuotesByCarrierResponse, error)
+	GetBestQuotes(ctx context.Context, args GetBestQuotesArgs) (*GetBestQuotesResponse, error)
+	GetShipmentQuote(ctx context.Context, args GetShipmentQuoteArgs) (*GetShipmentQuoteResponse, error)
+	GetRouteQuote(ctx context.Context, args GetRouteQuoteArgs) (*GetRouteQuoteResponse, error)
+	GetQuote(id string) (*StoredQuote, error)
+	CreateBooking(ctx context.Context, args CreateBookingArgs) (*Booking, error)
+	GetBooking(id string) (*Booking, error)
+	UpdateBookingStatus(args UpdateBookingStatusArgs) (*Booking, error)
+	TrackBooking(id string) (*TrackingInfo, error)
+	ListCarriers() (*ListCarriersResponse, error)
+	GetCarrier(name string) (*CarrierDefinition, error)
+	CreateCarrier(carrier CarrierDefinition) (*CarrierDefinition, error)
+	UpdateCarrier(args UpdateCarrierArgs) (*CarrierDefinition, error)
+	DeleteCarrier(args DeleteCarrierArgs) error
+	AddCarrierService(args CarrierServiceArgs) (*CarrierDefinition, error)
+	UpdateCarrierService(args CarrierServiceArgs) (*CarrierDefinition, error)
+	DeleteCarrierService(args CarrierServiceArgs) (*CarrierDefinition, error)
 }
 
 // Service implements the ServiceInterface exposing the required methods.
 type Service struct {
-	carrierServiceFinder CarrierServiceFinder
-	logger               *log.Logger
+	carrierServiceFinder        CarrierServiceFinderV2
+	carrierServiceFinderTimeout time.Duration
+	distanceCalculator          DistanceCalculator
+	exchangeRateProvider        ExchangeRateProvider
+	holidayCalendar             HolidayCalendar
+	quoteStore                  QuoteStore
+	quoteValidity               time.Duration
+	bookingStore                BookingStore
+	bookingLocks                bookingLocks
+	carrierDispatchers          CarrierDispatcherRegistry
+	carrierCatalogue            CarrierCatalogue
+	pricingRules                atomic.Value
+	logger                      *log.Logger
+
+	// now, when set, replaces time.Now to test time dependent behaviours.
+	now func() time.Time
+}
+
+// ServiceOption configures an optional feature of the Service.
+type ServiceOption func(s *Service)
+
+// WithExchangeRateProvider sets the ExchangeRateProvider used to quote prices in
+// currencies other than the one of the pricing rules; without it, such requests
+// are rejected.
+func WithExchangeRateProvider(exchangeRateProvider ExchangeRateProvider) ServiceOption {
+	return func(s *Service) {
+		s.exchangeRateProvider = exchangeRateProvider
+	}
+}
+
+// WithHolidayCalendar sets the HolidayCalendar used to apply bank holiday
+// surcharges; without it, no collection is considered on a bank holiday.
+func WithHolidayCalendar(holidayCalendar HolidayCalendar) ServiceOption {
+	return func(s *Service) {
+		s.holidayCalendar = holidayCalendar
+	}
 }
 
 // NewService returns a new Service initialized with the given parameters.
-func NewService(logger *log.Logger, carrierServiceFinder CarrierServiceFinder) *Service {
-	return &Service{
-		carrierServiceFinder: carrierServiceFinder,
+// When nil, DefaultPricingRules are used; an error is returned if the given
+// pricingRules are not valid.
+func NewService(
+	logger *log.Logger,
+	carrierServiceFinder CarrierServiceFinder,
+	distanceCalculator DistanceCalculator,
+	pricingRules *PricingRules,
+	options ...ServiceOption,
+) (*Service, error) {
+	if pricingRules == nil {
+		pricingRules = DefaultPricingRules()
+	}
+
+	err := pricingRules.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	service := &Service{
+		carrierServiceFinder: AdaptCarrierServiceFinder(carrierServiceFinder),
+		distanceCalculator:   distanceCalculator,
 		logger:               logger,
 	}
+	service.pricingRules.Store(pricingRules)
+
+	for _, option := range options {
+		option(service)
+	}
+
+	return service, nil
+}
+
+// PricingRules returns the PricingRules currently used by the Service.
+func (s *Service) PricingRules() *PricingRules {
+	return s.pricingRules.Load().(*PricingRules)
+}
+
+// SetPricingRules validates the given rules and atomically replaces the ones
+// used by the Service. Requests already in flight keep using the rules that
+// were in place when they started.
+func (s *Service) SetPricingRules(rules *PricingRules) error {
+	if rules == nil {
+		return errors.New("pricing rules must not be nil")
+	}
+
+	err := rules.Validate()
+	if err != nil {
+		return err
+	}
+
+	s.pricingRules.Store(rules)
+	s.logger.Println("pricing rules updated")
+
+	return nil
 }
 
 // GetBasicQuote calculates the basic price of the delivery between pickup and delivery
 // post codes, provided via the given args.
-func (s *Service) GetBasicQuote(args GetBasicQuoteArgs) (*GetBasicQuoteResponse, error) {
+func (s *Service) GetBasicQuote(ctx context.Context, args GetBasicQuoteArgs) (*GetBasicQuoteResponse, error) {
 	s.logger.Printf("executing GetBasicQuote with args: %v\n", args)
 
-	basePrice, err := s.calculateBasePrice(args.PickupPostcode, args.DeliveryPostcode)
+	rules := s.PricingRules()
+
+	exchangeRate, err := s.exchangeRate(rules, args.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	pickup, delivery, err := s.parsePostcodes(args.PickupPostcode, args.DeliveryPostcode)
+	if err != nil {
+		return nil, err
+	}
+
+	taxation, err := newTaxation(rules, args.PriceDisplay, pickup, delivery)
+	if err != nil {
+		return nil, err
+	}
+
+	basePrice, err := s.calculateBasePrice(rules, pickup, delivery, args.Parcel)
+	if err != nil {
+		return nil, err
+	}
+
+	surcharges := s.calculateSurcharges(rules, args.PickupTime, pickup, basePrice.amount)
+	price := basePrice.amount + sumOfAdjustments(surcharges)
+	amounts := taxation.apply(exchangeRate.convert(rules.money(price), rules.Rounding))
+
+	response := &GetBasicQuoteResponse{
+		PickupPostcode:   pickup.String(),
+		DeliveryPostcode: delivery.String(),
+		Price:            taxation.display(amounts),
+		Surcharges:       exchangeRate.convertAdjustments(surcharges, rules.Rounding),
+		TaxedAmounts:     amounts,
+		TaxJurisdiction:  taxation.jurisdiction,
+		TaxRate:          taxation.rate,
+		ExchangeRate:     exchangeRate,
+	}
+
+	if args.IncludeBreakdown {
+		breakdown := newPriceBreakdown(rules, *basePrice, "", surcharges, price)
+		response.Breakdown = exchangeRate.convertBreakdown(breakdown, rules.Rounding)
+	}
+
+	err = s.storeQuote(quoteTypeBasic, args, response)
 	if err != nil {
 		return nil, err
 	}
 
-	return &GetBasicQuoteResponse{
-		PickupPostcode:   args.PickupPostcode,
-		DeliveryPostcode: args.DeliveryPostcode,
-		Price:            *basePrice,
-	}, nil
+	return response, nil
 }
 
 // GetQuotesByVehicle calculates the price of the delivery betweeen pickup and delivery
 // post codes according to a specific vehicle, multiplying the basic price for the
 // relative markup.
-func (s *Service) GetQuotesByVehicle(args GetQuotesByVehicleArgs) (*GetQuotesByVehicleResponse, error) {
+func (s *Service) GetQuotesByVehicle(ctx context.Context, args GetQuotesByVehicleArgs) (*GetQuotesByVehicleResponse, error) {
 	s.logger.Printf("executing GetQuotesByVehicle with args: %v\n", args)
 
+	rules := s.PricingRules()
+
 	if !s.isVehicleValid(args.Vehicle) {
-		return nil, errInvalidVehicle
+		return nil, invalidArgs(errInvalidVehicle)
+	}
+
+	err := s.checkVehicleCapacity(rules, args.Vehicle, args.Parcel)
+	if err != nil {
+		return nil, err
+	}
+
+	exchangeRate, err := s.exchangeRate(rules, args.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	pickup, delivery, err := s.parsePostcodes(args.PickupPostcode, args.DeliveryPostcode)
+	if err != nil {
+		return nil, err
 	}
 
-	basePrice, err := s.calculateBasePrice(args.PickupPostcode, args.DeliveryPostcode)
+	taxation, err := newTaxation(rules, args.PriceDisplay, pickup, delivery)
 	if err != nil {
 		return nil, err
 	}
 
-	priceByVehicle := s.applyVehicleMarkup(*basePrice, args.Vehicle)
+	basePrice, err := s.calculateBasePrice(rules, pickup, delivery, args.Parcel)
+	if err != nil {
+		return nil, err
+	}
 
-	return &GetQuotesByVehicleResponse{
-		PickupPostcode:   args.PickupPostcode,
-		DeliveryPostcode: args.DeliveryPostcode,
+	priceByVehicle := s.applyVehicleMarkup(rules, basePrice.amount, args.Vehicle)
+	surcharges := s.calculateSurcharges(rules, args.PickupTime, pickup, priceByVehicle)
+	price := priceByVehicle + sumOfAdjustments(surcharges)
+	amounts := taxation.apply(exchangeRate.convert(rules.money(price), rules.Rounding))
+
+	response := &GetQuotesByVehicleResponse{
+		PickupPostcode:   pickup.String(),
+		DeliveryPostcode: delivery.String(),
 		Vehicle:          args.Vehicle,
-		Price:            priceByVehicle,
-	}, nil
+		Price:            taxation.display(amounts),
+		Surcharges:       exchangeRate.convertAdjustments(surcharges, rules.Rounding),
+		TaxedAmounts:     amounts,
+		TaxJurisdiction:  taxation.jurisdiction,
+		TaxRate:          taxation.rate,
+		ExchangeRate:     exchangeRate,
+	}
+
+	if args.IncludeBreakdown {
+		breakdown := newPriceBreakdown(rules, *basePrice, args.Vehicle, surcharges, price)
+		response.Breakdown = exchangeRate.convertBreakdown(breakdown, rules.Rounding)
+	}
+
+	err = s.storeQuote(quoteTypeByVehicle, args, response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
 }
 
 // GetQuotesByCarrier calculates the price of the delivery between pickup and delivery
 // post codes according to a specified vehicle and all the available carriers. A
 // markup is applied to the basic price for both the vehicle type and the carriers.
-func (s *Service) GetQuotesByCarrier(args GetQuotesByCarrierArgs) (*GetQuotesByCarrierResponse, error) {
+func (s *Service) GetQuotesByCarrier(ctx context.Context, args GetQuotesByCarrierArgs) (*GetQuotesByCarrierResponse, error) {
 	s.logger.Printf("executing GetQuotesByCarrier with args: %v\n", args)
 
+	response, err := s.calculateQuotesByCarrier(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.storeQuote(quoteTypeByCarrier, args, response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// calculateQuotesByCarrier calculates the response of the GetQuotesByCarrier
+// method, without storing it.
+func (s *Service) calculateQuotesByCarrier(ctx context.Context, args GetQuotesByCarrierArgs) (*GetQuotesByCarrierResponse, error) {
+	rules := s.PricingRules()
+
 	if !s.isVehicleValid(args.Vehicle) {
-		return nil, errInvalidVehicle
+		return nil, invalidArgs(errInvalidVehicle)
 	}
 
-	basePrice, err := s.calculateBasePrice(args.PickupPostcode, args.DeliveryPostcode)
+	err := s.checkVehicleCapacity(rules, args.Vehicle, args.Parcel)
 	if err != nil {
 		return nil, err
 	}
 
-	priceByVehicle := s.applyVehicleMarkup(*basePrice, args.Vehicle)
+	err = args.PriceListOptions.validate()
+	if err != nil {
+		return nil, invalidArgs(err)
+	}
 
-	availableCarrierServices := s.carrierServiceFinder.FindCarrierServicesForVehicle(args.Vehicle)
-	if len(availableCarrierServices) == 0 {
-		return nil, errNoAvailableCarrierServicesForVehicle
+	err = args.RecommendationOptions.validate()
+	if err != nil {
+		return nil, invalidArgs(err)
 	}
 
-	priceList := s.getPriceListFromPriceAndCarrierServices(priceByVehicle, availableCarrierServices)
+	exchangeRate, err := s.exchangeRate(rules, args.Currency)
+	if err != nil {
+		return nil, err
+	}
 
-	return &GetQuotesByCarrierResponse{
-		PickupPostcode:   args.PickupPostcode,
-		DeliveryPostcode: args.DeliveryPostcode,
-		Vehicle:          args.Vehicle,
-		PriceList:        priceList,
-	}, nil
-}
+	pickup, delivery, err := s.parsePostcodes(args.PickupPostcode, args.DeliveryPostcode)
+	if err != nil {
+		return nil, err
+	}
 
-func (s *Service) calculateBasePrice(pickupPostcode, deliveryPostcode string) (*int64, error) {
-	pickup, err := strconv.ParseInt(pickupPostcode, 36, 64)
+	taxation, err := newTaxation(rules, args.PriceDisplay, pickup, delivery)
 	if err != nil {
 		return nil, err
 	}
 
-	delivery, err := strconv.ParseInt(deliveryPostcode, 36, 64)
+	basePrice, err := s.calculateBasePrice(rules, pickup, delivery, args.Parcel)
 	if err != nil {
 		return nil, err
 	}
 
-	const someLargeNumber = 100000000
-	result := int64(math.Abs(float64(pickup)-float64(delivery))) / someLargeNumber
+	priceByVehicle := s.applyVehicleMarkup(rules, basePrice.amount, args.Vehicle)
+	surcharges := s.calculateSurcharges(rules, args.PickupTime, pickup, priceByVehicle)
+	price := priceByVehicle + sumOfAdjustments(surcharges)
 
-	return &result, nil
+	availableCarrierServices, err := s.findCarrierServices(ctx,
+		newCarrierQuery(args.Vehicle, pickup, []postcode.Postcode{*delivery}, args.Parcel, args.PickupTime),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(availableCarrierServices) == 0 {
+		return nil, invalidArgs(errNoAvailableCarrierServicesForVehicle)
+	}
+
+	var vehicleBreakdown *PriceBreakdown
+	if args.IncludeBreakdown {
+		vehicleBreakdown = newPriceBreakdown(rules, *basePrice, args.Vehicle, surcharges, price)
+	}
+
+	priceList := s.getPriceListFromPriceAndCarrierServices(rules, price, vehicleBreakdown, availableCarrierServices, pickup, args.PickupTime)
+
+	// filters are applied to the prices as displayed, once converted and taxed
+	displayedPriceList := taxation.applyToPriceList(exchangeRate.convertPriceList(priceList, rules.Rounding))
+	displayedPriceList = args.PriceListOptions.apply(displayedPriceList)
+
+	response := &GetQuotesByCarrierResponse{
+		PickupPostcode:   pickup.String(),
+		DeliveryPostcode: delivery.String(),
+		Vehicle:          args.Vehicle,
+		Recommended:      args.RecommendationOptions.recommend(displayedPriceList, args.PriceListOptions),
+		Surcharges:       exchangeRate.convertAdjustments(surcharges, rules.Rounding),
+		TaxJurisdiction:  taxation.jurisdiction,
+		TaxRate:          taxation.rate,
+		PriceList:        displayedPriceList,
+		ExchangeRate:     exchangeRate,
+	}
+
+	if response.Recommended != nil {
+		response.Price = response.Recommended.Amount
+	}
+
+	return response, nil
 }
 
-func (s *Service) isVehicleValid(vehicleLabelToVerify string) bool {
-	for _, vehicleType := range ValidVehicleTypes {
-		if vehicleType == vehicleLabelToVerify {
-			return true
+// parsePostcodes validates both the pickup and the delivery postcodes,
+// returning a *postcode.ValidationError for the first invalid one.
+func (s *Service) parsePostcodes(pickupPostcode, deliveryPostcode string) (*postcode.Postcode, *postcode.Postcode, error) {
+	pickup, err := postcode.Parse(pickupPostcode)
+	if err != nil {
+		return nil, nil, invalidArgs(err)
+	}
+
+	delivery, err := postcode.Parse(deliveryPostcode)
+	if err != nil {
+		return nil, nil, invalidArgs(err)
+	}
+
+	return pickup, delivery, nil
+}
+
+// calculateBasePrice returns the price of the delivery before any markup,
+// proportional to the distance between the pickup and delivery postcodes and,
+// when provided, to the chargeable weight of the parcel.
+func (s *Service) calculateBasePrice(rules *PricingRules, pickupPostcode, deliveryPostcode *postcode.Postcode, parcel *Parcel) (*basePrice, error) {
+	distance, err := s.distanceCalculator.CalculateDistance(*pickupPostcode, *deliveryPostcode)
+	if err != nil {
+		// the postcodes cannot be located
+		return nil, invalidArgs(err)
+	}
+
+	components := []float64{distance * rules.PricePerKilometre}
+
+	if parcel != nil {
+		err = parcel.validate()
+		if err != nil {
+			return nil, invalidArgs(err)
 		}
+
+		components = append(components, parcel.ChargeableWeightKg(rules.VolumetricDivisor)*rules.PricePerKilogram)
 	}
-	return false
+
+	result := rules.basePrice(components)
+
+	return &result, nil
 }
 
-func (s *Service) applyVehicleMarkup(basePrice int64, vehicleType string) int64 {
-	markup, exists := VehiclesMarkupTable[vehicleType]
-	if !exists {
-		return basePrice
+// checkVehicleCapacity returns an error if the given parcel is not valid or if
+// it cannot be carried by the given vehicle.
+func (s *Service) checkVehicleCapacity(rules *PricingRules, vehicleType string, parcel *Parcel) error {
+	if parcel == nil {
+		return nil
 	}
 
-	return int64(math.RoundToEven(float64(basePrice) * markup))
+	err := parcel.validate()
+	if err != nil {
+		return invalidArgs(err)
+	}
+
+	return invalidArgs(rules.vehicleCapacity(vehicleType).canCarry(vehicleType, *parcel))
 }
 
-func (s *Service) getPriceListFromPriceAndCarrierServices(priceByVehicle int64, availableCarrierServices []CarrierService) PriceByCarrierList {
+func (s *Service) isVehicleValid(vehicleLabelToVerify string) bool {
+	return isVehicleTypeKnown(vehicleLabelToVerify)
+}
+
+func (s *Service) applyVehicleMarkup(rules *PricingRules, basePrice int64, vehicleType string) int64 {
+	return multiplyMinorUnits(basePrice, rules.vehicleMultiplier(vehicleType), rules.Rounding)
+}
+
+// getPriceListFromPriceAndCarrierServices applies the markup of each carrier service
+// to the given price; when vehicleBreakdown is not nil, each price is itemised too.
+// When pickupTime is not nil, the delivery by each carrier service is estimated.
+// Carrier services whose markup is not in the currency of the pricing rules are
+// converted via the ExchangeRateProvider; they are skipped only when no exchange
+// rate is available.
+func (s *Service) getPriceListFromPriceAndCarrierServices(
+	rules *PricingRules,
+	priceByVehicle int64,
+	vehicleBreakdown *PriceBreakdown,
+	availableCarrierServices []CarrierService,
+	pickup *postcode.Postcode,
+	pickupTime *time.Time,
+) PriceByCarrierList {
 	priceList := PriceByCarrierList{}
 	for _, carrierService := range availableCarrierServices {
-		priceList = append(priceList, PriceByCarrier{
-			CarrierName:  carrierService.Name,
-			Amount:       priceByVehicle + carrierService.Markup,
-			DeliveryTime: carrierService.DeliveryTime,
-		})
+		carrierService, err := s.carrierServiceInCurrency(rules, carrierService)
+		if err != nil {
+			s.logger.Printf(
+				"skipping carrier service %s: cannot convert its markup from %s to %s: %v\n",
+				carrierService.Name,
+				carrierService.Markup.Currency,
+				rules.Currency,
+				err,
+			)
+			continue
+		}
+
+		priceByCarrier := PriceByCarrier{
+			CarrierName:       carrierService.Name,
+			ServiceID:         carrierService.ServiceID,
+			Amount:            rules.money(priceByVehicle + carrierService.Markup.Amount),
+			DeliveryTime:      carrierService.DeliveryTime,
+			EstimatedDelivery: s.estimateDelivery(carrierService, pickupTime, pickup),
+		}
+
+		if vehicleBreakdown != nil {
+			priceByCarrier.Breakdown = vehicleBreakdown.withCarrierService(carrierService)
+		}
+
+		priceList = append(priceList, priceByCarrier)
 	}
 
-	sort.Sort(priceList)
+	sort.Stable(priceList)
 
 	return priceList
 }
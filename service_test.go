@@ -2,11 +2,15 @@ package carrierpricing
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"io/ioutil"
 	"log"
 	"os"
 	"reflect"
 	"testing"
+
+	"github.com/giefferre/carrierpricing/postcode"
 )
 
 // TESTS
@@ -15,17 +19,97 @@ func TestNewService(t *testing.T) {
 	// tests that NewService method returns a valid Service object
 	logger := log.New(os.Stdout, "", log.LstdFlags)
 	csf := &mockCarrierServiceFinder{}
+	dc := &mockDistanceCalculator{}
+
+	rules := DefaultPricingRules()
 
 	expectedService := &Service{
-		carrierServiceFinder: csf,
+		carrierServiceFinder: AdaptCarrierServiceFinder(csf),
+		distanceCalculator:   dc,
 		logger:               logger,
 	}
+	expectedService.pricingRules.Store(rules)
 
-	service := NewService(logger, csf)
+	service, err := NewService(logger, csf, dc, rules)
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
 
 	if !reflect.DeepEqual(expectedService, service) {
 		t.Fatal("NewService method didn't return the correct Service object")
 	}
+
+	invalidRules := DefaultPricingRules()
+	delete(invalidRules.VehicleMultipliers, VehicleTypeBicycle)
+
+	_, err = NewService(logger, csf, dc, invalidRules)
+	if err == nil || err.Error() != "pricing rules: missing multiplier for bicycle" {
+		t.Fatalf("expected NewService to reject rules without a multiplier for each vehicle, received: '%v'", err)
+	}
+}
+
+func TestSetPricingRules(t *testing.T) {
+	// tests that SetPricingRules swaps the rules used by the service,
+	// keeping the previous ones when the new rules are not valid
+	logger := log.New(ioutil.Discard, "", 0)
+	service, err := NewService(logger, &mockCarrierServiceFinder{}, &mockDistanceCalculator{}, nil)
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
+
+	invalidRules := DefaultPricingRules()
+	invalidRules.Rounding = "sideways"
+
+	err = service.SetPricingRules(invalidRules)
+	if err == nil {
+		t.Fatal("expected SetPricingRules to return an error for invalid rules")
+	}
+	if service.PricingRules().Rounding != RoundingModeHalfEven {
+		t.Fatal("expected invalid rules not to be applied")
+	}
+
+	newRules := DefaultPricingRules()
+	newRules.PricePerKilometre = 200
+	newRules.MinimumCharge = 1000
+	newRules.Rounding = RoundingModeUp
+
+	err = service.SetPricingRules(newRules)
+	if err != nil {
+		t.Fatalf("SetPricingRules returned error %v", err)
+	}
+
+	result, err := service.GetBasicQuote(context.Background(), GetBasicQuoteArgs{
+		PickupPostcode:   "SW1A1AA",
+		DeliveryPostcode: "EC2A3LT",
+	})
+	if err != nil {
+		t.Fatalf("GetBasicQuote returned error %v", err)
+	}
+	if result.Price != gbp(1000) {
+		t.Fatalf("expected the minimum charge to be applied, received price %v", result.Price)
+	}
+
+	newRules = DefaultPricingRules()
+	newRules.PricePerKilometre = 200
+	newRules.Rounding = RoundingModeUp
+
+	err = service.SetPricingRules(newRules)
+	if err != nil {
+		t.Fatalf("SetPricingRules returned error %v", err)
+	}
+
+	vehicleResult, err := service.GetQuotesByVehicle(context.Background(), GetQuotesByVehicleArgs{
+		PickupPostcode:   "SW1A1AA",
+		DeliveryPostcode: "EC2A3LT",
+		Vehicle:          VehicleTypeBicycle,
+	})
+	if err != nil {
+		t.Fatalf("GetQuotesByVehicle returned error %v", err)
+	}
+	// 3.16km * 200 = 632, 632 * 1.1 = 695.2 rounded up
+	if vehicleResult.Price != gbp(696) {
+		t.Fatalf("expected price 696, received %v", vehicleResult.Price)
+	}
 }
 
 func TestGetBasicQuoteLogs(t *testing.T) {
@@ -35,14 +119,17 @@ func TestGetBasicQuoteLogs(t *testing.T) {
 	logger := log.New(logDestination, "", 0)
 	csf := &mockCarrierServiceFinder{}
 
-	service := NewService(logger, csf)
+	service, err := NewService(logger, csf, &mockDistanceCalculator{}, nil)
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
 
-	service.GetBasicQuote(GetBasicQuoteArgs{
-		"FROM",
-		"TO",
+	service.GetBasicQuote(context.Background(), GetBasicQuoteArgs{
+		PickupPostcode:   "FROM",
+		DeliveryPostcode: "TO",
 	})
 
-	expectedLogString := "executing GetBasicQuote with args: {FROM TO}\n"
+	expectedLogString := "executing GetBasicQuote with args: {FROM TO <nil> <nil> false  }\n"
 	actualLogString := logDestination.String()
 
 	if expectedLogString != logDestination.String() {
@@ -63,7 +150,7 @@ func TestGetBasicQuote(t *testing.T) {
 				DeliveryPostcode: "EC2A3LT",
 			},
 			ExpectedResult: nil,
-			ExpectedError:  errors.New("strconv.ParseInt: parsing \"_\": invalid syntax"),
+			ExpectedError:  errors.New("invalid postcode \"_\": postcode has an invalid length"),
 		},
 		// case #2 invalid DeliveryPostcode argument
 		{
@@ -72,7 +159,7 @@ func TestGetBasicQuote(t *testing.T) {
 				DeliveryPostcode: "",
 			},
 			ExpectedResult: nil,
-			ExpectedError:  errors.New("strconv.ParseInt: parsing \"\": invalid syntax"),
+			ExpectedError:  errors.New("invalid postcode \"\": postcode is empty"),
 		},
 		// case #3 valid request, expected result
 		{
@@ -81,9 +168,37 @@ func TestGetBasicQuote(t *testing.T) {
 				DeliveryPostcode: "EC2A3LT",
 			},
 			ExpectedResult: &GetBasicQuoteResponse{
+				PickupPostcode:   "SW1A 1AA",
+				DeliveryPostcode: "EC2A 3LT",
+				Price:            gbp(316),
+				TaxedAmounts:     taxedGBP(316, 63),
+				TaxJurisdiction:  JurisdictionGreatBritain,
+				TaxRate:          0.2,
+			},
+			ExpectedError: nil,
+		},
+		// case #4 distance calculator cannot locate a postcode
+		{
+			Arguments: GetBasicQuoteArgs{
 				PickupPostcode:   "SW1A1AA",
-				DeliveryPostcode: "EC2A3LT",
-				Price:            316,
+				DeliveryPostcode: "BFPO 801",
+			},
+			ExpectedResult: nil,
+			ExpectedError:  errors.New("unknown location for the given postcode"),
+		},
+		// case #5 lowercase and spaced postcodes are normalised
+		{
+			Arguments: GetBasicQuoteArgs{
+				PickupPostcode:   "sw1a 1aa",
+				DeliveryPostcode: " ec2a3lt ",
+			},
+			ExpectedResult: &GetBasicQuoteResponse{
+				PickupPostcode:   "SW1A 1AA",
+				DeliveryPostcode: "EC2A 3LT",
+				Price:            gbp(316),
+				TaxedAmounts:     taxedGBP(316, 63),
+				TaxJurisdiction:  JurisdictionGreatBritain,
+				TaxRate:          0.2,
 			},
 			ExpectedError: nil,
 		},
@@ -92,10 +207,13 @@ func TestGetBasicQuote(t *testing.T) {
 	logger := log.New(os.Stdout, "", log.LstdFlags)
 	csf := &mockCarrierServiceFinder{}
 
-	service := NewService(logger, csf)
+	service, err := NewService(logger, csf, &mockDistanceCalculator{}, nil)
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
 
 	for _, tc := range tests {
-		result, err := service.GetBasicQuote(tc.Arguments)
+		result, err := service.GetBasicQuote(context.Background(), tc.Arguments)
 		if (tc.ExpectedError != nil && err == nil) ||
 			(tc.ExpectedError == nil && err != nil) ||
 			(tc.ExpectedError != nil && err != nil && tc.ExpectedError.Error() != err.Error()) {
@@ -122,15 +240,18 @@ func TestGetQuotesByVehicleLogs(t *testing.T) {
 	logger := log.New(logDestination, "", 0)
 	csf := &mockCarrierServiceFinder{}
 
-	service := NewService(logger, csf)
+	service, err := NewService(logger, csf, &mockDistanceCalculator{}, nil)
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
 
-	service.GetQuotesByVehicle(GetQuotesByVehicleArgs{
-		"FROM",
-		"TO",
-		"bicycle",
+	service.GetQuotesByVehicle(context.Background(), GetQuotesByVehicleArgs{
+		PickupPostcode:   "FROM",
+		DeliveryPostcode: "TO",
+		Vehicle:          "bicycle",
 	})
 
-	expectedLogString := "executing GetQuotesByVehicle with args: {FROM TO bicycle}\n"
+	expectedLogString := "executing GetQuotesByVehicle with args: {FROM TO bicycle <nil> <nil> false  }\n"
 	actualLogString := logDestination.String()
 
 	if expectedLogString != logDestination.String() {
@@ -152,7 +273,7 @@ func TestGetQuotesByVehicle(t *testing.T) {
 				Vehicle:          "bicycle",
 			},
 			ExpectedResult: nil,
-			ExpectedError:  errors.New("strconv.ParseInt: parsing \"_\": invalid syntax"),
+			ExpectedError:  errors.New("invalid postcode \"_\": postcode has an invalid length"),
 		},
 		// case #2 invalid DeliveryPostcode argument
 		{
@@ -162,7 +283,7 @@ func TestGetQuotesByVehicle(t *testing.T) {
 				Vehicle:          "bicycle",
 			},
 			ExpectedResult: nil,
-			ExpectedError:  errors.New("strconv.ParseInt: parsing \"\": invalid syntax"),
+			ExpectedError:  errors.New("invalid postcode \"\": postcode is empty"),
 		},
 		// case #3 invalid Vehicle argument
 		{
@@ -174,18 +295,74 @@ func TestGetQuotesByVehicle(t *testing.T) {
 			ExpectedResult: nil,
 			ExpectedError:  errors.New("invalid vehicle provided"),
 		},
-		// case #4 valid request, expected result
+		// case #4 parcel too heavy for the vehicle
 		{
 			Arguments: GetQuotesByVehicleArgs{
 				PickupPostcode:   "SW1A1AA",
 				DeliveryPostcode: "EC2A3LT",
 				Vehicle:          "bicycle",
+				Parcel:           &Parcel{WeightKg: 40, LengthCm: 120, WidthCm: 80, HeightCm: 100},
+			},
+			ExpectedResult: nil,
+			ExpectedError:  errors.New("bicycle cannot carry the parcel: weight of 40kg exceeds the maximum of 8kg"),
+		},
+		// case #5 parcel too big for the vehicle
+		{
+			Arguments: GetQuotesByVehicleArgs{
+				PickupPostcode:   "SW1A1AA",
+				DeliveryPostcode: "EC2A3LT",
+				Vehicle:          "motorbike",
+				Parcel:           &Parcel{WeightKg: 2, LengthCm: 100, WidthCm: 50, HeightCm: 20},
+			},
+			ExpectedResult: nil,
+			ExpectedError:  errors.New("motorbike cannot carry the parcel: volume of 100l exceeds the maximum of 80l"),
+		},
+		// case #6 invalid parcel
+		{
+			Arguments: GetQuotesByVehicleArgs{
+				PickupPostcode:   "SW1A1AA",
+				DeliveryPostcode: "EC2A3LT",
+				Vehicle:          "motorbike",
+				Parcel:           &Parcel{WeightKg: -2},
+			},
+			ExpectedResult: nil,
+			ExpectedError:  errors.New("invalid parcel: weight and dimensions must be non negative numbers"),
+		},
+		// case #7 valid request with a parcel, priced by its volumetric weight
+		{
+			Arguments: GetQuotesByVehicleArgs{
+				PickupPostcode:   "SW1A1AA",
+				DeliveryPostcode: "EC2A3LT",
+				Vehicle:          "parcel_car",
+				Parcel:           &Parcel{WeightKg: 2, LengthCm: 50, WidthCm: 40, HeightCm: 30},
 			},
 			ExpectedResult: &GetQuotesByVehicleResponse{
+				PickupPostcode:   "SW1A 1AA",
+				DeliveryPostcode: "EC2A 3LT",
+				Vehicle:          "parcel_car",
+				// (316 + 12kg * 10) * 1.2
+				Price:           gbp(523),
+				TaxedAmounts:    taxedGBP(523, 105),
+				TaxJurisdiction: JurisdictionGreatBritain,
+				TaxRate:         0.2,
+			},
+			ExpectedError: nil,
+		},
+		// case #8 valid request, expected result
+		{
+			Arguments: GetQuotesByVehicleArgs{
 				PickupPostcode:   "SW1A1AA",
 				DeliveryPostcode: "EC2A3LT",
 				Vehicle:          "bicycle",
-				Price:            348,
+			},
+			ExpectedResult: &GetQuotesByVehicleResponse{
+				PickupPostcode:   "SW1A 1AA",
+				DeliveryPostcode: "EC2A 3LT",
+				Vehicle:          "bicycle",
+				Price:            gbp(348),
+				TaxedAmounts:     taxedGBP(348, 70),
+				TaxJurisdiction:  JurisdictionGreatBritain,
+				TaxRate:          0.2,
 			},
 			ExpectedError: nil,
 		},
@@ -194,10 +371,13 @@ func TestGetQuotesByVehicle(t *testing.T) {
 	logger := log.New(os.Stdout, "", log.LstdFlags)
 	csf := &mockCarrierServiceFinder{}
 
-	service := NewService(logger, csf)
+	service, err := NewService(logger, csf, &mockDistanceCalculator{}, nil)
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
 
 	for _, tc := range tests {
-		result, err := service.GetQuotesByVehicle(tc.Arguments)
+		result, err := service.GetQuotesByVehicle(context.Background(), tc.Arguments)
 		if (tc.ExpectedError != nil && err == nil) ||
 			(tc.ExpectedError == nil && err != nil) ||
 			(tc.ExpectedError != nil && err != nil && tc.ExpectedError.Error() != err.Error()) {
@@ -224,15 +404,18 @@ func TestGetQuotesByCarrierLogs(t *testing.T) {
 	logger := log.New(logDestination, "", 0)
 	csf := &mockCarrierServiceFinder{}
 
-	service := NewService(logger, csf)
+	service, err := NewService(logger, csf, &mockDistanceCalculator{}, nil)
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
 
-	service.GetQuotesByCarrier(GetQuotesByCarrierArgs{
-		"FROM",
-		"TO",
-		"small_van",
+	service.GetQuotesByCarrier(context.Background(), GetQuotesByCarrierArgs{
+		PickupPostcode:   "FROM",
+		DeliveryPostcode: "TO",
+		Vehicle:          "small_van",
 	})
 
-	expectedLogString := "executing GetQuotesByCarrier with args: {FROM TO small_van}\n"
+	expectedLogString := "executing GetQuotesByCarrier with args: {FROM TO small_van <nil> <nil> false   { <nil> 0 <nil> [] []} { <nil>}}\n"
 	actualLogString := logDestination.String()
 
 	if expectedLogString != logDestination.String() {
@@ -254,7 +437,7 @@ func TestGetQuotesByCarrier(t *testing.T) {
 				Vehicle:          "bicycle",
 			},
 			ExpectedResult: nil,
-			ExpectedError:  errors.New("strconv.ParseInt: parsing \"_\": invalid syntax"),
+			ExpectedError:  errors.New("invalid postcode \"_\": postcode has an invalid length"),
 		},
 		// case #2 invalid DeliveryPostcode argument
 		{
@@ -264,7 +447,7 @@ func TestGetQuotesByCarrier(t *testing.T) {
 				Vehicle:          "bicycle",
 			},
 			ExpectedResult: nil,
-			ExpectedError:  errors.New("strconv.ParseInt: parsing \"\": invalid syntax"),
+			ExpectedError:  errors.New("invalid postcode \"\": postcode is empty"),
 		},
 		// case #3 invalid Vehicle argument
 		{
@@ -294,19 +477,33 @@ func TestGetQuotesByCarrier(t *testing.T) {
 				Vehicle:          "small_van",
 			},
 			ExpectedResult: &GetQuotesByCarrierResponse{
-				PickupPostcode:   "SW1A1AA",
-				DeliveryPostcode: "EC2A3LT",
+				PickupPostcode:   "SW1A 1AA",
+				DeliveryPostcode: "EC2A 3LT",
+				TaxJurisdiction:  JurisdictionGreatBritain,
+				TaxRate:          0.2,
 				Vehicle:          "small_van",
+				Price:            gbp(421),
+				Recommended: &RecommendedCarrier{
+					CarrierName:  "MockService2",
+					ServiceID:    "1",
+					Amount:       gbp(421),
+					DeliveryTime: workingDays(5),
+					Reason:       "cheapest carrier",
+				},
 				PriceList: PriceByCarrierList{
 					PriceByCarrier{
 						CarrierName:  "MockService2",
-						Amount:       421,
-						DeliveryTime: 5,
+						ServiceID:    "1",
+						Amount:       gbp(421),
+						TaxedAmounts: taxedGBP(421, 84),
+						DeliveryTime: workingDays(5),
 					},
 					PriceByCarrier{
 						CarrierName:  "MockService1",
-						Amount:       431,
-						DeliveryTime: 1,
+						ServiceID:    "1",
+						Amount:       gbp(431),
+						TaxedAmounts: taxedGBP(431, 86),
+						DeliveryTime: workingDays(1),
 					},
 				},
 			},
@@ -317,10 +514,13 @@ func TestGetQuotesByCarrier(t *testing.T) {
 	logger := log.New(os.Stdout, "", log.LstdFlags)
 	csf := &mockCarrierServiceFinder{}
 
-	service := NewService(logger, csf)
+	service, err := NewService(logger, csf, &mockDistanceCalculator{}, nil)
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
 
 	for _, tc := range tests {
-		result, err := service.GetQuotesByCarrier(tc.Arguments)
+		result, err := service.GetQuotesByCarrier(context.Background(), tc.Arguments)
 		if (tc.ExpectedError != nil && err == nil) ||
 			(tc.ExpectedError == nil && err != nil) ||
 			(tc.ExpectedError != nil && err != nil && tc.ExpectedError.Error() != err.Error()) {
@@ -346,19 +546,48 @@ type mockCarrierServiceFinder struct{}
 
 func (mcsf *mockCarrierServiceFinder) FindCarrierServicesForVehicle(vehicleType string) (availableCarrierServices []CarrierService) {
 	switch vehicleType {
+	case VehicleTypeParcelCar:
+		availableCarrierServices = []CarrierService{
+			CarrierService{
+				Name:         "MockService3",
+				Markup:       gbp(5),
+				DeliveryTime: workingDays(3),
+			},
+		}
 	case VehicleTypeSmallVan:
 		availableCarrierServices = []CarrierService{
 			CarrierService{
-				Name:         "MockService1",
-				Markup:       20,
-				DeliveryTime: 1,
+				Name:          "MockService1",
+				Markup:        gbp(20),
+				BasePrice:     gbp(15),
+				ServiceMarkup: gbp(5),
+				DeliveryTime:  workingDays(1),
 			},
 			CarrierService{
 				Name:         "MockService2",
-				Markup:       10,
-				DeliveryTime: 5,
+				Markup:       gbp(10),
+				DeliveryTime: workingDays(5),
 			},
 		}
 	}
 	return
 }
+
+type mockDistanceCalculator struct{}
+
+func (mdc *mockDistanceCalculator) CalculateDistance(pickup, delivery postcode.Postcode) (float64, error) {
+	if pickup.Area == "BFPO" || delivery.Area == "BFPO" {
+		return 0, errors.New("unknown location for the given postcode")
+	}
+	return 3.16, nil
+}
+
+// gbp returns the given amount of pence as Money.
+func gbp(amount int64) Money {
+	return NewMoney(amount, CurrencyGBP)
+}
+
+// taxedGBP returns the taxed amounts for the given net price and tax, in pence.
+func taxedGBP(net, tax int64) TaxedAmounts {
+	return TaxedAmounts{Net: gbp(net), Tax: gbp(tax), Gross: gbp(net + tax)}
+}
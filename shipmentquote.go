@@ -0,0 +1,300 @@
+package carrierpricing
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/giefferre/carrierpricing/postcode"
+)
+
+var errNoParcels = errors.New("at least one parcel must be provided")
+
+// GetShipmentQuoteArgs contains arguments for the GetShipmentQuote method.
+// When IncludeBreakdown is true, the consolidated prices are itemised.
+// When Currency is set, prices are converted to it.
+// PriceDisplay chooses whether prices are displayed before (PriceDisplayNet, the
+// default) or including taxes (PriceDisplayGross).
+// When PickupTime is set, the surcharges of the period it falls in are applied.
+type GetShipmentQuoteArgs struct {
+	PickupPostcode   string     `json:"pickup_postcode"`
+	DeliveryPostcode string     `json:"delivery_postcode"`
+	Vehicle          string     `json:"vehicle"`
+	Parcels          []Parcel   `json:"parcels"`
+	PickupTime       *time.Time `json:"pickup_time,omitempty"`
+	IncludeBreakdown bool       `json:"include_breakdown"`
+	Currency         string     `json:"currency,omitempty"`
+	PriceDisplay     string     `json:"price_display,omitempty"`
+}
+
+// GetShipmentQuoteResponse is the response object for the GetShipmentQuote method.
+// Loads is the number of vehicle trips needed to carry all the parcels, Price
+// is the consolidated price of all of them, while PriceList contains the
+// consolidated price for each of the available carriers.
+// ExchangeRate is set when prices have been converted to the requested currency.
+// The Breakdown, when requested, itemises the price before taxes.
+// QuoteReference is set when quotes are stored.
+type GetShipmentQuoteResponse struct {
+	QuoteReference
+	PickupPostcode   string            `json:"pickup_postcode"`
+	DeliveryPostcode string            `json:"delivery_postcode"`
+	Vehicle          string            `json:"vehicle"`
+	Loads            int               `json:"loads"`
+	Price            Money             `json:"price"`
+	Surcharges       []PriceAdjustment `json:"surcharges,omitempty"`
+	TaxedAmounts
+	TaxJurisdiction string             `json:"tax_jurisdiction"`
+	TaxRate         float64            `json:"tax_rate"`
+	Breakdown       *PriceBreakdown    `json:"breakdown,omitempty"`
+	Parcels         []ParcelPrice      `json:"parcels"`
+	PriceList       PriceByCarrierList `json:"price_list"`
+	ExchangeRate    *ExchangeRate      `json:"exchange_rate,omitempty"`
+}
+
+// ParcelPrice is the object returned in the GetShipmentQuoteResponse indicating
+// the share of the consolidated price for a single parcel. Index is the position
+// of the parcel in the request, Load is the trip, starting from 1, carrying it.
+type ParcelPrice struct {
+	Index  int   `json:"index"`
+	Load   int   `json:"load"`
+	Amount Money `json:"price"`
+}
+
+// GetShipmentQuote calculates the consolidated price of the delivery of several
+// parcels between pickup and delivery post codes using a specific vehicle.
+// Parcels are grouped in as few loads as the vehicle capacity allows; the
+// vehicle markup is applied to each load, as well as the markup of every
+// available carrier, since each load is a separate trip.
+func (s *Service) GetShipmentQuote(ctx context.Context, args GetShipmentQuoteArgs) (*GetShipmentQuoteResponse, error) {
+	s.logger.Printf("executing GetShipmentQuote with args: %v\n", args)
+
+	rules := s.PricingRules()
+
+	if !s.isVehicleValid(args.Vehicle) {
+		return nil, invalidArgs(errInvalidVehicle)
+	}
+
+	if len(args.Parcels) == 0 {
+		return nil, invalidArgs(errNoParcels)
+	}
+
+	for _, parcel := range args.Parcels {
+		err := s.checkVehicleCapacity(rules, args.Vehicle, &parcel)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	exchangeRate, err := s.exchangeRate(rules, args.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	pickup, delivery, err := s.parsePostcodes(args.PickupPostcode, args.DeliveryPostcode)
+	if err != nil {
+		return nil, err
+	}
+
+	taxation, err := newTaxation(rules, args.PriceDisplay, pickup, delivery)
+	if err != nil {
+		return nil, err
+	}
+
+	distance, err := s.distanceCalculator.CalculateDistance(*pickup, *delivery)
+	if err != nil {
+		// the postcodes cannot be located
+		return nil, invalidArgs(err)
+	}
+
+	loads := splitParcelsIntoLoads(args.Parcels, rules.vehicleCapacity(args.Vehicle))
+
+	var price int64
+	var breakdown *PriceBreakdown
+	var surcharges []PriceAdjustment
+	parcelPrices := make([]ParcelPrice, len(args.Parcels))
+
+	for loadIndex, load := range loads {
+		// the cost of the distance is shared equally among the parcels of the load,
+		// each parcel being charged for its own weight as well.
+		shares := make([]float64, len(load))
+		for i, parcelIndex := range load {
+			shares[i] = distance*rules.PricePerKilometre/float64(len(load)) +
+				args.Parcels[parcelIndex].ChargeableWeightKg(rules.VolumetricDivisor)*rules.PricePerKilogram
+		}
+
+		basePrice := rules.basePrice(shares)
+		loadPrice := s.applyVehicleMarkup(rules, basePrice.amount, args.Vehicle)
+
+		// every load is collected at the same time
+		loadSurcharges := s.calculateSurcharges(rules, args.PickupTime, pickup, loadPrice)
+		if len(loadSurcharges) > 0 {
+			surcharges = mergePriceAdjustments(surcharges, loadSurcharges)
+			loadPrice += sumOfAdjustments(loadSurcharges)
+		}
+		price += loadPrice
+
+		if args.IncludeBreakdown {
+			loadBreakdown := newPriceBreakdown(rules, basePrice, args.Vehicle, loadSurcharges, loadPrice)
+			if breakdown == nil {
+				breakdown = loadBreakdown
+			} else {
+				breakdown.add(loadBreakdown)
+			}
+		}
+
+		for i, amount := range allocateProportionally(loadPrice, shares) {
+			parcelPrices[load[i]] = ParcelPrice{
+				Index:  load[i],
+				Load:   loadIndex + 1,
+				Amount: rules.money(amount),
+			}
+		}
+	}
+
+	availableCarrierServices, err := s.findCarrierServices(ctx, CarrierQuery{
+		Vehicle:           args.Vehicle,
+		PickupPostcode:    pickup,
+		DeliveryPostcodes: []postcode.Postcode{*delivery},
+		Parcels:           args.Parcels,
+		PickupTime:        args.PickupTime,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(availableCarrierServices) == 0 {
+		return nil, invalidArgs(errNoAvailableCarrierServicesForVehicle)
+	}
+
+	// carriers charge their markup for each load
+	carrierServicesByLoad := make([]CarrierService, len(availableCarrierServices))
+	for i, carrierService := range availableCarrierServices {
+		carrierService.Markup.Amount *= int64(len(loads))
+		carrierService.BasePrice.Amount *= int64(len(loads))
+		carrierService.ServiceMarkup.Amount *= int64(len(loads))
+		carrierServicesByLoad[i] = carrierService
+	}
+
+	priceList := s.getPriceListFromPriceAndCarrierServices(rules, price, breakdown, carrierServicesByLoad, pickup, args.PickupTime)
+	convertedPrice := exchangeRate.convert(rules.money(price), rules.Rounding)
+	amounts := taxation.apply(convertedPrice)
+
+	response := &GetShipmentQuoteResponse{
+		PickupPostcode:   pickup.String(),
+		DeliveryPostcode: delivery.String(),
+		Vehicle:          args.Vehicle,
+		Loads:            len(loads),
+		Price:            taxation.display(amounts),
+		Surcharges:       exchangeRate.convertAdjustments(surcharges, rules.Rounding),
+		TaxedAmounts:     amounts,
+		TaxJurisdiction:  taxation.jurisdiction,
+		TaxRate:          taxation.rate,
+		Breakdown:        exchangeRate.convertBreakdown(breakdown, rules.Rounding),
+		Parcels:          taxation.displayParcelPrices(exchangeRate.convertParcelPrices(parcelPrices, convertedPrice), amounts),
+		PriceList:        taxation.applyToPriceList(exchangeRate.convertPriceList(priceList, rules.Rounding)),
+		ExchangeRate:     exchangeRate,
+	}
+
+	err = s.storeQuote(quoteTypeShipment, args, response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// splitParcelsIntoLoads groups the given parcels so that each group does not
+// exceed the vehicle capacity, returning the indexes of the parcels of each group.
+// Parcels are placed from the heaviest to the lightest in the first load having
+// enough room for them (first fit decreasing); every parcel is expected to fit
+// the vehicle on its own.
+func splitParcelsIntoLoads(parcels []Parcel, capacity VehicleCapacity) [][]int {
+	order := make([]int, len(parcels))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return parcels[order[i]].WeightKg > parcels[order[j]].WeightKg
+	})
+
+	loads := [][]int{}
+	loadWeights := []float64{}
+	loadVolumes := []float64{}
+
+	for _, parcelIndex := range order {
+		weight := parcels[parcelIndex].WeightKg
+		volume := parcels[parcelIndex].VolumeLitres()
+
+		placed := false
+		for i := range loads {
+			if capacity.MaxWeightKg > 0 && loadWeights[i]+weight > capacity.MaxWeightKg {
+				continue
+			}
+			if capacity.MaxVolumeLitres > 0 && loadVolumes[i]+volume > capacity.MaxVolumeLitres {
+				continue
+			}
+
+			loads[i] = append(loads[i], parcelIndex)
+			loadWeights[i] += weight
+			loadVolumes[i] += volume
+			placed = true
+			break
+		}
+
+		if !placed {
+			loads = append(loads, []int{parcelIndex})
+			loadWeights = append(loadWeights, weight)
+			loadVolumes = append(loadVolumes, volume)
+		}
+	}
+
+	// keep the parcels of each load in the same order they were requested
+	for _, load := range loads {
+		sort.Ints(load)
+	}
+
+	return loads
+}
+
+// allocateProportionally splits total in integer amounts proportional to the
+// given weights, using the largest remainder method so that the amounts always
+// add up to total. When all the weights are zero, total is split equally.
+func allocateProportionally(total int64, weights []float64) []int64 {
+	amounts := make([]int64, len(weights))
+	if len(weights) == 0 {
+		return amounts
+	}
+
+	var sumOfWeights float64
+	for _, weight := range weights {
+		sumOfWeights += weight
+	}
+
+	remainders := make([]float64, len(weights))
+	var allocated int64
+	for i, weight := range weights {
+		exact := float64(total) / float64(len(weights))
+		if sumOfWeights > 0 {
+			exact = float64(total) * weight / sumOfWeights
+		}
+		amounts[i] = int64(math.Floor(exact))
+		remainders[i] = exact - float64(amounts[i])
+		allocated += amounts[i]
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return remainders[order[i]] > remainders[order[j]]
+	})
+
+	for i := 0; allocated < total; i = (i + 1) % len(order) {
+		amounts[order[i]]++
+		allocated++
+	}
+
+	return amounts
+}
@@ -0,0 +1,227 @@
+package carrierpricing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestGetShipmentQuoteLogs(t *testing.T) {
+	// tests that the GetShipmentQuote logs the execution
+	logDestination := bytes.NewBufferString("")
+
+	logger := log.New(logDestination, "", 0)
+	service, err := NewService(logger, &mockCarrierServiceFinder{}, &mockDistanceCalculator{}, nil)
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
+
+	service.GetShipmentQuote(context.Background(), GetShipmentQuoteArgs{
+		PickupPostcode:   "FROM",
+		DeliveryPostcode: "TO",
+		Vehicle:          "small_van",
+	})
+
+	expectedLogString := "executing GetShipmentQuote with args: {FROM TO small_van [] <nil> false  }\n"
+	actualLogString := logDestination.String()
+
+	if expectedLogString != actualLogString {
+		t.Fatalf("expected log message was not received, had %v", actualLogString)
+	}
+}
+
+func TestGetShipmentQuote(t *testing.T) {
+	tests := []struct {
+		Arguments      GetShipmentQuoteArgs
+		ExpectedResult *GetShipmentQuoteResponse
+		ExpectedError  error
+	}{
+		// case #1 invalid Vehicle argument
+		{
+			Arguments: GetShipmentQuoteArgs{
+				PickupPostcode:   "SW1A1AA",
+				DeliveryPostcode: "EC2A3LT",
+				Vehicle:          "scooter",
+				Parcels:          []Parcel{Parcel{WeightKg: 1}},
+			},
+			ExpectedResult: nil,
+			ExpectedError:  errors.New("invalid vehicle provided"),
+		},
+		// case #2 no parcels
+		{
+			Arguments: GetShipmentQuoteArgs{
+				PickupPostcode:   "SW1A1AA",
+				DeliveryPostcode: "EC2A3LT",
+				Vehicle:          "small_van",
+			},
+			ExpectedResult: nil,
+			ExpectedError:  errors.New("at least one parcel must be provided"),
+		},
+		// case #3 a parcel is too heavy for the vehicle on its own
+		{
+			Arguments: GetShipmentQuoteArgs{
+				PickupPostcode:   "SW1A1AA",
+				DeliveryPostcode: "EC2A3LT",
+				Vehicle:          "small_van",
+				Parcels:          []Parcel{Parcel{WeightKg: 1}, Parcel{WeightKg: 600}},
+			},
+			ExpectedResult: nil,
+			ExpectedError:  errors.New("small_van cannot carry the parcel: weight of 600kg exceeds the maximum of 500kg"),
+		},
+		// case #4 invalid DeliveryPostcode argument
+		{
+			Arguments: GetShipmentQuoteArgs{
+				PickupPostcode:   "SW1A1AA",
+				DeliveryPostcode: "",
+				Vehicle:          "small_van",
+				Parcels:          []Parcel{Parcel{WeightKg: 1}},
+			},
+			ExpectedResult: nil,
+			ExpectedError:  errors.New("invalid postcode \"\": postcode is empty"),
+		},
+		// case #5 no carrier services for the vehicle
+		{
+			Arguments: GetShipmentQuoteArgs{
+				PickupPostcode:   "SW1A1AA",
+				DeliveryPostcode: "EC2A3LT",
+				Vehicle:          "bicycle",
+				Parcels:          []Parcel{Parcel{WeightKg: 1}},
+			},
+			ExpectedResult: nil,
+			ExpectedError:  errors.New("no available carrier services for the given vehicle"),
+		},
+		// case #6 all the parcels fit a single load
+		{
+			Arguments: GetShipmentQuoteArgs{
+				PickupPostcode:   "SW1A1AA",
+				DeliveryPostcode: "EC2A3LT",
+				Vehicle:          "small_van",
+				Parcels:          []Parcel{Parcel{WeightKg: 10}, Parcel{WeightKg: 20}},
+			},
+			ExpectedResult: &GetShipmentQuoteResponse{
+				PickupPostcode:   "SW1A 1AA",
+				DeliveryPostcode: "EC2A 3LT",
+				Vehicle:          "small_van",
+				Loads:            1,
+				// (316 + 30kg * 10) * 1.3
+				Price:           gbp(801),
+				TaxedAmounts:    taxedGBP(801, 160),
+				TaxJurisdiction: JurisdictionGreatBritain,
+				TaxRate:         0.2,
+				Parcels: []ParcelPrice{
+					ParcelPrice{Index: 0, Load: 1, Amount: gbp(335)},
+					ParcelPrice{Index: 1, Load: 1, Amount: gbp(466)},
+				},
+				PriceList: PriceByCarrierList{
+					PriceByCarrier{
+						CarrierName:  "MockService2",
+						ServiceID:    "1",
+						Amount:       gbp(811),
+						TaxedAmounts: taxedGBP(811, 162),
+						DeliveryTime: workingDays(5),
+					},
+					PriceByCarrier{
+						CarrierName:  "MockService1",
+						ServiceID:    "1",
+						Amount:       gbp(821),
+						TaxedAmounts: taxedGBP(821, 164),
+						DeliveryTime: workingDays(1),
+					},
+				},
+			},
+			ExpectedError: nil,
+		},
+		// case #7 parcels need to be split in two loads
+		{
+			Arguments: GetShipmentQuoteArgs{
+				PickupPostcode:   "SW1A1AA",
+				DeliveryPostcode: "EC2A3LT",
+				Vehicle:          "small_van",
+				Parcels:          []Parcel{Parcel{WeightKg: 300}, Parcel{WeightKg: 300}, Parcel{WeightKg: 100}},
+			},
+			ExpectedResult: &GetShipmentQuoteResponse{
+				PickupPostcode:   "SW1A 1AA",
+				DeliveryPostcode: "EC2A 3LT",
+				Vehicle:          "small_van",
+				Loads:            2,
+				// (316 + 400kg * 10) * 1.3 + (316 + 300kg * 10) * 1.3
+				Price:           gbp(9922),
+				TaxedAmounts:    taxedGBP(9922, 1984),
+				TaxJurisdiction: JurisdictionGreatBritain,
+				TaxRate:         0.2,
+				Parcels: []ParcelPrice{
+					ParcelPrice{Index: 0, Load: 1, Amount: gbp(4106)},
+					ParcelPrice{Index: 1, Load: 2, Amount: gbp(4311)},
+					ParcelPrice{Index: 2, Load: 1, Amount: gbp(1505)},
+				},
+				PriceList: PriceByCarrierList{
+					PriceByCarrier{
+						CarrierName:  "MockService2",
+						ServiceID:    "1",
+						Amount:       gbp(9942),
+						TaxedAmounts: taxedGBP(9942, 1988),
+						DeliveryTime: workingDays(5),
+					},
+					PriceByCarrier{
+						CarrierName:  "MockService1",
+						ServiceID:    "1",
+						Amount:       gbp(9962),
+						TaxedAmounts: taxedGBP(9962, 1992),
+						DeliveryTime: workingDays(1),
+					},
+				},
+			},
+			ExpectedError: nil,
+		},
+	}
+
+	logger := log.New(os.Stdout, "", log.LstdFlags)
+	service, err := NewService(logger, &mockCarrierServiceFinder{}, &mockDistanceCalculator{}, nil)
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
+
+	for _, tc := range tests {
+		result, err := service.GetShipmentQuote(context.Background(), tc.Arguments)
+		if (tc.ExpectedError != nil && err == nil) ||
+			(tc.ExpectedError == nil && err != nil) ||
+			(tc.ExpectedError != nil && err != nil && tc.ExpectedError.Error() != err.Error()) {
+			t.Fatalf(
+				"expected error '%v', received: '%v'\n",
+				tc.ExpectedError,
+				err,
+			)
+		}
+		if !reflect.DeepEqual(tc.ExpectedResult, result) {
+			t.Fatalf(
+				"expected result '%v', received: '%v'\n",
+				tc.ExpectedResult,
+				result,
+			)
+		}
+	}
+}
+
+func TestAllocateProportionally(t *testing.T) {
+	tests := []struct {
+		Total          int64
+		Weights        []float64
+		ExpectedResult []int64
+	}{
+		{100, []float64{1, 1, 1}, []int64{34, 33, 33}},
+		{100, []float64{0, 0}, []int64{50, 50}},
+		{10, []float64{1, 3}, []int64{3, 7}},
+		{0, []float64{1, 2}, []int64{0, 0}},
+	}
+
+	for _, tc := range tests {
+		result := allocateProportionally(tc.Total, tc.Weights)
+		if !reflect.DeepEqual(tc.ExpectedResult, result) {
+			t.Fatalf("expected allocation '%v' of %d, received: '%v'", tc.ExpectedResult, tc.Total, result)
+		}
+	}
+}
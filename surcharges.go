@@ -0,0 +1,155 @@
+package carrierpricing
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	// embedded time zone database, so that UK local time is available even
+	// where the system one is missing
+	_ "time/tzdata"
+
+	"github.com/giefferre/carrierpricing/postcode"
+)
+
+// SurchargePeriod defines when a SurchargeRule applies.
+type SurchargePeriod string
+
+const (
+	// SurchargePeriodTimeOfDay applies to collections between the From and To
+	// times of any day; when To is earlier than From, the period spans midnight.
+	SurchargePeriodTimeOfDay SurchargePeriod = "time_of_day"
+
+	// SurchargePeriodWeekend applies to collections on Saturdays and Sundays.
+	SurchargePeriodWeekend SurchargePeriod = "weekend"
+
+	// SurchargePeriodBankHoliday applies to collections on bank holidays.
+	SurchargePeriodBankHoliday SurchargePeriod = "bank_holiday"
+)
+
+var errInvalidSurchargePeriod = errors.New("invalid surcharge period")
+
+// ukLocation is the time zone pickup times are evaluated in.
+var ukLocation = loadUKLocation()
+
+// SurchargeRule is a surcharge applied to the price of the deliveries collected
+// in a given period. The surcharge is the price multiplied by Multiplier minus
+// one, when Multiplier is set, plus the FlatFee, in minor units.
+type SurchargeRule struct {
+	Description string          `json:"description"`
+	Period      SurchargePeriod `json:"period"`
+	From        string          `json:"from,omitempty"`
+	To          string          `json:"to,omitempty"`
+	Multiplier  float64         `json:"multiplier,omitempty"`
+	FlatFee     int64           `json:"flat_fee,omitempty"`
+}
+
+// validate returns an error if the SurchargeRule cannot be applied.
+func (sr SurchargeRule) validate() error {
+	if sr.Description == "" {
+		return errors.New("description must be provided")
+	}
+
+	switch sr.Period {
+	case SurchargePeriodTimeOfDay:
+		if _, err := parseClock(sr.From); err != nil {
+			return fmt.Errorf("from: %v", err)
+		}
+		if _, err := parseClock(sr.To); err != nil {
+			return fmt.Errorf("to: %v", err)
+		}
+	case SurchargePeriodWeekend, SurchargePeriodBankHoliday:
+	default:
+		return fmt.Errorf("%w %q", errInvalidSurchargePeriod, sr.Period)
+	}
+
+	if !isNonNegative(sr.Multiplier) || (sr.Multiplier != 0 && sr.Multiplier < 1) {
+		return errors.New("multiplier must be a number not lower than 1")
+	}
+
+	if sr.FlatFee < 0 {
+		return errors.New("flat_fee must not be negative")
+	}
+
+	return nil
+}
+
+// appliesTo returns true if a collection at the given UK local time falls in
+// the period of the SurchargeRule.
+func (sr SurchargeRule) appliesTo(localTime time.Time, isBankHoliday bool) bool {
+	switch sr.Period {
+	case SurchargePeriodTimeOfDay:
+		from, _ := parseClock(sr.From)
+		to, _ := parseClock(sr.To)
+		minutes := localTime.Hour()*60 + localTime.Minute()
+		if from <= to {
+			return minutes >= from && minutes < to
+		}
+		return minutes >= from || minutes < to
+	case SurchargePeriodWeekend:
+		return localTime.Weekday() == time.Saturday || localTime.Weekday() == time.Sunday
+	case SurchargePeriodBankHoliday:
+		return isBankHoliday
+	}
+	return false
+}
+
+// amount returns the surcharge for the given price.
+func (sr SurchargeRule) amount(price int64, rounding RoundingMode) int64 {
+	amount := sr.FlatFee
+	if sr.Multiplier != 0 {
+		amount += multiplyMinorUnits(price, sr.Multiplier, rounding) - price
+	}
+	return amount
+}
+
+// calculateSurcharges returns the surcharges applying to the given price of a
+// delivery collected at pickupTime from the pickup postcode; no surcharges
+// apply when the pickup time is not known.
+func (s *Service) calculateSurcharges(rules *PricingRules, pickupTime *time.Time, pickup *postcode.Postcode, price int64) []PriceAdjustment {
+	if pickupTime == nil || len(rules.Surcharges) == 0 {
+		return nil
+	}
+
+	localTime := pickupTime.In(ukLocation)
+	isBankHoliday := s.holidayCalendar != nil && s.holidayCalendar.IsBankHoliday(localTime, *pickup)
+
+	surcharges := []PriceAdjustment{}
+	for _, rule := range rules.Surcharges {
+		if !rule.appliesTo(localTime, isBankHoliday) {
+			continue
+		}
+
+		surcharges = append(surcharges, PriceAdjustment{
+			Description: rule.Description,
+			Amount:      rules.money(rule.amount(price, rules.Rounding)),
+		})
+	}
+
+	return surcharges
+}
+
+// sumOfAdjustments returns the sum of the given adjustments, in minor units.
+func sumOfAdjustments(adjustments []PriceAdjustment) int64 {
+	var sum int64
+	for _, adjustment := range adjustments {
+		sum += adjustment.Amount.Amount
+	}
+	return sum
+}
+
+// parseClock returns the minutes since midnight of a "15:04" formatted time.
+func parseClock(clock string) (int, error) {
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", clock)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+func loadUKLocation() *time.Location {
+	location, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
@@ -0,0 +1,154 @@
+package carrierpricing
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/giefferre/carrierpricing/postcode"
+)
+
+func TestSurchargeRuleAppliesTo(t *testing.T) {
+	evening := SurchargeRule{Description: "evening", Period: SurchargePeriodTimeOfDay, From: "19:00", To: "07:00", Multiplier: 1.25}
+	lunch := SurchargeRule{Description: "lunch", Period: SurchargePeriodTimeOfDay, From: "12:00", To: "14:00", FlatFee: 100}
+	weekend := SurchargeRule{Description: "weekend", Period: SurchargePeriodWeekend, Multiplier: 1.5}
+	bankHoliday := SurchargeRule{Description: "bank holiday", Period: SurchargePeriodBankHoliday, FlatFee: 1500}
+
+	tests := []struct {
+		Rule           SurchargeRule
+		LocalTime      time.Time
+		IsBankHoliday  bool
+		ExpectedResult bool
+	}{
+		// case #1 evening period spanning midnight, before midnight
+		{evening, time.Date(2026, 10, 14, 19, 0, 0, 0, time.UTC), false, true},
+		// case #2 evening period spanning midnight, after midnight
+		{evening, time.Date(2026, 10, 15, 6, 59, 0, 0, time.UTC), false, true},
+		// case #3 evening period spanning midnight, end is excluded
+		{evening, time.Date(2026, 10, 15, 7, 0, 0, 0, time.UTC), false, false},
+		// case #4 period within the same day
+		{lunch, time.Date(2026, 10, 15, 13, 30, 0, 0, time.UTC), false, true},
+		// case #5 period within the same day, outside of it
+		{lunch, time.Date(2026, 10, 15, 14, 0, 0, 0, time.UTC), false, false},
+		// case #6 saturday
+		{weekend, time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC), false, true},
+		// case #7 sunday
+		{weekend, time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC), false, true},
+		// case #8 weekday
+		{weekend, time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC), false, false},
+		// case #9 bank holiday
+		{bankHoliday, time.Date(2026, 12, 25, 10, 0, 0, 0, time.UTC), true, true},
+		// case #10 not a bank holiday
+		{bankHoliday, time.Date(2026, 12, 24, 10, 0, 0, 0, time.UTC), false, false},
+	}
+
+	for i, tc := range tests {
+		result := tc.Rule.appliesTo(tc.LocalTime, tc.IsBankHoliday)
+		if result != tc.ExpectedResult {
+			t.Fatalf("case #%d: expected %v, received: %v", i+1, tc.ExpectedResult, result)
+		}
+	}
+}
+
+func TestGetQuotesByVehicleSurcharges(t *testing.T) {
+	tests := []struct {
+		PickupTime         *time.Time
+		HolidayCalendar    HolidayCalendar
+		ExpectedPrice      Money
+		ExpectedSurcharges []PriceAdjustment
+	}{
+		// case #1 no pickup time, no surcharges
+		{
+			PickupTime:         nil,
+			ExpectedPrice:      gbp(411),
+			ExpectedSurcharges: nil,
+		},
+		// case #2 weekday, during the day
+		{
+			PickupTime:         timePtr(time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC)),
+			ExpectedPrice:      gbp(411),
+			ExpectedSurcharges: []PriceAdjustment{},
+		},
+		// case #3 weekday evening, in UK local time: 18:30 UTC is 19:30 BST
+		{
+			PickupTime:    timePtr(time.Date(2026, 10, 15, 18, 30, 0, 0, time.UTC)),
+			ExpectedPrice: gbp(514),
+			ExpectedSurcharges: []PriceAdjustment{
+				{Description: "evening collection", Amount: gbp(103)},
+			},
+		},
+		// case #4 saturday evening
+		{
+			PickupTime:    timePtr(time.Date(2026, 10, 17, 20, 0, 0, 0, time.UTC)),
+			ExpectedPrice: gbp(719),
+			ExpectedSurcharges: []PriceAdjustment{
+				{Description: "evening collection", Amount: gbp(103)},
+				{Description: "weekend collection", Amount: gbp(205)},
+			},
+		},
+		// case #5 bank holiday, no holiday calendar configured
+		{
+			PickupTime:         timePtr(time.Date(2026, 12, 25, 10, 0, 0, 0, time.UTC)),
+			ExpectedPrice:      gbp(411),
+			ExpectedSurcharges: []PriceAdjustment{},
+		},
+		// case #6 bank holiday
+		{
+			PickupTime:      timePtr(time.Date(2026, 12, 25, 10, 0, 0, 0, time.UTC)),
+			HolidayCalendar: mockHolidayCalendar{"2026-12-25": true},
+			ExpectedPrice:   gbp(1911),
+			ExpectedSurcharges: []PriceAdjustment{
+				{Description: "bank holiday collection", Amount: gbp(1500)},
+			},
+		},
+	}
+
+	for i, tc := range tests {
+		logger := log.New(ioutil.Discard, "", 0)
+		options := []ServiceOption{}
+		if tc.HolidayCalendar != nil {
+			options = append(options, WithHolidayCalendar(tc.HolidayCalendar))
+		}
+		service, err := NewService(logger, &mockCarrierServiceFinder{}, &mockDistanceCalculator{}, nil, options...)
+		if err != nil {
+			t.Fatalf("NewService returned error %v", err)
+		}
+
+		result, err := service.GetQuotesByVehicle(context.Background(), GetQuotesByVehicleArgs{
+			PickupPostcode:   "SW1A1AA",
+			DeliveryPostcode: "EC2A3LT",
+			Vehicle:          VehicleTypeSmallVan,
+			PickupTime:       tc.PickupTime,
+			IncludeBreakdown: true,
+		})
+		if err != nil {
+			t.Fatalf("case #%d: GetQuotesByVehicle returned error %v", i+1, err)
+		}
+
+		if result.Price != tc.ExpectedPrice {
+			t.Fatalf("case #%d: expected price %v, received: %v", i+1, tc.ExpectedPrice, result.Price)
+		}
+		if !reflect.DeepEqual(tc.ExpectedSurcharges, result.Surcharges) {
+			t.Fatalf("case #%d: expected surcharges '%v', received: '%v'", i+1, tc.ExpectedSurcharges, result.Surcharges)
+		}
+		if !reflect.DeepEqual(append([]PriceAdjustment{}, tc.ExpectedSurcharges...), result.Breakdown.Surcharges) {
+			t.Fatalf("case #%d: expected breakdown surcharges '%v', received: '%v'", i+1, tc.ExpectedSurcharges, result.Breakdown.Surcharges)
+		}
+		if result.Breakdown.Total != result.Price {
+			t.Fatalf("case #%d: expected breakdown total to match price %v, received %v", i+1, result.Price, result.Breakdown.Total)
+		}
+	}
+}
+
+type mockHolidayCalendar map[string]bool
+
+func (mhc mockHolidayCalendar) IsBankHoliday(day time.Time, location postcode.Postcode) bool {
+	return mhc[day.Format("2006-01-02")]
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,168 @@
+package carrierpricing
+
+import (
+	"errors"
+
+	"github.com/giefferre/carrierpricing/postcode"
+)
+
+const (
+	// JurisdictionGreatBritain is the tax jurisdiction of England, Scotland and Wales.
+	JurisdictionGreatBritain = "GB"
+
+	// JurisdictionNorthernIreland is the tax jurisdiction of Northern Ireland (BT postcodes).
+	JurisdictionNorthernIreland = "NI"
+
+	// JurisdictionIsleOfMan is the tax jurisdiction of the Isle of Man (IM postcodes).
+	JurisdictionIsleOfMan = "IM"
+
+	// JurisdictionJersey is the tax jurisdiction of Jersey (JE postcodes).
+	JurisdictionJersey = "JE"
+
+	// JurisdictionGuernsey is the tax jurisdiction of Guernsey (GY postcodes).
+	JurisdictionGuernsey = "GY"
+)
+
+// ValidJurisdictions is the list of all the tax jurisdictions postcodes may belong to.
+var ValidJurisdictions = []string{
+	JurisdictionGreatBritain,
+	JurisdictionNorthernIreland,
+	JurisdictionIsleOfMan,
+	JurisdictionJersey,
+	JurisdictionGuernsey,
+}
+
+const (
+	// PriceDisplayNet means that prices are displayed before taxes; this is the default.
+	PriceDisplayNet = "net"
+
+	// PriceDisplayGross means that prices are displayed including taxes.
+	PriceDisplayGross = "gross"
+)
+
+var (
+	errInvalidJurisdiction = errors.New("invalid tax jurisdiction provided")
+	errInvalidPriceDisplay = errors.New("invalid price display provided")
+)
+
+// TaxedAmounts contains the price before taxes, the taxes and the price including them.
+type TaxedAmounts struct {
+	Net   Money `json:"net"`
+	Tax   Money `json:"tax"`
+	Gross Money `json:"gross"`
+}
+
+// taxation is used to calculate the taxes of the prices of a single quote.
+type taxation struct {
+	jurisdiction string
+	rate         float64
+	rounding     RoundingMode
+	displayGross bool
+}
+
+// newTaxation returns the taxation applying to a delivery between the given
+// stops, the first one being the pickup. When all the stops belong to the same
+// jurisdiction its tax rate applies; when the delivery crosses the border of a
+// jurisdiction where deliveries are not taxed (e.g. the Channel Islands) no tax
+// applies, being an export; otherwise the tax rate of the pickup applies.
+func newTaxation(rules *PricingRules, priceDisplay string, stops ...*postcode.Postcode) (*taxation, error) {
+	if priceDisplay != "" && priceDisplay != PriceDisplayNet && priceDisplay != PriceDisplayGross {
+		return nil, invalidArgs(errInvalidPriceDisplay)
+	}
+
+	t := &taxation{
+		jurisdiction: jurisdictionOf(stops[0]),
+		rounding:     rules.Rounding,
+		displayGross: priceDisplay == PriceDisplayGross,
+	}
+	t.rate = rules.TaxRates[t.jurisdiction]
+
+	for _, stop := range stops[1:] {
+		jurisdiction := jurisdictionOf(stop)
+		if jurisdiction != t.jurisdiction && rules.TaxRates[jurisdiction] == 0 {
+			t.jurisdiction = jurisdiction
+			t.rate = 0
+			break
+		}
+	}
+
+	return t, nil
+}
+
+// apply returns the taxed amounts for the given price before taxes.
+func (t *taxation) apply(net Money) TaxedAmounts {
+	tax := NewMoney(multiplyMinorUnits(net.Amount, t.rate, t.rounding), net.Currency)
+
+	return TaxedAmounts{
+		Net:   net,
+		Tax:   tax,
+		Gross: NewMoney(net.Amount+tax.Amount, net.Currency),
+	}
+}
+
+// display returns the price to be displayed among the given taxed amounts.
+func (t *taxation) display(amounts TaxedAmounts) Money {
+	if t.displayGross {
+		return amounts.Gross
+	}
+	return amounts.Net
+}
+
+// applyToPriceList returns a copy of the given PriceByCarrierList, whose prices
+// are expected to be before taxes, with the taxed amounts of every price.
+func (t *taxation) applyToPriceList(priceList PriceByCarrierList) PriceByCarrierList {
+	taxed := make(PriceByCarrierList, len(priceList))
+	for i, priceByCarrier := range priceList {
+		priceByCarrier.TaxedAmounts = t.apply(priceByCarrier.Amount)
+		priceByCarrier.Amount = t.display(priceByCarrier.TaxedAmounts)
+		taxed[i] = priceByCarrier
+	}
+	return taxed
+}
+
+// displayParcelPrices returns the given parcel prices, which are expected to be
+// before taxes; when gross prices are displayed, the gross amount is shared
+// proportionally to them instead, so that they still add up to it.
+func (t *taxation) displayParcelPrices(parcelPrices []ParcelPrice, amounts TaxedAmounts) []ParcelPrice {
+	if !t.displayGross {
+		return parcelPrices
+	}
+
+	weights := make([]float64, len(parcelPrices))
+	for i, parcelPrice := range parcelPrices {
+		weights[i] = float64(parcelPrice.Amount.Amount)
+	}
+
+	displayed := make([]ParcelPrice, len(parcelPrices))
+	for i, amount := range allocateProportionally(amounts.Gross.Amount, weights) {
+		displayed[i] = parcelPrices[i]
+		displayed[i].Amount = NewMoney(amount, amounts.Gross.Currency)
+	}
+
+	return displayed
+}
+
+// jurisdictionOf returns the tax jurisdiction the given postcode belongs to.
+func jurisdictionOf(p *postcode.Postcode) string {
+	switch p.Area {
+	case "BT":
+		return JurisdictionNorthernIreland
+	case "IM":
+		return JurisdictionIsleOfMan
+	case "JE":
+		return JurisdictionJersey
+	case "GY":
+		return JurisdictionGuernsey
+	default:
+		return JurisdictionGreatBritain
+	}
+}
+
+func isJurisdictionKnown(jurisdiction string) bool {
+	for _, validJurisdiction := range ValidJurisdictions {
+		if jurisdiction == validJurisdiction {
+			return true
+		}
+	}
+	return false
+}
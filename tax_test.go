@@ -0,0 +1,156 @@
+package carrierpricing
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"testing"
+
+	"github.com/giefferre/carrierpricing/postcode"
+)
+
+func TestNewTaxation(t *testing.T) {
+	tests := []struct {
+		Postcodes            []string
+		ExpectedJurisdiction string
+		ExpectedRate         float64
+	}{
+		// case #1 within Great Britain
+		{[]string{"SW1A1AA", "EC2A3LT"}, JurisdictionGreatBritain, 0.2},
+		// case #2 from Great Britain to Northern Ireland
+		{[]string{"SW1A1AA", "BT11AA"}, JurisdictionGreatBritain, 0.2},
+		// case #3 within Northern Ireland
+		{[]string{"BT11AA", "BT71NN"}, JurisdictionNorthernIreland, 0.2},
+		// case #4 from Great Britain to Jersey, an export
+		{[]string{"SW1A1AA", "JE23AB"}, JurisdictionJersey, 0},
+		// case #5 from Guernsey to Great Britain
+		{[]string{"GY11AA", "SW1A1AA"}, JurisdictionGuernsey, 0},
+		// case #6 route through the Isle of Man and Jersey
+		{[]string{"SW1A1AA", "IM11AA", "JE23AB"}, JurisdictionJersey, 0},
+		// case #7 British Forces Post Office
+		{[]string{"SW1A1AA", "BFPO 123"}, JurisdictionGreatBritain, 0.2},
+	}
+
+	for i, tc := range tests {
+		stops := []*postcode.Postcode{}
+		for _, rawPostcode := range tc.Postcodes {
+			stop, err := postcode.Parse(rawPostcode)
+			if err != nil {
+				t.Fatalf("case #%d: Parse returned error %v", i+1, err)
+			}
+			stops = append(stops, stop)
+		}
+
+		taxation, err := newTaxation(DefaultPricingRules(), "", stops...)
+		if err != nil {
+			t.Fatalf("case #%d: newTaxation returned error %v", i+1, err)
+		}
+		if taxation.jurisdiction != tc.ExpectedJurisdiction || taxation.rate != tc.ExpectedRate {
+			t.Fatalf(
+				"case #%d: expected jurisdiction %s with rate %v, received: %s with rate %v",
+				i+1,
+				tc.ExpectedJurisdiction,
+				tc.ExpectedRate,
+				taxation.jurisdiction,
+				taxation.rate,
+			)
+		}
+	}
+}
+
+func TestTaxationApply(t *testing.T) {
+	tests := []struct {
+		Rate           float64
+		Rounding       RoundingMode
+		Net            Money
+		ExpectedResult TaxedAmounts
+	}{
+		{0.2, RoundingModeHalfEven, gbp(411), taxedGBP(411, 82)},
+		{0.2, RoundingModeHalfEven, gbp(1005), taxedGBP(1005, 201)},
+		// 2.5 pence of tax
+		{0.2, RoundingModeHalfEven, gbp(1253), taxedGBP(1253, 251)},
+		{0.2, RoundingModeDown, gbp(1253), taxedGBP(1253, 250)},
+		{0.05, RoundingModeHalfUp, gbp(1210), taxedGBP(1210, 61)},
+		{0, RoundingModeHalfEven, gbp(411), taxedGBP(411, 0)},
+	}
+
+	for i, tc := range tests {
+		taxation := &taxation{rate: tc.Rate, rounding: tc.Rounding}
+		if result := taxation.apply(tc.Net); result != tc.ExpectedResult {
+			t.Fatalf("case #%d: expected %+v, received: %+v", i+1, tc.ExpectedResult, result)
+		}
+	}
+}
+
+func TestGetQuotesByVehicleGrossPrice(t *testing.T) {
+	logger := log.New(ioutil.Discard, "", 0)
+	service, err := NewService(logger, &mockCarrierServiceFinder{}, &mockDistanceCalculator{}, nil)
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
+
+	args := GetQuotesByVehicleArgs{
+		PickupPostcode:   "SW1A1AA",
+		DeliveryPostcode: "EC2A3LT",
+		Vehicle:          VehicleTypeSmallVan,
+		PriceDisplay:     PriceDisplayGross,
+	}
+
+	result, err := service.GetQuotesByVehicle(context.Background(), args)
+	if err != nil {
+		t.Fatalf("GetQuotesByVehicle returned error %v", err)
+	}
+	if result.Price != gbp(493) || result.TaxedAmounts != taxedGBP(411, 82) {
+		t.Fatalf("expected gross price of 4.93 GBP, received: %v, %+v", result.Price, result.TaxedAmounts)
+	}
+
+	args.DeliveryPostcode = "JE23AB"
+	result, err = service.GetQuotesByVehicle(context.Background(), args)
+	if err != nil {
+		t.Fatalf("GetQuotesByVehicle returned error %v", err)
+	}
+	if result.Price != gbp(411) || result.TaxJurisdiction != JurisdictionJersey {
+		t.Fatalf("expected untaxed price of 4.11 GBP, received: %v in %s", result.Price, result.TaxJurisdiction)
+	}
+
+	args.PriceDisplay = "including_vat"
+	_, err = service.GetQuotesByVehicle(context.Background(), args)
+	if !errors.Is(err, errInvalidPriceDisplay) {
+		t.Fatalf("expected error '%v', received: '%v'", errInvalidPriceDisplay, err)
+	}
+}
+
+func TestGetShipmentQuoteGrossPrice(t *testing.T) {
+	// tests that gross parcel prices add up to the gross price
+	logger := log.New(ioutil.Discard, "", 0)
+	service, err := NewService(logger, &mockCarrierServiceFinder{}, &mockDistanceCalculator{}, nil)
+	if err != nil {
+		t.Fatalf("NewService returned error %v", err)
+	}
+
+	result, err := service.GetShipmentQuote(context.Background(), GetShipmentQuoteArgs{
+		PickupPostcode:   "SW1A1AA",
+		DeliveryPostcode: "EC2A3LT",
+		Vehicle:          VehicleTypeSmallVan,
+		Parcels:          []Parcel{Parcel{WeightKg: 10}, Parcel{WeightKg: 20}},
+		PriceDisplay:     PriceDisplayGross,
+	})
+	if err != nil {
+		t.Fatalf("GetShipmentQuote returned error %v", err)
+	}
+
+	var sum int64
+	for _, parcelPrice := range result.Parcels {
+		sum += parcelPrice.Amount.Amount
+	}
+	if result.Price != result.Gross || sum != result.Gross.Amount {
+		t.Fatalf("expected parcel prices to add up to %v, received: %d", result.Gross, sum)
+	}
+
+	for _, priceByCarrier := range result.PriceList {
+		if priceByCarrier.Amount != priceByCarrier.Gross {
+			t.Fatalf("expected gross carrier price, received: %+v", priceByCarrier)
+		}
+	}
+}